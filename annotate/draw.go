@@ -0,0 +1,277 @@
+package annotate
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"yolo_detection/detector"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// COCOSkeleton connects the 17 COCO keypoints (0-indexed) the way
+// ultralytics draws them
+var COCOSkeleton = [][2]int{
+	{15, 13}, {13, 11}, {16, 14}, {14, 12}, {11, 12}, {5, 11}, {6, 12},
+	{5, 6}, {5, 7}, {6, 8}, {7, 9}, {8, 10}, {1, 2}, {0, 1}, {0, 2},
+	{1, 3}, {2, 4}, {3, 5}, {4, 6},
+}
+
+// Options control how results are drawn
+type Options struct {
+	Palette        Palette
+	Colors         map[string]color.RGBA // per class overrides of the palette
+	Thickness      int                   // line width in pixels
+	ShowLabels     bool
+	ShowConfidence bool      // append the confidence to the label
+	Face           font.Face // nil uses a 7x13 bitmap font
+
+	Skeleton              [][2]int // keypoint edges drawn for poses
+	MinKeypointVisibility float32  // keypoints below are not drawn
+}
+
+var DefaultOptions = Options{
+	Palette:               DefaultPalette,
+	Thickness:             2,
+	ShowLabels:            true,
+	ShowConfidence:        true,
+	Skeleton:              COCOSkeleton,
+	MinKeypointVisibility: 0.5,
+}
+
+// one thing to draw
+type shape struct {
+	box        detector.Box
+	polygon    detector.Polygon // drawn instead of box when set
+	keypoints  []detector.Keypoint
+	class      string
+	confidence float32
+}
+
+// NewCanvas returns an RGBA copy of img to draw on
+func NewCanvas(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	canvas := image.NewRGBA(bounds)
+	draw.Draw(canvas, bounds, img, bounds.Min, draw.Src)
+	return canvas
+}
+
+// Detections draws boxes and labels onto dst. Coordinates are relative to
+// the bounds of dst, like the detector output.
+func Detections(dst *image.RGBA, detections []detector.Detection, opts Options) {
+	shapes := make([]shape, len(detections))
+	for i, det := range detections {
+		shapes[i] = shape{box: det.Box, class: det.Class, confidence: det.Confidence}
+	}
+	render(dst, shapes, opts)
+}
+
+// DrawResult saves a copy of img with the detections drawn in the default
+// style, the one-call form of NewCanvas, Detections and Save
+func DrawResult(img image.Image, detections []detector.Detection, outputPath string) error {
+	canvas := NewCanvas(img)
+	Detections(canvas, detections, DefaultOptions)
+	return Save(outputPath, canvas)
+}
+
+// Poses draws boxes, labels and skeletons onto dst
+func Poses(dst *image.RGBA, detections []detector.PoseDetection, opts Options) {
+	shapes := make([]shape, len(detections))
+	for i, det := range detections {
+		shapes[i] = shape{box: det.Box, keypoints: det.Keypoints, class: det.Class, confidence: det.Confidence}
+	}
+	render(dst, shapes, opts)
+}
+
+// OBBs draws rotated boxes and labels onto dst
+func OBBs(dst *image.RGBA, detections []detector.OBBDetection, opts Options) {
+	shapes := make([]shape, len(detections))
+	for i, det := range detections {
+		polygon := det.Polygon()
+		shapes[i] = shape{box: polygon.Bounds(), polygon: polygon, class: det.Class, confidence: det.Confidence}
+	}
+	render(dst, shapes, opts)
+}
+
+// render draws all outlines first so labels are never covered by another box
+func render(dst *image.RGBA, shapes []shape, opts Options) {
+	thickness := opts.Thickness
+	if thickness < 1 {
+		thickness = 1
+	}
+	origin := dst.Bounds().Min
+
+	for _, s := range shapes {
+		c := opts.color(s.class)
+		if len(s.polygon) > 0 {
+			for i, a := range s.polygon {
+				b := s.polygon[(i+1)%len(s.polygon)]
+				drawLine(dst, toPoint(a, origin), toPoint(b, origin), thickness, c)
+			}
+		} else {
+			drawRect(dst, toRect(s.box, origin), thickness, c)
+		}
+		drawSkeleton(dst, s.keypoints, opts, origin, thickness, c)
+	}
+
+	if !opts.ShowLabels {
+		return
+	}
+	face := opts.Face
+	if face == nil {
+		face = basicfont.Face7x13
+	}
+	for _, s := range shapes {
+		label := s.class
+		if opts.ShowConfidence {
+			label = fmt.Sprintf("%s %.2f", s.class, s.confidence)
+		}
+		drawLabel(dst, label, toRect(s.box, origin), face, opts.color(s.class))
+	}
+}
+
+func (o Options) color(class string) color.RGBA {
+	if c, ok := o.Colors[class]; ok {
+		return c
+	}
+	return o.Palette.Color(class)
+}
+
+func toPoint(p detector.Point, origin image.Point) image.Point {
+	return image.Pt(int(p.X), int(p.Y)).Add(origin)
+}
+
+func toRect(b detector.Box, origin image.Point) image.Rectangle {
+	return image.Rect(int(b.X1), int(b.Y1), int(b.X2), int(b.Y2)).Add(origin)
+}
+
+// drawRect draws the outline growing inwards, so boxes touching the image
+// border stay visible
+func drawRect(dst *image.RGBA, r image.Rectangle, thickness int, c color.RGBA) {
+	src := image.NewUniform(c)
+	t := thickness
+	if r.Dx() < 2*t || r.Dy() < 2*t {
+		draw.Draw(dst, r, src, image.Point{}, draw.Src)
+		return
+	}
+	for _, band := range []image.Rectangle{
+		image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+t),
+		image.Rect(r.Min.X, r.Max.Y-t, r.Max.X, r.Max.Y),
+		image.Rect(r.Min.X, r.Min.Y, r.Min.X+t, r.Max.Y),
+		image.Rect(r.Max.X-t, r.Min.Y, r.Max.X, r.Max.Y),
+	} {
+		draw.Draw(dst, band, src, image.Point{}, draw.Src)
+	}
+}
+
+// drawLabel draws the text on a filled box above r, or inside r when there
+// is no room above, kept within the image
+func drawLabel(dst *image.RGBA, label string, r image.Rectangle, face font.Face, c color.RGBA) {
+	const pad = 2
+	metrics := face.Metrics()
+	textW := font.MeasureString(face, label).Ceil()
+	textH := (metrics.Ascent + metrics.Descent).Ceil()
+	size := image.Pt(textW+2*pad, textH+2*pad)
+
+	bounds := dst.Bounds()
+	pos := image.Pt(r.Min.X, r.Min.Y-size.Y)
+	if pos.Y < bounds.Min.Y {
+		pos.Y = r.Min.Y
+	}
+	if pos.X+size.X > bounds.Max.X {
+		pos.X = bounds.Max.X - size.X
+	}
+	if pos.Y+size.Y > bounds.Max.Y {
+		pos.Y = bounds.Max.Y - size.Y
+	}
+	if pos.X < bounds.Min.X {
+		pos.X = bounds.Min.X
+	}
+	if pos.Y < bounds.Min.Y {
+		pos.Y = bounds.Min.Y
+	}
+
+	background := image.Rectangle{Min: pos, Max: pos.Add(size)}
+	draw.Draw(dst, background, image.NewUniform(c), image.Point{}, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(textColor(c)),
+		Face: face,
+		Dot:  fixed.P(pos.X+pad, pos.Y+pad+metrics.Ascent.Ceil()),
+	}
+	d.DrawString(label)
+}
+
+// drawSkeleton draws the visible keypoints and the edges between them
+func drawSkeleton(dst *image.RGBA, keypoints []detector.Keypoint, opts Options, origin image.Point, thickness int, c color.RGBA) {
+	visible := func(i int) bool {
+		return i >= 0 && i < len(keypoints) && keypoints[i].Visibility >= opts.MinKeypointVisibility
+	}
+	point := func(kp detector.Keypoint) image.Point {
+		return image.Pt(int(kp.X), int(kp.Y)).Add(origin)
+	}
+
+	for _, e := range opts.Skeleton {
+		if !visible(e[0]) || !visible(e[1]) {
+			continue
+		}
+		drawLine(dst, point(keypoints[e[0]]), point(keypoints[e[1]]), thickness, c)
+	}
+
+	for i, kp := range keypoints {
+		if visible(i) {
+			dot(dst, point(kp), thickness+2, c)
+		}
+	}
+}
+
+// drawLine draws a line of the given width using Bresenham's algorithm
+func drawLine(dst *image.RGBA, a, b image.Point, thickness int, c color.RGBA) {
+	dx := abs(b.X - a.X)
+	dy := -abs(b.Y - a.Y)
+	sx, sy := 1, 1
+	if a.X > b.X {
+		sx = -1
+	}
+	if a.Y > b.Y {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		dot(dst, a, thickness, c)
+		if a == b {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			a.X += sx
+		}
+		if e2 <= dx {
+			e += dx
+			a.Y += sy
+		}
+	}
+}
+
+// dot fills a size x size square centred on p
+func dot(dst *image.RGBA, p image.Point, size int, c color.RGBA) {
+	min := p.Sub(image.Pt(size/2, size/2))
+	r := image.Rectangle{Min: min, Max: min.Add(image.Pt(size, size))}.Intersect(dst.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
@@ -0,0 +1,135 @@
+package annotate
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"yolo_detection/detector"
+)
+
+var black = color.RGBA{A: 255}
+
+func blackCanvas(r image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(r)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// labelOnly draws only the label of one detection, the outline is given a
+// distinct colour
+func labelOnly(dst *image.RGBA, box detector.Box) color.RGBA {
+	c := color.RGBA{R: 200, G: 40, B: 40, A: 255}
+	opts := DefaultOptions
+	opts.ShowConfidence = false
+	opts.Colors = map[string]color.RGBA{"cola": c}
+	Detections(dst, []detector.Detection{{Box: box, Class: "cola", Confidence: 0.9}}, opts)
+	return c
+}
+
+// the label "cola" in the 7x13 font with padding is 32x17 pixels
+const labelW, labelH = 32, 17
+
+func TestLabelPlacement(t *testing.T) {
+	tests := []struct {
+		name string
+		box  detector.Box
+		want image.Rectangle // label background
+	}{
+		{"above the box", detector.Box{X1: 20, Y1: 40, X2: 60, Y2: 60}, image.Rect(20, 23, 52, 40)},
+		{"inside at the top edge", detector.Box{X1: 20, Y1: 0, X2: 60, Y2: 30}, image.Rect(20, 0, 52, 17)},
+		{"shifted left at the right edge", detector.Box{X1: 90, Y1: 40, X2: 100, Y2: 50}, image.Rect(68, 23, 100, 40)},
+		{"inside near the top edge", detector.Box{X1: 0, Y1: 5, X2: 10, Y2: 50}, image.Rect(0, 5, 32, 22)},
+		{"corner box", detector.Box{X1: 95, Y1: 45, X2: 100, Y2: 50}, image.Rect(68, 28, 100, 45)},
+		{"box outside the image", detector.Box{X1: -30, Y1: -30, X2: -10, Y2: -10}, image.Rect(0, 0, 32, 17)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounds := image.Rect(0, 0, 100, 50)
+			dst := blackCanvas(bounds)
+			c := labelOnly(dst, tt.box)
+
+			if !tt.want.In(bounds) {
+				t.Fatalf("bad test case, %v is not inside the image", tt.want)
+			}
+			// the padded corners of the label are background, never text
+			for _, p := range []image.Point{tt.want.Min, {tt.want.Max.X - 1, tt.want.Min.Y}, {tt.want.Min.X, tt.want.Max.Y - 1}, tt.want.Max.Sub(image.Pt(1, 1))} {
+				if got := dst.RGBAAt(p.X, p.Y); got != c {
+					t.Errorf("label corner %v is %v, want %v", p, got, c)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelInsideOffsetCanvas(t *testing.T) {
+	// detections are relative to the canvas origin, labels stay inside it
+	bounds := image.Rect(100, 100, 200, 150)
+	dst := blackCanvas(bounds)
+	c := labelOnly(dst, detector.Box{X1: 95, Y1: 0, X2: 100, Y2: 5})
+
+	want := image.Rect(200-labelW, 100, 200, 100+labelH)
+	if got := dst.RGBAAt(want.Min.X, want.Max.Y-1); got != c {
+		t.Errorf("label corner is %v, want %v", got, c)
+	}
+	if got := dst.RGBAAt(want.Min.X-1, want.Max.Y-1); got != black {
+		t.Errorf("pixel left of the label is %v, want black", got)
+	}
+}
+
+func TestThickOutlineStaysInsideBox(t *testing.T) {
+	dst := blackCanvas(image.Rect(0, 0, 40, 40))
+	opts := DefaultOptions
+	opts.ShowLabels = false
+	opts.Thickness = 3
+	Detections(dst, []detector.Detection{{Box: detector.Box{X1: 0, Y1: 0, X2: 40, Y2: 40}, Class: "cola"}}, opts)
+
+	c := DefaultPalette.Color("cola")
+	for _, tc := range []struct {
+		p    image.Point
+		want color.RGBA
+	}{
+		{image.Pt(0, 0), c},
+		{image.Pt(2, 20), c},
+		{image.Pt(37, 20), c},
+		{image.Pt(39, 39), c},
+		{image.Pt(3, 20), black},
+		{image.Pt(20, 20), black},
+	} {
+		if got := dst.RGBAAt(tc.p.X, tc.p.Y); got != tc.want {
+			t.Errorf("pixel %v is %v, want %v", tc.p, got, tc.want)
+		}
+	}
+}
+
+func TestDrawResult(t *testing.T) {
+	img := blackCanvas(image.Rect(0, 0, 100, 60))
+	path := filepath.Join(t.TempDir(), "result.png")
+	box := detector.Box{X1: 20, Y1: 30, X2: 60, Y2: 50}
+	if err := DrawResult(img, []detector.Detection{{Box: box, Class: "redbull", Confidence: 0.8}}, path); err != nil {
+		t.Fatal(err)
+	}
+	if img.RGBAAt(20, 40) != black {
+		t.Error("input image was drawn on")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	saved, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Bounds() != img.Bounds() {
+		t.Errorf("saved bounds %v", saved.Bounds())
+	}
+	if got := color.RGBAModel.Convert(saved.At(20, 40)); got != DefaultOptions.color("redbull") {
+		t.Errorf("outline pixel %v, want the class colour", got)
+	}
+}
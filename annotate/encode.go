@@ -0,0 +1,59 @@
+package annotate
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// output image format
+type Format string
+
+const (
+	PNG  Format = "png"
+	JPEG Format = "jpeg"
+)
+
+// FormatFromPath picks the format from the file extension, JPEG unless the
+// path ends in .png
+func FormatFromPath(path string) Format {
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		return PNG
+	}
+	return JPEG
+}
+
+// Encode writes the image in the given format
+func Encode(w io.Writer, img image.Image, format Format) error {
+	var err error
+	switch format {
+	case PNG:
+		err = png.Encode(w, img)
+	case JPEG, "":
+		err = jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	default:
+		return fmt.Errorf("unsupported image format %q", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode image: %v", err)
+	}
+	return nil
+}
+
+// Save writes the image to path, the format follows the extension
+func Save(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	if err := Encode(f, img, FormatFromPath(path)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,52 @@
+package annotate
+
+import (
+	"hash/fnv"
+	"image/color"
+)
+
+// Palette assigns colours to class names. The same name always gets the
+// same colour, for any set of classes.
+type Palette []color.RGBA
+
+// DefaultPalette holds 20 well separated colours (the ultralytics palette)
+var DefaultPalette = Palette{
+	{R: 255, G: 56, B: 56, A: 255},
+	{R: 255, G: 157, B: 151, A: 255},
+	{R: 255, G: 112, B: 31, A: 255},
+	{R: 255, G: 178, B: 29, A: 255},
+	{R: 207, G: 210, B: 49, A: 255},
+	{R: 72, G: 249, B: 10, A: 255},
+	{R: 146, G: 204, B: 23, A: 255},
+	{R: 61, G: 219, B: 134, A: 255},
+	{R: 26, G: 147, B: 52, A: 255},
+	{R: 0, G: 212, B: 187, A: 255},
+	{R: 44, G: 153, B: 168, A: 255},
+	{R: 0, G: 194, B: 255, A: 255},
+	{R: 52, G: 69, B: 147, A: 255},
+	{R: 100, G: 115, B: 255, A: 255},
+	{R: 0, G: 24, B: 236, A: 255},
+	{R: 132, G: 56, B: 255, A: 255},
+	{R: 82, G: 0, B: 133, A: 255},
+	{R: 203, G: 56, B: 255, A: 255},
+	{R: 255, G: 149, B: 200, A: 255},
+	{R: 255, G: 55, B: 199, A: 255},
+}
+
+// Color returns the colour of a class, chosen by a hash of its name
+func (p Palette) Color(class string) color.RGBA {
+	if len(p) == 0 {
+		return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(class))
+	return p[h.Sum32()%uint32(len(p))]
+}
+
+// textColor returns black or white, whichever reads better on background c
+func textColor(c color.RGBA) color.RGBA {
+	if 0.299*float64(c.R)+0.587*float64(c.G)+0.114*float64(c.B) > 140 {
+		return color.RGBA{A: 255}
+	}
+	return color.RGBA{R: 255, G: 255, B: 255, A: 255}
+}
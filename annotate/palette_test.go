@@ -0,0 +1,55 @@
+package annotate
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestPaletteColor(t *testing.T) {
+	// pinned so colours stay the same between releases
+	tests := map[string]int{
+		"person":  8,
+		"cola":    8,
+		"redbull": 17,
+	}
+	for class, index := range tests {
+		if got := DefaultPalette.Color(class); got != DefaultPalette[index] {
+			t.Errorf("Color(%q) = %v, want palette entry %d %v", class, got, index, DefaultPalette[index])
+		}
+	}
+
+	// a copy of the palette gives the same colours
+	palette := append(Palette(nil), DefaultPalette...)
+	for class := range tests {
+		if palette.Color(class) != DefaultPalette.Color(class) {
+			t.Errorf("Color(%q) differs between equal palettes", class)
+		}
+	}
+
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	if got := Palette(nil).Color("person"); got != white {
+		t.Errorf("empty palette gave %v, want white", got)
+	}
+}
+
+func TestOptionsColorOverride(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	opts := DefaultOptions
+	opts.Colors = map[string]color.RGBA{"cola": red}
+
+	if got := opts.color("cola"); got != red {
+		t.Errorf("override gave %v, want %v", got, red)
+	}
+	if got := opts.color("person"); got != DefaultPalette.Color("person") {
+		t.Errorf("class without override gave %v", got)
+	}
+}
+
+func TestTextColor(t *testing.T) {
+	if c := textColor(color.RGBA{R: 255, G: 255, B: 0, A: 255}); c.R != 0 {
+		t.Errorf("expected black text on yellow, got %v", c)
+	}
+	if c := textColor(color.RGBA{B: 200, A: 255}); c.R != 255 {
+		t.Errorf("expected white text on dark blue, got %v", c)
+	}
+}
@@ -1,91 +1,178 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"image"
+	"strings"
 	"time"
+	"yolo_detection/bench"
 	"yolo_detection/classifier"
 	"yolo_detection/detector"
-	"yolo_detection/imageutils"
 )
 
-// BenchmarkResult holds the timing measurements
-type BenchmarkResult struct {
-    InferenceTime time.Duration
-    NumRuns       int
-}
+func cmdBench(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == "compare" {
+		fs := newFlags("bench compare", "")
+		history := fs.String("history", "bench_history.jsonl", "benchmark history file")
+		baseline := fs.String("baseline", "", "baseline git revision, empty for the previous session")
+		if err := parse(fs, args[1:]); err != nil {
+			return err
+		}
+		return RunBenchCompare(*history, *baseline)
+	}
+
+	fs := newFlags("bench", "images...")
+	lib := runtimeFlag(fs)
+	kind := fs.String("kind", "detector", "model kind: detector or classifier")
+	models := fs.String("model", "", "models to compare, comma separated (required)")
+	warmup := fs.Int("warmup", bench.DefaultConfig.Warmup, "untimed frames per worker")
+	frames := fs.Int("frames", bench.DefaultConfig.Frames, "timed frames per worker")
+	concurrency := fs.String("concurrency", "1", "concurrency levels, comma separated")
+	format := fs.String("format", "table", "output format: table or json")
+	history := fs.String("history", "", "append the results to this history file")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if *models == "" {
+		return usagef("-model is required")
+	}
+	if err := oneOf("kind", *kind, "detector", "classifier"); err != nil {
+		return err
+	}
+	if err := oneOf("format", *format, "table", "json"); err != nil {
+		return err
+	}
+	cfg := bench.Config{Warmup: *warmup, Frames: *frames}
+	var err error
+	if cfg.Concurrency, err = parseInts(*concurrency); err != nil {
+		return err
+	}
+	paths, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
 
-func measureInferenceTimeYolo(yolo *detector.YOLODetector, img image.Image, runs int) (BenchmarkResult, error) {
-    var totalInferenceTime time.Duration
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+	return RunBench(ctx, *kind, strings.Split(*models, ","), paths, cfg, *format == "json", *history)
+}
 
-    // Do preprocessing once
-    targetSize := imageutils.ImageSize{
-        Width:  detector.DefaultConfig.InputWidth,
-        Height: detector.DefaultConfig.InputHeight,
-    }
-    imageutils.PreprocessImage(img, targetSize)
-    
-    // Do one full detection to set up the tensors
-    _, err := yolo.Detect(img)
-    if err != nil {
-        return BenchmarkResult{}, fmt.Errorf("initial detection failed: %v", err)
-    }
+// RunBench benchmarks detector or classifier models (kind "detector" or
+// "classifier") on the images at every concurrency level of cfg, then
+// prints a table or, with asJSON, the JSON report. With a historyPath the
+// results are appended to the history file for RunBenchCompare.
+func RunBench(ctx context.Context, kind string, modelPaths, imagePaths []string, cfg bench.Config, asJSON bool, historyPath string) error {
+	images, err := bench.LoadImages(imagePaths)
+	if err != nil {
+		return err
+	}
 
-    // Run just inference (session.Run) multiple times
-    for i := 0; i < runs; i++ {
-        inferenceStart := time.Now()
-        err := yolo.RunInferenceOnly()  // Only running session.Run()
-        if err != nil {
-            return BenchmarkResult{}, fmt.Errorf("inference failed: %v", err)
-        }
-        inferenceTime := time.Since(inferenceStart)
-        totalInferenceTime += inferenceTime
+	report := bench.Report{Machine: bench.CurrentMachine(), Time: time.Now()}
+	hashes := make(map[string]string, len(modelPaths))
+	for _, modelPath := range modelPaths {
+		if hashes[modelPath], err = bench.ModelHash(modelPath); err != nil {
+			return err
+		}
+		newRunner, destroy, err := benchRunner(ctx, kind, modelPath)
+		if err != nil {
+			return err
+		}
+		results, err := bench.Run(modelPath, newRunner, images, cfg)
+		destroy()
+		if err != nil {
+			return err
+		}
+		report.Results = append(report.Results, results...)
+	}
 
-        // fmt.Printf("Run %d - Inference: %v\n", i+1, inferenceTime)
-    }
+	if historyPath != "" {
+		if err := bench.AppendHistory(historyPath, report.Records(bench.GitRevision(), hashes)); err != nil {
+			return err
+		}
+	}
 
-    avgInferenceTime := totalInferenceTime / time.Duration(runs)
-    
-    return BenchmarkResult{
-        InferenceTime: avgInferenceTime,
-        NumRuns:      runs,
-    }, nil
+	if asJSON {
+		return report.WriteJSON(stdout)
+	}
+	return report.Print(stdout)
 }
 
+// errRegression is returned by RunBenchCompare when a stage got slower
+var errRegression = errors.New("performance regression")
 
-func measureInferenceTimeBinary(model *classifier.Classifier, img image.Image, runs int) (BenchmarkResult, error) {
-    var totalInferenceTime time.Duration
+// RunBenchCompare compares the newest benchmark session of this machine
+// with the newest earlier session of baselineRevision, or the previous
+// session when it is empty, and fails with errRegression when a stage is
+// significantly slower
+func RunBenchCompare(historyPath, baselineRevision string) error {
+	records, err := bench.LoadHistory(historyPath)
+	if err != nil {
+		return err
+	}
 
-    // Do preprocessing once
-    targetSize := imageutils.ImageSize{
-        Width:  detector.DefaultConfig.InputWidth,
-        Height: detector.DefaultConfig.InputHeight,
-    }
-    imageutils.PreprocessImage(img, targetSize)
-    
-    // Do one full detection to set up the tensors
-    _, err := model.Classify(img)
-    if err != nil {
-        return BenchmarkResult{}, fmt.Errorf("initial detection failed: %v", err)
-    }
+	fingerprint := bench.CurrentMachine().Fingerprint()
+	current := bench.Latest(records, fingerprint, "")
+	if len(current) == 0 {
+		return fmt.Errorf("no benchmarks of this machine (%s) in %s", fingerprint, historyPath)
+	}
+	// without a revision the session before the current one is the baseline
+	var earlier []bench.Record
+	for _, rec := range records {
+		if rec.Time.Before(current[0].Time) {
+			earlier = append(earlier, rec)
+		}
+	}
+	baseline := bench.Latest(earlier, fingerprint, baselineRevision)
+	if len(baseline) == 0 {
+		return fmt.Errorf("no benchmarks of revision %q on this machine in %s", baselineRevision, historyPath)
+	}
+	fmt.Printf("Comparing %s (%s) against baseline %s (%s)\n",
+		current[0].Revision, current[0].Time.Format(time.RFC3339), baseline[0].Revision, baseline[0].Time.Format(time.RFC3339))
 
-    // Run just inference (session.Run) multiple times
-    for i := 0; i < runs; i++ {
-        inferenceStart := time.Now()
-        err := model.RunInferenceOnly()  // Only running session.Run()
-        if err != nil {
-            return BenchmarkResult{}, fmt.Errorf("inference failed: %v", err)
-        }
-        inferenceTime := time.Since(inferenceStart)
-        totalInferenceTime += inferenceTime
+	comparisons := bench.Compare(baseline, current, bench.DefaultCompareOptions)
+	if err := bench.PrintComparisons(stdout, comparisons); err != nil {
+		return err
+	}
+	if n := bench.Regressions(comparisons); n > 0 {
+		return fmt.Errorf("%w in %d stages", errRegression, n)
+	}
+	return nil
+}
 
-        // fmt.Printf("Run %d - Inference: %v\n", i+1, inferenceTime)
-    }
+// benchRunner returns a constructor of model instances for bench.Run and a
+// function destroying every instance it created
+func benchRunner(ctx context.Context, kind, modelPath string) (func() (bench.Runner, error), func(), error) {
+	// bench.Run creates the instances one after another
+	var created []interface{ Destroy() }
+	destroy := func() {
+		for _, m := range created {
+			m.Destroy()
+		}
+		created = nil
+	}
 
-    avgInferenceTime := totalInferenceTime / time.Duration(runs)
-    
-    return BenchmarkResult{
-        InferenceTime: avgInferenceTime,
-        NumRuns:      runs,
-    }, nil
-}
\ No newline at end of file
+	switch kind {
+	case "detector":
+		return func() (bench.Runner, error) {
+			d, err := detector.New(ctx, modelPath)
+			if err != nil {
+				return nil, err
+			}
+			created = append(created, d)
+			return bench.Detector(d), nil
+		}, destroy, nil
+	case "classifier":
+		return func() (bench.Runner, error) {
+			c, err := classifier.New(ctx, modelPath)
+			if err != nil {
+				return nil, err
+			}
+			created = append(created, c)
+			return bench.Classifier(c), nil
+		}, destroy, nil
+	}
+	return nil, nil, fmt.Errorf("unknown model kind %q, want detector or classifier", kind)
+}
@@ -0,0 +1,236 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+	"yolo_detection/classifier"
+	"yolo_detection/detector"
+	"yolo_detection/imageloader"
+	"yolo_detection/imageutils"
+)
+
+// Runner is a model split into the stages timed by the benchmark. A Runner
+// is used by one goroutine at a time.
+type Runner interface {
+	Preprocess(img image.Image) error
+	Infer() error
+	Postprocess() error
+}
+
+// Detector adapts a detector to Runner
+func Detector(d *detector.YOLODetector) Runner {
+	return &detectorRunner{d: d}
+}
+
+type detectorRunner struct {
+	d      *detector.YOLODetector
+	params imageutils.LetterboxParams
+}
+
+func (r *detectorRunner) Preprocess(img image.Image) error {
+	var err error
+	r.params, err = r.d.Preprocess(img)
+	return err
+}
+
+func (r *detectorRunner) Infer() error {
+	return r.d.RunInferenceOnly()
+}
+
+func (r *detectorRunner) Postprocess() error {
+	r.d.Postprocess(r.params)
+	return nil
+}
+
+// Classifier adapts a classifier to Runner
+func Classifier(c *classifier.Classifier) Runner {
+	return classifierRunner{c: c}
+}
+
+type classifierRunner struct {
+	c *classifier.Classifier
+}
+
+func (r classifierRunner) Preprocess(img image.Image) error { return r.c.Preprocess(img) }
+func (r classifierRunner) Infer() error                     { return r.c.RunInferenceOnly() }
+func (r classifierRunner) Postprocess() error {
+	r.c.Postprocess()
+	return nil
+}
+
+// Image is an encoded image kept in memory, so decoding is timed without
+// disk reads
+type Image struct {
+	Path string
+	Data []byte
+}
+
+// LoadImages reads the image files into memory
+func LoadImages(paths []string) ([]Image, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no benchmark images")
+	}
+	images := make([]Image, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %v", err)
+		}
+		images[i] = Image{Path: path, Data: data}
+	}
+	return images, nil
+}
+
+// Config controls one benchmark
+type Config struct {
+	Warmup      int   // untimed frames per worker
+	Frames      int   // timed frames per worker
+	Concurrency []int // number of parallel workers, one model instance each
+}
+
+var DefaultConfig = Config{
+	Warmup:      10,
+	Frames:      100,
+	Concurrency: []int{1},
+}
+
+// Run benchmarks a model at every concurrency level of the config.
+// newRunner is called once per worker, since ONNX sessions and their
+// tensors can not be shared between goroutines.
+func Run(model string, newRunner func() (Runner, error), images []Image, cfg Config) ([]Result, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no benchmark images")
+	}
+	if cfg.Frames < 1 {
+		return nil, fmt.Errorf("frames must be at least 1, got %d", cfg.Frames)
+	}
+
+	var runners []Runner
+	var results []Result
+	for _, concurrency := range cfg.Concurrency {
+		if concurrency < 1 {
+			return nil, fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+		}
+		for len(runners) < concurrency {
+			r, err := newRunner()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s: %v", model, err)
+			}
+			runners = append(runners, r)
+		}
+
+		result, err := run(runners[:concurrency], images, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%s at concurrency %d: %v", model, concurrency, err)
+		}
+		result.Model = model
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// durations of every stage of one worker, indexed by Stage order
+type timings [numStages][]time.Duration
+
+func run(runners []Runner, images []Image, cfg Config) (Result, error) {
+	// warm up all workers before the clock starts
+	if _, err := parallel(runners, images, cfg.Warmup); err != nil {
+		return Result{}, err
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	perWorker, err := parallel(runners, images, cfg.Frames)
+	wall := time.Since(start)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var all timings
+	for _, t := range perWorker {
+		for s := range all {
+			all[s] = append(all[s], t[s]...)
+		}
+	}
+
+	frames := len(runners) * cfg.Frames
+	result := Result{
+		Concurrency:    len(runners),
+		Frames:         frames,
+		Wall:           wall,
+		Throughput:     float64(frames) / wall.Seconds(),
+		AllocsPerFrame: float64(after.Mallocs-before.Mallocs) / float64(frames),
+		BytesPerFrame:  float64(after.TotalAlloc-before.TotalAlloc) / float64(frames),
+	}
+	for s, samples := range all {
+		result.Stages = append(result.Stages, NewStats(Stages[s], samples))
+	}
+	return result, nil
+}
+
+// parallel runs frames frames on every runner at the same time
+func parallel(runners []Runner, images []Image, frames int) ([]timings, error) {
+	perWorker := make([]timings, len(runners))
+	errs := make([]error, len(runners))
+
+	var wg sync.WaitGroup
+	for w, r := range runners {
+		wg.Add(1)
+		go func(w int, r Runner) {
+			defer wg.Done()
+			for i := 0; i < frames; i++ {
+				// workers start at different images
+				img := images[(w+i)%len(images)]
+				if err := frame(r, img, &perWorker[w]); err != nil {
+					errs[w] = fmt.Errorf("%s: %v", img.Path, err)
+					return
+				}
+			}
+		}(w, r)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return perWorker, nil
+}
+
+// frame times the stages of one image
+func frame(r Runner, img Image, t *timings) error {
+	start := time.Now()
+	decoded, _, err := imageloader.Decode(bytes.NewReader(img.Data), imageloader.DefaultLimits)
+	if err != nil {
+		return err
+	}
+	decodeEnd := time.Now()
+	if err := r.Preprocess(decoded); err != nil {
+		return err
+	}
+	preprocessEnd := time.Now()
+	if err := r.Infer(); err != nil {
+		return err
+	}
+	inferEnd := time.Now()
+	if err := r.Postprocess(); err != nil {
+		return err
+	}
+	end := time.Now()
+
+	t[0] = append(t[0], decodeEnd.Sub(start))
+	t[1] = append(t[1], preprocessEnd.Sub(decodeEnd))
+	t[2] = append(t[2], inferEnd.Sub(preprocessEnd))
+	t[3] = append(t[3], end.Sub(inferEnd))
+	t[4] = append(t[4], end.Sub(start))
+	return nil
+}
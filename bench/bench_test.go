@@ -0,0 +1,121 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewStats(t *testing.T) {
+	samples := make([]time.Duration, 100)
+	for i := range samples {
+		// shuffled 1..100 ms
+		samples[i] = time.Duration((i*37)%100+1) * time.Millisecond
+	}
+	s := NewStats(StageTotal, samples)
+
+	if s.N != 100 || s.Mean != 50500*time.Microsecond {
+		t.Errorf("n %d mean %v, want 100 and 50.5ms", s.N, s.Mean)
+	}
+	for _, c := range []struct {
+		name      string
+		got, want time.Duration
+	}{
+		{"p50", s.P50, 50 * time.Millisecond},
+		{"p90", s.P90, 90 * time.Millisecond},
+		{"p99", s.P99, 99 * time.Millisecond},
+		{"max", s.Max, 100 * time.Millisecond},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s %v, want %v", c.name, c.got, c.want)
+		}
+	}
+	if empty := NewStats(StageTotal, nil); empty.N != 0 || empty.P99 != 0 {
+		t.Errorf("unexpected stats of no samples %+v", empty)
+	}
+}
+
+type fakeRunner struct {
+	frames *int64
+	fail   bool
+}
+
+func (r fakeRunner) Preprocess(img image.Image) error {
+	if img.Bounds().Dx() != 4 {
+		return fmt.Errorf("unexpected image %v", img.Bounds())
+	}
+	return nil
+}
+
+func (r fakeRunner) Infer() error {
+	if r.fail {
+		return fmt.Errorf("inference failed")
+	}
+	time.Sleep(100 * time.Microsecond)
+	return nil
+}
+
+func (r fakeRunner) Postprocess() error {
+	atomic.AddInt64(r.frames, 1)
+	return nil
+}
+
+func testImages(t *testing.T) []Image {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 3))); err != nil {
+		t.Fatal(err)
+	}
+	return []Image{{Path: "a.png", Data: buf.Bytes()}}
+}
+
+func TestRun(t *testing.T) {
+	var frames int64
+	var created int
+	newRunner := func() (Runner, error) {
+		created++
+		return fakeRunner{frames: &frames}, nil
+	}
+
+	results, err := Run("fake", newRunner, testImages(t), Config{Warmup: 2, Frames: 5, Concurrency: []int{1, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != 3 {
+		t.Errorf("created %d runners, want 3 reused across levels", created)
+	}
+	// warmup and timed frames of both levels
+	if frames != 1*7+3*7 {
+		t.Errorf("ran %d frames, want 28", frames)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	r := results[1]
+	if r.Model != "fake" || r.Concurrency != 3 || r.Frames != 15 || r.Throughput <= 0 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if len(r.Stages) != numStages {
+		t.Fatalf("got %d stages, want %d", len(r.Stages), numStages)
+	}
+	inference, _ := r.Stage(StageInference)
+	total, _ := r.Stage(StageTotal)
+	if inference.N != 15 || inference.P50 < 100*time.Microsecond || total.Mean < inference.Mean {
+		t.Errorf("inference %+v total %+v", inference, total)
+	}
+}
+
+func TestRunError(t *testing.T) {
+	var frames int64
+	newRunner := func() (Runner, error) { return fakeRunner{frames: &frames, fail: true}, nil }
+	if _, err := Run("fake", newRunner, testImages(t), DefaultConfig); err == nil {
+		t.Error("expected the inference error")
+	}
+	if _, err := Run("fake", newRunner, nil, DefaultConfig); err == nil {
+		t.Error("expected an error without images")
+	}
+}
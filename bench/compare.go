@@ -0,0 +1,184 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// CompareOptions decide when a difference counts as a regression
+type CompareOptions struct {
+	Alpha       float64 // significance level of the one-sided Welch t-test
+	MinSlowdown float64 // smallest relative slowdown reported, e.g. 0.05
+}
+
+var DefaultCompareOptions = CompareOptions{Alpha: 0.01, MinSlowdown: 0.05}
+
+// Comparison of one stage between a baseline and a current record
+type Comparison struct {
+	Model       string    `json:"model"`
+	Concurrency int       `json:"concurrency"`
+	Stage       Stage     `json:"stage"`
+	Baseline    Stats     `json:"baseline"`
+	Current     Stats     `json:"current"`
+	Change      float64   `json:"change"` // relative change of the mean, positive is slower
+	P           float64   `json:"p"`      // probability of the slowdown under equal means
+	ModelHashes [2]string `json:"model_hashes"`
+	Regression  bool      `json:"regression"`
+}
+
+// Compare matches records by model and concurrency and tests every stage
+// for a slowdown. Records without a counterpart are skipped.
+func Compare(baseline, current []Record, opts CompareOptions) []Comparison {
+	type key struct {
+		model       string
+		concurrency int
+	}
+	base := make(map[key]Record, len(baseline))
+	for _, rec := range baseline {
+		base[key{rec.Result.Model, rec.Result.Concurrency}] = rec
+	}
+
+	var comparisons []Comparison
+	for _, cur := range current {
+		b, ok := base[key{cur.Result.Model, cur.Result.Concurrency}]
+		if !ok {
+			continue
+		}
+		for _, cs := range cur.Result.Stages {
+			bs, ok := b.Result.Stage(cs.Stage)
+			if !ok {
+				continue
+			}
+			c := Comparison{
+				Model:       cur.Result.Model,
+				Concurrency: cur.Result.Concurrency,
+				Stage:       cs.Stage,
+				Baseline:    bs,
+				Current:     cs,
+				P:           welchSlower(bs, cs),
+				ModelHashes: [2]string{b.ModelHash, cur.ModelHash},
+			}
+			if bs.Mean > 0 {
+				c.Change = float64(cs.Mean-bs.Mean) / float64(bs.Mean)
+			}
+			c.Regression = c.P < opts.Alpha && c.Change >= opts.MinSlowdown
+			comparisons = append(comparisons, c)
+		}
+	}
+	return comparisons
+}
+
+// Regressions counts the comparisons flagged as regressions
+func Regressions(comparisons []Comparison) int {
+	var n int
+	for _, c := range comparisons {
+		if c.Regression {
+			n++
+		}
+	}
+	return n
+}
+
+// PrintComparisons writes one row per stage, regressions are marked
+func PrintComparisons(w io.Writer, comparisons []Comparison) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "model\tconc\tstage\tbase ms\tnow ms\tchange\tp\t\t\n")
+	for _, c := range comparisons {
+		mark := ""
+		if c.Regression {
+			mark = "SLOWER"
+		}
+		if c.ModelHashes[0] != c.ModelHashes[1] {
+			mark += " (model changed)"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%+.1f%%\t%.3g\t%s\t\n",
+			c.Model, c.Concurrency, c.Stage, ms(c.Baseline.Mean), ms(c.Current.Mean), 100*c.Change, c.P, mark)
+	}
+	return tw.Flush()
+}
+
+// welchSlower is the one-sided p-value of Welch's t-test for the current
+// mean being larger than the baseline mean
+func welchSlower(baseline, current Stats) float64 {
+	if baseline.N < 2 || current.N < 2 {
+		return 1
+	}
+	m1, m2 := float64(baseline.Mean), float64(current.Mean)
+	v1 := float64(baseline.StdDev) * float64(baseline.StdDev) / float64(baseline.N)
+	v2 := float64(current.StdDev) * float64(current.StdDev) / float64(current.N)
+
+	if v1+v2 == 0 {
+		if m2 > m1 {
+			return 0
+		}
+		return 1
+	}
+	t := (m2 - m1) / math.Sqrt(v1+v2)
+	df := (v1 + v2) * (v1 + v2) / (v1*v1/float64(baseline.N-1) + v2*v2/float64(current.N-1))
+	return 1 - studentCDF(t, df)
+}
+
+// studentCDF is the cumulative distribution function of Student's t
+func studentCDF(t, df float64) float64 {
+	tail := 0.5 * regIncBeta(df/2, 0.5, df/(df+t*t))
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b)
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+
+	// the continued fraction converges quickly below the mean
+	if x < (a+1)/(a+b+2) {
+		return front * betaCF(a, b, x) / a
+	}
+	return 1 - front*betaCF(b, a, 1-x)/b
+}
+
+// betaCF evaluates the continued fraction of the incomplete beta function
+// with the modified Lentz method
+func betaCF(a, b, x float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		for _, num := range [2]float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-12 {
+			break
+		}
+	}
+	return h
+}
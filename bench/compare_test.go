@@ -0,0 +1,91 @@
+package bench
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStudentCDF(t *testing.T) {
+	for _, c := range []struct {
+		t, df, want float64
+	}{
+		{0, 5, 0.5},
+		{2.228, 10, 0.975},
+		{-2.228, 10, 0.025},
+		{1.96, 1e6, 0.975},
+		{6.314, 1, 0.95},
+	} {
+		if got := studentCDF(c.t, c.df); math.Abs(got-c.want) > 1e-3 {
+			t.Errorf("studentCDF(%v, %v) = %.4f, want %.4f", c.t, c.df, got, c.want)
+		}
+	}
+}
+
+func record(fingerprint, revision string, at time.Time, mean, stddev time.Duration) Record {
+	return Record{
+		Time:        at,
+		Fingerprint: fingerprint,
+		Revision:    revision,
+		ModelHash:   "abc",
+		Result: Result{Model: "det.onnx", Concurrency: 1, Stages: []Stats{
+			{Stage: StageInference, N: 100, Mean: mean, StdDev: stddev},
+		}},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	at := time.Now()
+	base := []Record{record("m", "r1", at, 20*time.Millisecond, time.Millisecond)}
+
+	for _, c := range []struct {
+		name       string
+		mean       time.Duration
+		stddev     time.Duration
+		regression bool
+	}{
+		{"same", 20 * time.Millisecond, time.Millisecond, false},
+		{"faster", 15 * time.Millisecond, time.Millisecond, false},
+		{"slower", 23 * time.Millisecond, time.Millisecond, true},
+		// significant but below MinSlowdown
+		{"tiny", 20500 * time.Microsecond, time.Millisecond, false},
+		// large but too noisy to be significant
+		{"noisy", 23 * time.Millisecond, 40 * time.Millisecond, false},
+	} {
+		comps := Compare(base, []Record{record("m", "r2", at, c.mean, c.stddev)}, DefaultCompareOptions)
+		if len(comps) != 1 {
+			t.Fatalf("%s: got %d comparisons, want 1", c.name, len(comps))
+		}
+		if comps[0].Regression != c.regression {
+			t.Errorf("%s: regression %v (change %.3f, p %.3g), want %v", c.name, comps[0].Regression, comps[0].Change, comps[0].P, c.regression)
+		}
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	if err := AppendHistory(path, []Record{record("m", "r1", t0, time.Millisecond, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendHistory(path, []Record{record("m", "r2", t1, time.Millisecond, 0), record("other", "r2", t1.Add(time.Hour), time.Millisecond, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := LoadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+	if latest := Latest(records, "m", ""); len(latest) != 1 || latest[0].Revision != "r2" {
+		t.Errorf("latest %+v, want the r2 session of machine m", latest)
+	}
+	if latest := Latest(records, "m", "r1"); len(latest) != 1 || !latest[0].Time.Equal(t0) {
+		t.Errorf("latest r1 %+v", latest)
+	}
+}
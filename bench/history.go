@@ -0,0 +1,140 @@
+package bench
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Record is one result in the history file, with everything that decides
+// whether two results are comparable
+type Record struct {
+	Time        time.Time `json:"time"` // shared by all records of a session
+	Fingerprint string    `json:"fingerprint"`
+	Machine     Machine   `json:"machine"`
+	Revision    string    `json:"revision"`
+	ModelHash   string    `json:"model_hash"`
+	Result      Result    `json:"result"`
+}
+
+// Fingerprint identifies the hardware and toolchain, results of different
+// fingerprints are not compared
+func (m Machine) Fingerprint() string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%d|%s", m.Hostname, m.OS, m.Arch, m.CPU, m.NumCPU, m.GoVersion)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// ModelHash returns a short hash of the model file
+func ModelHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open model: %v", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash model: %v", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// GitRevision returns the short revision of the working tree, with a
+// "-dirty" suffix for uncommitted changes, or "" outside a git checkout
+func GitRevision() string {
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	revision := strings.TrimSpace(string(out))
+	if status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no").Output(); err == nil && len(status) > 0 {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// Records turns a report into history records. modelHashes maps a model
+// name to the hash of its file.
+func (r Report) Records(revision string, modelHashes map[string]string) []Record {
+	records := make([]Record, len(r.Results))
+	for i, res := range r.Results {
+		records[i] = Record{
+			Time:        r.Time,
+			Fingerprint: r.Machine.Fingerprint(),
+			Machine:     r.Machine,
+			Revision:    revision,
+			ModelHash:   modelHashes[res.Model],
+			Result:      res,
+		}
+	}
+	return records
+}
+
+// AppendHistory appends records to a JSON lines file, creating it if needed
+func AppendHistory(path string, records []Record) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %v", err)
+	}
+	enc := json.NewEncoder(f)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to write history: %v", err)
+		}
+	}
+	return f.Close()
+}
+
+// LoadHistory reads all records of a history file
+func LoadHistory(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %v", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %v", err)
+	}
+	return records, nil
+}
+
+// Latest returns the records of the newest session on the machine with
+// fingerprint, limited to revision unless it is empty
+func Latest(records []Record, fingerprint, revision string) []Record {
+	var newest time.Time
+	for _, rec := range records {
+		if rec.Fingerprint == fingerprint && (revision == "" || rec.Revision == revision) && rec.Time.After(newest) {
+			newest = rec.Time
+		}
+	}
+
+	var session []Record
+	for _, rec := range records {
+		if rec.Fingerprint == fingerprint && (revision == "" || rec.Revision == revision) && rec.Time.Equal(newest) {
+			session = append(session, rec)
+		}
+	}
+	return session
+}
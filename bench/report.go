@@ -0,0 +1,123 @@
+package bench
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Result of one model at one concurrency level
+type Result struct {
+	Model          string        `json:"model"`
+	Concurrency    int           `json:"concurrency"`
+	Frames         int           `json:"frames"` // timed frames over all workers
+	Wall           time.Duration `json:"wall_ns"`
+	Throughput     float64       `json:"throughput"` // frames per second
+	AllocsPerFrame float64       `json:"allocs_per_frame"`
+	BytesPerFrame  float64       `json:"bytes_per_frame"`
+	Stages         []Stats       `json:"stages"`
+}
+
+// Stage returns the stats of one stage
+func (r Result) Stage(stage Stage) (Stats, bool) {
+	for _, s := range r.Stages {
+		if s.Stage == stage {
+			return s, true
+		}
+	}
+	return Stats{}, false
+}
+
+// Machine describes where a benchmark ran
+type Machine struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	CPU       string `json:"cpu"`
+	NumCPU    int    `json:"num_cpu"`
+	GoVersion string `json:"go_version"`
+}
+
+// CurrentMachine describes the machine the process runs on
+func CurrentMachine() Machine {
+	hostname, _ := os.Hostname()
+	return Machine{
+		Hostname:  hostname,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPU:       cpuModel(),
+		NumCPU:    runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// cpuModel reads the CPU name on linux, empty elsewhere
+func cpuModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// Report holds the results of a benchmark session
+type Report struct {
+	Machine Machine   `json:"machine"`
+	Time    time.Time `json:"time"`
+	Results []Result  `json:"results"`
+}
+
+// String renders the report as a table
+func (r Report) String() string {
+	var b strings.Builder
+	r.Print(&b)
+	return b.String()
+}
+
+// Print writes one row per stage, throughput and allocations on the total row
+func (r Report) Print(w io.Writer) error {
+	fmt.Fprintf(w, "%s, %s/%s, %d CPUs, %s\n", r.Machine.CPU, r.Machine.OS, r.Machine.Arch, r.Machine.NumCPU, r.Machine.GoVersion)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "model\tconc\tstage\tmean ms\tp50 ms\tp90 ms\tp99 ms\tfps\tallocs/frame\t\n")
+	for _, res := range r.Results {
+		for _, s := range res.Stages {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t", res.Model, res.Concurrency, s.Stage, ms(s.Mean), ms(s.P50), ms(s.P90), ms(s.P99))
+			if s.Stage == StageTotal {
+				fmt.Fprintf(tw, "%.1f\t%.0f\t\n", res.Throughput, res.AllocsPerFrame)
+			} else {
+				fmt.Fprintf(tw, "\t\t\n")
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.2f", float64(d)/float64(time.Millisecond))
+}
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package bench
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// part of a frame that is timed separately
+type Stage string
+
+const (
+	StageDecode      Stage = "decode"
+	StagePreprocess  Stage = "preprocess"
+	StageInference   Stage = "inference"
+	StagePostprocess Stage = "postprocess"
+	// the whole frame, decode to postprocess
+	StageTotal Stage = "total"
+)
+
+// Stages in the order of a frame
+var Stages = [...]Stage{StageDecode, StagePreprocess, StageInference, StagePostprocess, StageTotal}
+
+const numStages = len(Stages)
+
+// Stats summarizes the durations of one stage
+type Stats struct {
+	Stage  Stage         `json:"stage"`
+	N      int           `json:"n"`
+	Mean   time.Duration `json:"mean_ns"`
+	StdDev time.Duration `json:"stddev_ns"`
+	P50    time.Duration `json:"p50_ns"`
+	P90    time.Duration `json:"p90_ns"`
+	P99    time.Duration `json:"p99_ns"`
+	Max    time.Duration `json:"max_ns"`
+}
+
+// NewStats computes the summary of samples
+func NewStats(stage Stage, samples []time.Duration) Stats {
+	s := Stats{Stage: stage, N: len(samples)}
+	if len(samples) == 0 {
+		return s
+	}
+
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum float64
+	for _, d := range sorted {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(sorted))
+	var sq float64
+	for _, d := range sorted {
+		sq += (float64(d) - mean) * (float64(d) - mean)
+	}
+	if len(sorted) > 1 {
+		s.StdDev = time.Duration(math.Sqrt(sq / float64(len(sorted)-1)))
+	}
+
+	s.Mean = time.Duration(mean)
+	s.P50 = percentile(sorted, 50)
+	s.P90 = percentile(sorted, 90)
+	s.P99 = percentile(sorted, 99)
+	s.Max = sorted[len(sorted)-1]
+	return s
+}
+
+// percentile of sorted samples by the nearest rank method
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
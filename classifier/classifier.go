@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"image"
+	"math"
 	"os"
+	"sort"
 	"yolo_detection/imageutils"
 
 	onnxruntime "github.com/yalue/onnxruntime_go"
@@ -12,69 +14,117 @@ import (
 
 // create new classifier
 func New(ctx context.Context, modelPath string) (*Classifier, error) {
+	return NewWithConfig(ctx, modelPath, DefaultConfig)
+}
+
+// create new classifier with a custom config
+func NewWithConfig(ctx context.Context, modelPath string, config Config) (*Classifier, error) {
 	fmt.Println("SCOPE: Classifier.New")
 	defer fmt.Println("SCOPE: Classifier.New END")
 
-	INPUT_LAYER_NAME := "input"
-	OUTPUT_LAYER_NAME := "empty_loaded"
+	if len(config.Labels) == 0 {
+		return nil, fmt.Errorf("classifier config needs at least one label")
+	}
+
 	// check if file exists
 	if _, err := os.Stat(modelPath); err != nil {
 		return nil, fmt.Errorf("model file not found: %v", err)
 	}
 
-	// pre-allocate input tensor 1, 3, h, w
-	inputShape := onnxruntime.NewShape(1, 3, int64(DefaultConfig.InputHeight), int64(DefaultConfig.InputWidth))
-	inputTensor, err := onnxruntime.NewEmptyTensor[float32](inputShape)
+	inputTensor, outputTensor, session, err := newClassifierSession(modelPath, 1, config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create input tensor: %v", err)
+		return nil, err
+	}
+
+	targetSize := imageutils.ImageSize{Width: config.InputWidth, Height: config.InputHeight}
+	model := &Classifier{
+		modelPath:    modelPath,
+		preprocessor: imageutils.NewPreprocessor(targetSize, config.Preprocess, config.PreprocessWorkers),
+		session:      session,
+		config:       config,
+		inputTensor:  inputTensor,
+		outputTensor: outputTensor,
+	}
+
+	// second session for batched inference (crops of a detector, ...)
+	if config.BatchSize > 1 {
+		model.batchInputTensor, model.batchOutputTensor, model.batchSession, err =
+			newClassifierSession(modelPath, config.BatchSize, config)
+		if err != nil {
+			model.Destroy()
+			return nil, err
+		}
 	}
 	go func() {
-		fmt.Println("Waiting Destroy inputTensor")
 		<-ctx.Done()
-		inputTensor.Destroy()
-		fmt.Println("Destroy inputTensor")
+		model.Destroy()
 	}()
 
+	return model, nil
+}
+
+// newClassifierSession allocates input and output tensors for batchSize
+// images and creates a session bound to them. Nothing is left allocated on
+// errors.
+func newClassifierSession(modelPath string, batchSize int, config Config) (
+	*onnxruntime.Tensor[float32], *onnxruntime.Tensor[float32], *onnxruntime.Session[float32], error) {
+	INPUT_LAYER_NAME := "input"
+	OUTPUT_LAYER_NAME := "empty_loaded"
+
+	// pre-allocate input tensor n, 3, h, w (or n, h, w, 3)
+	targetSize := imageutils.ImageSize{Width: config.InputWidth, Height: config.InputHeight}
+	inputShape := onnxruntime.NewShape(config.Preprocess.TensorShape(batchSize, targetSize)...)
+	inputTensor, err := onnxruntime.NewEmptyTensor[float32](inputShape)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create input tensor: %v", err)
+	}
+
 	// pre-allocate output tensor
-	outputShape := []int64{1, 2} // 2 possible classes
+	outputShape := []int64{int64(batchSize), int64(len(config.Labels))} // one output per label
 	outputTensor, err := onnxruntime.NewEmptyTensor[float32](outputShape)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create output tensor: %v", err)
+		inputTensor.Destroy()
+		return nil, nil, nil, fmt.Errorf("failed to create output tensor: %v", err)
 	}
-	go func() {
-		fmt.Println("Waiting Destroy outputTensor")
-		<-ctx.Done()
-		outputTensor.Destroy()
-		fmt.Println("Destroy outputTensor")
-	}()
 
 	// create ONNX runtime session
 	session, err := onnxruntime.NewSession(
 		modelPath,
-		[]string{INPUT_LAYER_NAME},                   // Run options
-		[]string{OUTPUT_LAYER_NAME},                  // Input names (empty means use default names)
-		[]*onnxruntime.Tensor[float32]{inputTensor},  // Input tensors
-		[]*onnxruntime.Tensor[float32]{outputTensor}, // Output tensors
+		[]string{INPUT_LAYER_NAME},
+		[]string{OUTPUT_LAYER_NAME},
+		[]*onnxruntime.Tensor[float32]{inputTensor},
+		[]*onnxruntime.Tensor[float32]{outputTensor},
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create ONNX session :%v", err)
+		destroySession(nil, inputTensor, outputTensor)
+		return nil, nil, nil, fmt.Errorf("failed to create ONNX session :%v", err)
 	}
-	go func() {
-		fmt.Println("Waiting destroy session")
-		<-ctx.Done()
-		session.Destroy()
-		fmt.Println("Destroy session")
-	}()
 
-	model := &Classifier{
-		modelPath:    modelPath,
-		session:      session,
-		config:       DefaultConfig,
-		inputTensor:  inputTensor,
-		outputTensor: outputTensor,
-	}
+	return inputTensor, outputTensor, session, nil
+}
 
-	return model, nil
+// Destroy releases the sessions and tensors of the classifier. It is called
+// when the context given to New is done; calling it earlier releases them
+// right away, e.g. before onnxruntime.DestroyEnvironment. The classifier can
+// not be used afterwards.
+func (d *Classifier) Destroy() {
+	d.destroyOnce.Do(func() {
+		destroySession(d.session, d.inputTensor, d.outputTensor)
+		destroySession(d.batchSession, d.batchInputTensor, d.batchOutputTensor)
+	})
+}
+
+// destroySession releases a session before the tensors bound to it, nil
+// values are skipped
+func destroySession(session *onnxruntime.Session[float32], tensors ...*onnxruntime.Tensor[float32]) {
+	if session != nil {
+		session.Destroy()
+	}
+	for _, t := range tensors {
+		if t != nil {
+			t.Destroy()
+		}
+	}
 }
 
 // RunInferenceOnly executes just the neural network session.Run() step
@@ -82,30 +132,197 @@ func (d *Classifier) RunInferenceOnly() error {
 	return d.session.Run()
 }
 
-func (d *Classifier) Classify(img image.Image) ([]float32, error) {
-	targetSize := imageutils.ImageSize{
-		Width:  d.config.InputWidth,
-		Height: d.config.InputHeight,
+// Classify returns the labels passing ConfThreshold sorted by confidence,
+// limited to TopK. If no label passes a single UnknownClass result carrying
+// the best confidence is returned.
+func (d *Classifier) Classify(img image.Image) ([]Classification, error) {
+	probabilities, err := d.Probabilities(img)
+	if err != nil {
+		return nil, err
 	}
-	// tensorData, params := imageutils.PreprocessImage(img, targetSize)
-	tensorData, _ := imageutils.PreprocessImage(img, targetSize)
+	return d.classifications(probabilities), nil
+}
 
-	// verify data is valid
-	if !imageutils.VerifyTensorData(tensorData) {
-		return nil, fmt.Errorf("invalid tensor data after preprocessing")
+// Probabilities returns one probability per label after applying the
+// configured activation
+func (d *Classifier) Probabilities(img image.Image) ([]float32, error) {
+	// preprocess straight into the input tensor
+	if err := d.Preprocess(img); err != nil {
+		return nil, err
 	}
 
-	// data to input tensor
-	copy(d.inputTensor.GetData(), tensorData)
-	// After copy to input tensor
-
 	// run inference
 	err := d.session.Run()
 	if err != nil {
 		return nil, fmt.Errorf("inference failed: %v", err)
 	}
-	// get output
+
+	return d.Postprocess(), nil
+}
+
+// Preprocess writes img into the input tensor. Preprocess, RunInferenceOnly
+// and Postprocess together are Probabilities, split up so the stages can be
+// timed separately.
+func (d *Classifier) Preprocess(img image.Image) error {
+	if _, err := d.preprocessor.Run(d.inputTensor.GetData(), img); err != nil {
+		return fmt.Errorf("preprocessing failed: %v", err)
+	}
+	return nil
+}
+
+// Postprocess returns the probabilities of the last run
+func (d *Classifier) Postprocess() []float32 {
+	// copy, the output tensor is overwritten by the next run
 	outputData := d.outputTensor.GetData()
+	probabilities := make([]float32, len(outputData))
+	copy(probabilities, outputData)
+
+	applyActivation(probabilities, d.config.Activation)
+	return probabilities
+}
+
+// ClassifyBatch classifies several images. With Config.BatchSize > 1 the
+// images are sent through the batched session in chunks, otherwise they
+// are classified one after another.
+func (d *Classifier) ClassifyBatch(imgs []image.Image) ([][]Classification, error) {
+	probabilities, err := d.ProbabilitiesBatch(imgs)
+	if err != nil {
+		return nil, err
+	}
+	results := make([][]Classification, len(imgs))
+	for i, p := range probabilities {
+		results[i] = d.classifications(p)
+	}
+	return results, nil
+}
+
+// batchRanges splits n items into [start, end) ranges of at most size items,
+// only the last one may be shorter
+func batchRanges(n, size int) [][2]int {
+	var ranges [][2]int
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		ranges = append(ranges, [2]int{start, end})
+	}
+	return ranges
+}
 
-	return outputData, nil
+// ProbabilitiesBatch returns the probabilities of several images, see
+// ClassifyBatch
+func (d *Classifier) ProbabilitiesBatch(imgs []image.Image) ([][]float32, error) {
+	results := make([][]float32, len(imgs))
+
+	if d.batchSession == nil {
+		for i, img := range imgs {
+			probabilities, err := d.Probabilities(img)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = probabilities
+		}
+		return results, nil
+	}
+
+	batchSize := d.config.BatchSize
+	inputData := d.batchInputTensor.GetData()
+	outputData := d.batchOutputTensor.GetData()
+	inputStride := d.preprocessor.TensorLen()
+	outputStride := len(outputData) / batchSize
+
+	for _, batch := range batchRanges(len(imgs), batchSize) {
+		start, end := batch[0], batch[1]
+
+		for i, img := range imgs[start:end] {
+			if _, err := d.preprocessor.Run(inputData[i*inputStride:(i+1)*inputStride], img); err != nil {
+				return nil, fmt.Errorf("preprocessing failed: %v", err)
+			}
+		}
+
+		// unused slots of a partial batch are zeroed
+		for i := (end - start) * inputStride; i < len(inputData); i++ {
+			inputData[i] = 0
+		}
+
+		if err := d.batchSession.Run(); err != nil {
+			return nil, fmt.Errorf("batch inference failed: %v", err)
+		}
+
+		for i := 0; i < end-start; i++ {
+			probabilities := make([]float32, outputStride)
+			copy(probabilities, outputData[i*outputStride:(i+1)*outputStride])
+			applyActivation(probabilities, d.config.Activation)
+			results[start+i] = probabilities
+		}
+	}
+
+	return results, nil
+}
+
+// Labels returns the label of every model output
+func (d *Classifier) Labels() []string {
+	return d.config.Labels
+}
+
+// classifications turns probabilities into sorted, filtered results
+func (d *Classifier) classifications(probabilities []float32) []Classification {
+	all := make([]Classification, len(probabilities))
+	for i, p := range probabilities {
+		label := fmt.Sprintf("class_%d", i)
+		if i < len(d.config.Labels) {
+			label = d.config.Labels[i]
+		}
+		all[i] = Classification{Class: label, Confidence: p}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Confidence > all[j].Confidence
+	})
+
+	var result []Classification
+	for _, c := range all {
+		if c.Confidence < d.config.ConfThreshold {
+			break
+		}
+		result = append(result, c)
+		if d.config.TopK > 0 && len(result) == d.config.TopK {
+			break
+		}
+	}
+
+	if len(result) == 0 {
+		var best float32
+		if len(all) > 0 {
+			best = all[0].Confidence
+		}
+		result = []Classification{{Class: UnknownClass, Confidence: best}}
+	}
+	return result
+}
+
+// applyActivation converts raw outputs into probabilities in place
+func applyActivation(values []float32, activation Activation) {
+	switch activation {
+	case ActivationSoftmax:
+		maxValue := float32(math.Inf(-1))
+		for _, v := range values {
+			if v > maxValue {
+				maxValue = v
+			}
+		}
+		var sum float64
+		for i, v := range values {
+			e := math.Exp(float64(v - maxValue))
+			values[i] = float32(e)
+			sum += e
+		}
+		for i := range values {
+			values[i] = float32(float64(values[i]) / sum)
+		}
+	case ActivationSigmoid:
+		for i, v := range values {
+			values[i] = float32(1 / (1 + math.Exp(-float64(v))))
+		}
+	}
 }
@@ -0,0 +1,134 @@
+package classifier
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBatchRanges(t *testing.T) {
+	for _, tc := range []struct {
+		n, size int
+		want    [][2]int
+	}{
+		{0, 4, nil},
+		{3, 4, [][2]int{{0, 3}}},
+		{8, 4, [][2]int{{0, 4}, {4, 8}}},
+		// the last batch is partial when the size does not divide n
+		{7, 3, [][2]int{{0, 3}, {3, 6}, {6, 7}}},
+		{5, 1, [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}}},
+	} {
+		if got := batchRanges(tc.n, tc.size); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("batchRanges(%d, %d) = %v, want %v", tc.n, tc.size, got, tc.want)
+		}
+	}
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-5
+}
+
+func TestApplyActivationSoftmax(t *testing.T) {
+	// large logits must not overflow
+	values := []float32{1, 2, 3, 1000}
+	applyActivation(values, ActivationSoftmax)
+	var sum float32
+	for i, v := range values {
+		if v < 0 || v > 1 {
+			t.Errorf("probability %d = %v outside [0, 1]", i, v)
+		}
+		sum += v
+	}
+	if !near(sum, 1) {
+		t.Errorf("probabilities sum to %v", sum)
+	}
+	if !near(values[3], 1) {
+		t.Errorf("dominant logit has probability %v", values[3])
+	}
+
+	values = []float32{0, math.Ln2}
+	applyActivation(values, ActivationSoftmax)
+	if !near(values[0], 1.0/3) || !near(values[1], 2.0/3) {
+		t.Errorf("softmax(0, ln 2) = %v", values)
+	}
+}
+
+func TestApplyActivationSigmoid(t *testing.T) {
+	values := []float32{0, 2, -2}
+	applyActivation(values, ActivationSigmoid)
+	want := []float32{0.5, 0.880797, 0.119203}
+	for i := range want {
+		if !near(values[i], want[i]) {
+			t.Errorf("sigmoid %d = %v, want %v", i, values[i], want[i])
+		}
+	}
+}
+
+func TestApplyActivationNone(t *testing.T) {
+	values := []float32{-3, 0.5, 7}
+	applyActivation(values, ActivationNone)
+	if !reflect.DeepEqual(values, []float32{-3, 0.5, 7}) {
+		t.Errorf("values changed to %v", values)
+	}
+}
+
+func TestClassifications(t *testing.T) {
+	labels := []string{"marlboro", "camel", "lucky_strike"}
+	for _, tc := range []struct {
+		name          string
+		config        Config
+		probabilities []float32
+		want          []Classification
+	}{
+		{
+			name:          "sorted by confidence",
+			config:        Config{Labels: labels, ConfThreshold: 0.1},
+			probabilities: []float32{0.2, 0.7, 0.1},
+			want:          []Classification{result("camel", 0.7), result("marlboro", 0.2), result("lucky_strike", 0.1)},
+		},
+		{
+			name:          "truncated to top k",
+			config:        Config{Labels: labels, ConfThreshold: 0.1, TopK: 2},
+			probabilities: []float32{0.2, 0.7, 0.1},
+			want:          []Classification{result("camel", 0.7), result("marlboro", 0.2)},
+		},
+		{
+			name:          "threshold before top k",
+			config:        Config{Labels: labels, ConfThreshold: 0.5, TopK: 2},
+			probabilities: []float32{0.2, 0.7, 0.1},
+			want:          []Classification{result("camel", 0.7)},
+		},
+		{
+			name:          "ties keep label order",
+			config:        Config{Labels: labels, TopK: 2},
+			probabilities: []float32{0.4, 0.2, 0.4},
+			want:          []Classification{result("marlboro", 0.4), result("lucky_strike", 0.4)},
+		},
+		{
+			name:          "outputs without a label",
+			config:        Config{Labels: labels[:1]},
+			probabilities: []float32{0.1, 0.6},
+			want:          []Classification{result("class_1", 0.6), result("marlboro", 0.1)},
+		},
+		{
+			name:          "below the threshold is unknown",
+			config:        Config{Labels: labels, ConfThreshold: 0.5},
+			probabilities: []float32{0.2, 0.3, 0.1},
+			want:          []Classification{result(UnknownClass, 0.3)},
+		},
+		{
+			name:   "no outputs is unknown",
+			config: Config{Labels: labels, ConfThreshold: 0.5},
+			want:   []Classification{result(UnknownClass, 0)},
+		},
+	} {
+		d := &Classifier{config: tc.config}
+		if got := d.classifications(tc.probabilities); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func result(class string, confidence float32) Classification {
+	return Classification{Class: class, Confidence: confidence}
+}
@@ -1,29 +1,30 @@
 package classifier
 
 import (
+	"sync"
+	"yolo_detection/imageutils"
+	"yolo_detection/results"
+
 	onnxruntime "github.com/yalue/onnxruntime_go"
 )
 
-// bounding box
-type Box struct {
-	X1 float32
-	Y1 float32
-	X2 float32
-	Y2 float32
-}
-
-// detection (one box with class and confidence)
-type Classification struct {
-	Class	string
-	Confidence float32
-}
+// classification (one label with confidence)
+type Classification = results.Classification
 
 type Classifier struct {
 	modelPath 	string
+	preprocessor *imageutils.Preprocessor
 	session 	*onnxruntime.Session[float32]
 	config		Config
     inputTensor   *onnxruntime.Tensor[float32]
     outputTensor  *onnxruntime.Tensor[float32]
+
+	// only set when Config.BatchSize > 1
+	batchSession      *onnxruntime.Session[float32]
+	batchInputTensor  *onnxruntime.Tensor[float32]
+	batchOutputTensor *onnxruntime.Tensor[float32]
+
+	destroyOnce sync.Once
 }
 
 type Config struct {
@@ -31,10 +32,34 @@ type Config struct {
 	InputHeight 	int
 	ConfThreshold 	float32
 	IOUThreshold 	float32
+	Labels 			[]string   // one name per model output
+	Activation 		Activation // how raw outputs become probabilities
+	TopK 			int        // max number of results, 0 returns all passing labels
+	BatchSize 		int        // images per inference in ClassifyBatch, <= 1 classifies one by one
+	Preprocess 		imageutils.PreprocessSpec
+	PreprocessWorkers int // goroutines used for preprocessing, <= 1 runs inline
 }
 
+// activation applied to the raw model output
+type Activation int
+
+const (
+	// output is already normalized (e.g. exported with a softmax layer)
+	ActivationNone Activation = iota
+	// single-label: probabilities sum to one
+	ActivationSoftmax
+	// multi-label: every output is an independent probability
+	ActivationSigmoid
+)
+
+// class reported when no label passes ConfThreshold
+const UnknownClass = "unknown"
+
 var DefaultConfig = Config{
 	InputWidth: 	224,
 	InputHeight: 	224,
 	ConfThreshold:  0.25,
-}
\ No newline at end of file
+	Labels: 		[]string{"empty", "loaded"},
+	Activation: 	ActivationNone,
+	Preprocess: 	imageutils.DefaultPreprocessSpec,
+}
@@ -0,0 +1,38 @@
+package classifier
+
+import (
+	"fmt"
+	"image"
+	"yolo_detection/imageutils"
+)
+
+// ClassifyTTA classifies every augmentation of the image and ranks the
+// averaged probabilities like Classify. A nil augmentation set uses
+// imageutils.DefaultAugmentations.
+func (d *Classifier) ClassifyTTA(img image.Image, augmentations []imageutils.Augmentation) ([]Classification, error) {
+	if augmentations == nil {
+		augmentations = imageutils.DefaultAugmentations
+	}
+	if len(augmentations) == 0 {
+		return d.Classify(img)
+	}
+
+	var sum []float32
+	for _, aug := range augmentations {
+		output, err := d.Probabilities(aug.Apply(img, d.config.Preprocess.Interpolation))
+		if err != nil {
+			return nil, fmt.Errorf("tta classification failed: %v", err)
+		}
+		if sum == nil {
+			sum = make([]float32, len(output))
+		}
+		for i, v := range output {
+			sum[i] += v
+		}
+	}
+
+	for i := range sum {
+		sum[i] /= float32(len(augmentations))
+	}
+	return d.classifications(sum), nil
+}
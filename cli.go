@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"yolo_detection/imageloader"
+
+	onnxruntime "github.com/yalue/onnxruntime_go"
+)
+
+// usageError marks invalid flags or arguments, reported with exitUsage
+type usageError struct {
+	err     error
+	printed bool // already reported by the flag package
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{err: fmt.Errorf(format, args...)}
+}
+
+// newFlags returns a flag set for a command, the arguments are described
+// in the usage line
+func newFlags(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: yolo_detection %s [flags] %s\n\nflags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return err
+		}
+		return usageError{err: err, printed: true}
+	}
+	return nil
+}
+
+// default location of the runtime library, relative to the repository
+const defaultRuntimeLibrary = "detector/onnxruntime-linux-x64-1.20.0/lib/libonnxruntime.so"
+
+// runtimeFlag registers -lib, defaulting to $ONNXRUNTIME_LIB
+func runtimeFlag(fs *flag.FlagSet) *string {
+	lib := os.Getenv("ONNXRUNTIME_LIB")
+	if lib == "" {
+		lib = defaultRuntimeLibrary
+	}
+	return fs.String("lib", lib, "onnxruntime shared library (env ONNXRUNTIME_LIB)")
+}
+
+// set once the environment is initialized, run destroys it on exit
+var runtimeReady bool
+
+// initRuntime loads the onnxruntime library
+func initRuntime(lib string) error {
+	// cmake  libonnxruntime_providers_shared.so  libonnxruntime.so  libonnxruntime.so.1  libonnxruntime.so.1.20.0  pkgconfig
+	onnxruntime.SetSharedLibraryPath(lib)
+	if err := onnxruntime.InitializeEnvironment(); err != nil {
+		return fmt.Errorf("failed to initialize onnxruntime from %s: %v", lib, err)
+	}
+	runtimeReady = true
+	return nil
+}
+
+// expandInputs turns files, directories and globs into a sorted list of
+// image files. Patterns matching nothing are an error.
+func expandInputs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, usagef("no input images")
+	}
+
+	var paths []string
+	for _, arg := range args {
+		matches := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(arg); err != nil {
+				return nil, usagef("invalid pattern %q: %v", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("%s matches no files", arg)
+			}
+		}
+
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				paths = append(paths, m)
+				continue
+			}
+			entries, err := os.ReadDir(m)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !e.IsDir() && imageloader.IsImage(e.Name()) {
+					paths = append(paths, filepath.Join(m, e.Name()))
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no images found in %v", args)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// parseLabels reads labels from a file with one label per line, or splits
+// a comma separated list
+func parseLabels(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(value); err != nil {
+		var labels []string
+		for _, l := range strings.Split(value, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				labels = append(labels, l)
+			}
+		}
+		return labels, nil
+	}
+
+	f, err := os.Open(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels: %v", err)
+	}
+	defer f.Close()
+	var labels []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if l := strings.TrimSpace(scanner.Text()); l != "" {
+			labels = append(labels, l)
+		}
+	}
+	return labels, scanner.Err()
+}
+
+// parseInts splits a comma separated list of integers
+func parseInts(value string) ([]int, error) {
+	var values []int
+	for _, s := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, usagef("invalid number %q in %q", s, value)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// oneOf checks a flag value against the allowed values
+func oneOf(name, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return usagef("invalid -%s %q, want one of %v", name, value, allowed)
+}
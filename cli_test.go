@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// touch creates empty files below dir
+func touch(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "b.jpg", "a.PNG", "c.jpeg", "f.webp", "g.TIFF", "h.bmp", "i.gif", "notes.txt", "sub/d.jpg", "other/e.png")
+	join := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+		return paths
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		// directories are not searched recursively and only images are kept
+		{"directory", []string{dir}, join("a.PNG", "b.jpg", "c.jpeg", "f.webp", "g.TIFF", "h.bmp", "i.gif")},
+		{"files are sorted", join("sub/d.jpg", "b.jpg"), join("b.jpg", "sub/d.jpg")},
+		// files named explicitly are kept whatever their extension
+		{"explicit file", join("notes.txt"), join("notes.txt")},
+		{"glob", []string{filepath.Join(dir, "*.jp*g")}, join("b.jpg", "c.jpeg")},
+		{"glob of directories", []string{filepath.Join(dir, "[os]*")}, join("other/e.png", "sub/d.jpg")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandInputs(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandInputsErrors(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "notes.txt")
+
+	var usageErr usageError
+	if _, err := expandInputs(nil); !errors.As(err, &usageErr) {
+		t.Errorf("no arguments: err = %v, want a usage error", err)
+	}
+	if _, err := expandInputs([]string{filepath.Join(dir, "[")}); !errors.As(err, &usageErr) {
+		t.Errorf("bad pattern: err = %v, want a usage error", err)
+	}
+	for name, args := range map[string][]string{
+		"glob without matches":   {filepath.Join(dir, "*.png")},
+		"missing file":           {filepath.Join(dir, "missing.jpg")},
+		"directory of no images": {dir},
+	} {
+		if _, err := expandInputs(args); err == nil || errors.As(err, &usageErr) {
+			t.Errorf("%s: err = %v, want a plain error", name, err)
+		}
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.txt")
+	if err := os.WriteFile(path, []byte("empty\n  loaded \n\nunknown\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{"empty,loaded", []string{"empty", "loaded"}},
+		{" empty , loaded ,", []string{"empty", "loaded"}},
+		{"person", []string{"person"}},
+		{path, []string{"empty", "loaded", "unknown"}},
+	}
+	for _, tt := range tests {
+		got, err := parseLabels(tt.value)
+		if err != nil {
+			t.Fatalf("parseLabels(%q): %v", tt.value, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLabels(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseInts(t *testing.T) {
+	got, err := parseInts("1, 2,8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 8}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseInts = %v, want %v", got, want)
+	}
+
+	var usageErr usageError
+	for _, value := range []string{"", "1,,2", "1,two", "1.5"} {
+		if _, err := parseInts(value); !errors.As(err, &usageErr) {
+			t.Errorf("parseInts(%q): err = %v, want a usage error", value, err)
+		}
+	}
+}
+
+func TestOneOf(t *testing.T) {
+	if err := oneOf("format", "json", "text", "json"); err != nil {
+		t.Errorf("allowed value rejected: %v", err)
+	}
+	var usageErr usageError
+	if err := oneOf("format", "xml", "text", "json"); !errors.As(err, &usageErr) {
+		t.Errorf("err = %v, want a usage error", err)
+	}
+}
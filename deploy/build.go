@@ -0,0 +1,276 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"yolo_detection/classifier"
+	"yolo_detection/detector"
+	"yolo_detection/embedding"
+	"yolo_detection/pipeline"
+)
+
+// Deployment holds the models and pipelines built from a config
+type Deployment struct {
+	Config      *Config
+	Detectors   map[string]*detector.YOLODetector
+	Classifiers map[string]*classifier.Classifier
+	Embedders   map[string]*embedding.Embedder
+	Pipelines   map[string]*pipeline.Pipeline
+}
+
+// Build creates every model and pipeline of the config. The onnxruntime
+// environment must be initialized, the models are released when ctx is
+// done or by Destroy.
+func Build(ctx context.Context, cfg *Config) (*Deployment, error) {
+	d := &Deployment{
+		Config:      cfg,
+		Detectors:   make(map[string]*detector.YOLODetector),
+		Classifiers: make(map[string]*classifier.Classifier),
+		Embedders:   make(map[string]*embedding.Embedder),
+		Pipelines:   make(map[string]*pipeline.Pipeline),
+	}
+
+	for _, name := range keys(cfg.Models) {
+		m := cfg.Models[name]
+		var err error
+		switch m.Kind {
+		case KindDetector:
+			d.Detectors[name], err = buildDetector(ctx, m)
+		case KindClassifier:
+			d.Classifiers[name], err = buildClassifier(ctx, m)
+		case KindEmbedder:
+			d.Embedders[name], err = buildEmbedder(ctx, m)
+		}
+		if err != nil {
+			d.Destroy()
+			return nil, fmt.Errorf("model %s: %v", name, err)
+		}
+	}
+
+	for _, name := range keys(cfg.Pipelines) {
+		p, err := d.buildPipeline(cfg.Pipelines[name])
+		if err != nil {
+			d.Destroy()
+			return nil, fmt.Errorf("pipeline %s: %v", name, err)
+		}
+		d.Pipelines[name] = p
+	}
+	return d, nil
+}
+
+// Destroy releases every model of the deployment, the pipelines can not be
+// used afterwards
+func (d *Deployment) Destroy() {
+	// a model that failed to build is stored as nil
+	for _, m := range d.Detectors {
+		if m != nil {
+			m.Destroy()
+		}
+	}
+	for _, m := range d.Classifiers {
+		if m != nil {
+			m.Destroy()
+		}
+	}
+	for _, m := range d.Embedders {
+		if m != nil {
+			m.Destroy()
+		}
+	}
+}
+
+// DetectorConfig returns the detector config of a model: the defaults, then
+// thresholds saved next to the model, then the settings of the config. The
+// path of the saved thresholds is empty if there are none.
+func (m ModelConfig) DetectorConfig() (detector.Config, string, error) {
+	config, thresholdsPath, err := detector.ConfigFor(m.Path)
+	if err != nil {
+		return config, "", err
+	}
+	if len(m.Labels) > 0 {
+		config.Classes = m.Labels
+	}
+	if m.InputWidth > 0 {
+		config.InputWidth = m.InputWidth
+	}
+	if m.InputHeight > 0 {
+		config.InputHeight = m.InputHeight
+	}
+	// a configured threshold replaces the saved per class thresholds too
+	if m.ConfThreshold > 0 {
+		config.ConfThreshold = m.ConfThreshold
+		config.ClassThresholds = nil
+	}
+	if m.ClassThresholds != nil {
+		config.ClassThresholds = m.ClassThresholds
+	}
+	if m.IOUThreshold > 0 {
+		config.IOUThreshold = m.IOUThreshold
+	}
+	if m.BatchSize > 0 {
+		config.BatchSize = m.BatchSize
+	}
+	config.PreprocessWorkers = m.PreprocessWorkers
+	config.Preprocess, err = m.Preprocess.Spec(config.Preprocess)
+	return config, thresholdsPath, err
+}
+
+// ClassifierConfig returns the classifier config of a model
+func (m ModelConfig) ClassifierConfig() (classifier.Config, error) {
+	config := classifier.DefaultConfig
+	if len(m.Labels) > 0 {
+		config.Labels = m.Labels
+	}
+	if m.InputWidth > 0 {
+		config.InputWidth = m.InputWidth
+	}
+	if m.InputHeight > 0 {
+		config.InputHeight = m.InputHeight
+	}
+	if m.ConfThreshold > 0 {
+		config.ConfThreshold = m.ConfThreshold
+	}
+	switch m.Activation {
+	case "softmax":
+		config.Activation = classifier.ActivationSoftmax
+	case "sigmoid":
+		config.Activation = classifier.ActivationSigmoid
+	case "none":
+		config.Activation = classifier.ActivationNone
+	}
+	if m.TopK > 0 {
+		config.TopK = m.TopK
+	}
+	if m.BatchSize > 0 {
+		config.BatchSize = m.BatchSize
+	}
+	config.PreprocessWorkers = m.PreprocessWorkers
+	var err error
+	config.Preprocess, err = m.Preprocess.Spec(config.Preprocess)
+	return config, err
+}
+
+// EmbedderConfig returns the embedder config of a model
+func (m ModelConfig) EmbedderConfig() (embedding.Config, error) {
+	config := embedding.DefaultConfig
+	if m.InputWidth > 0 {
+		config.InputWidth = m.InputWidth
+	}
+	if m.InputHeight > 0 {
+		config.InputHeight = m.InputHeight
+	}
+	if m.InputName != "" {
+		config.InputName = m.InputName
+	}
+	if m.OutputName != "" {
+		config.OutputName = m.OutputName
+	}
+	if m.Normalize != nil {
+		config.Normalize = *m.Normalize
+	}
+	config.PreprocessWorkers = m.PreprocessWorkers
+	var err error
+	config.Preprocess, err = m.Preprocess.Spec(config.Preprocess)
+	return config, err
+}
+
+func buildDetector(ctx context.Context, m ModelConfig) (*detector.YOLODetector, error) {
+	config, _, err := m.DetectorConfig()
+	if err != nil {
+		return nil, err
+	}
+	return detector.NewWithConfig(ctx, m.Path, config)
+}
+
+func buildClassifier(ctx context.Context, m ModelConfig) (*classifier.Classifier, error) {
+	config, err := m.ClassifierConfig()
+	if err != nil {
+		return nil, err
+	}
+	return classifier.NewWithConfig(ctx, m.Path, config)
+}
+
+func buildEmbedder(ctx context.Context, m ModelConfig) (*embedding.Embedder, error) {
+	config, err := m.EmbedderConfig()
+	if err != nil {
+		return nil, err
+	}
+	return embedding.NewWithConfig(ctx, m.Path, config)
+}
+
+func (d *Deployment) buildPipeline(cfg PipelineConfig) (*pipeline.Pipeline, error) {
+	// Validate rejects references to missing models, a config built without
+	// it must not leave a pipeline with a nil model
+	det := d.Detectors[cfg.Detector]
+	if det == nil {
+		return nil, fmt.Errorf("detector %q is not built", cfg.Detector)
+	}
+	p := pipeline.New(det)
+
+	if q := cfg.Quality; q != nil {
+		thresholds, err := q.thresholds()
+		if err != nil {
+			return nil, err
+		}
+		p.Quality = &pipeline.QualityGate{Thresholds: thresholds, Policy: pipeline.QualityFlag}
+		if q.Policy == "reject" {
+			p.Quality.Policy = pipeline.QualityReject
+		}
+	}
+
+	if cfg.Enhance != nil {
+		// an empty chain is left out, a nil Enhancer skips the step
+		if chain := cfg.Enhance.Chain(); chain != nil {
+			p.Enhance = chain
+		}
+	}
+
+	var err error
+	switch {
+	case cfg.Zones != nil:
+		p.Zones, err = pipeline.NewZones(*cfg.Zones)
+	case cfg.ZonesFile != "":
+		p.Zones, err = pipeline.LoadZones(cfg.ZonesFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cl := cfg.Classify; cl != nil {
+		stage := &pipeline.ClassifyStage{Classifiers: make(map[string]pipeline.BatchClassifier), Crop: cropConfig(cl.Crop)}
+		for class, model := range cl.Classifiers {
+			c := d.Classifiers[model]
+			if c == nil {
+				return nil, fmt.Errorf("classifier %q for class %s is not built", model, class)
+			}
+			stage.Classifiers[class] = c
+		}
+		p.Classify = stage
+	}
+
+	if id := cfg.Identify; id != nil {
+		embedder := d.Embedders[id.Embedder]
+		if embedder == nil {
+			return nil, fmt.Errorf("embedder %q is not built", id.Embedder)
+		}
+		gallery, err := embedding.LoadGallery(id.Gallery)
+		if err != nil {
+			return nil, err
+		}
+		p.Identify = &pipeline.IdentifyStage{
+			Embedder:  embedder,
+			Gallery:   gallery,
+			Classes:   id.Classes,
+			Crop:      cropConfig(id.Crop),
+			Threshold: id.Threshold,
+		}
+	}
+	return p, nil
+}
+
+func cropConfig(c *pipeline.CropConfig) pipeline.CropConfig {
+	if c == nil {
+		return pipeline.DefaultCropConfig
+	}
+	return *c
+}
@@ -0,0 +1,55 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+	"yolo_detection/classifier"
+	"yolo_detection/detector"
+	"yolo_detection/embedding"
+	"yolo_detection/pipeline"
+)
+
+func TestBuildPipelineMissingModels(t *testing.T) {
+	// models that were never loaded, buildPipeline only wires them up
+	d := &Deployment{
+		Detectors:   map[string]*detector.YOLODetector{"shelf": {}, "failed": nil},
+		Classifiers: map[string]*classifier.Classifier{"packs": {}},
+		Embedders:   map[string]*embedding.Embedder{},
+		Pipelines:   map[string]*pipeline.Pipeline{},
+	}
+
+	for _, c := range []struct {
+		name string
+		cfg  PipelineConfig
+		want string
+	}{
+		{"unknown detector", PipelineConfig{Detector: "nope"}, `detector "nope" is not built`},
+		{"nil detector", PipelineConfig{Detector: "failed"}, `detector "failed" is not built`},
+		{
+			"unknown classifier",
+			PipelineConfig{Detector: "shelf", Classify: &ClassifyConfig{Classifiers: map[string]string{"cigarettes": "nope"}}},
+			`classifier "nope" for class cigarettes is not built`,
+		},
+		{
+			"unknown embedder",
+			PipelineConfig{Detector: "shelf", Identify: &IdentifyConfig{Embedder: "nope", Gallery: "gallery.json"}},
+			`embedder "nope" is not built`,
+		},
+	} {
+		p, err := d.buildPipeline(c.cfg)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: error %v, want %q", c.name, err, c.want)
+		}
+		if p != nil {
+			t.Errorf("%s: got a pipeline", c.name)
+		}
+	}
+
+	p, err := d.buildPipeline(PipelineConfig{Detector: "shelf", Classify: &ClassifyConfig{Classifiers: map[string]string{"cigarettes": "packs"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Detector != d.Detectors["shelf"] || p.Classify.Classifiers["cigarettes"] != d.Classifiers["packs"] {
+		t.Errorf("pipeline not wired to the built models: %+v", p)
+	}
+}
@@ -0,0 +1,113 @@
+package deploy
+
+import (
+	"encoding/json"
+	"yolo_detection/imageutils"
+	"yolo_detection/pipeline"
+)
+
+// Config describes a whole deployment: the runtime, the models, one
+// pipeline per camera and where results go
+type Config struct {
+	Runtime   RuntimeConfig             `json:"runtime"`
+	Models    map[string]ModelConfig    `json:"models"`
+	Pipelines map[string]PipelineConfig `json:"pipelines"`
+	Outputs   []OutputConfig            `json:"outputs"`
+}
+
+type RuntimeConfig struct {
+	Library string `json:"library"` // onnxruntime shared library
+}
+
+// what a model is used for
+type ModelKind string
+
+const (
+	KindDetector   ModelKind = "detector"
+	KindClassifier ModelKind = "classifier"
+	KindEmbedder   ModelKind = "embedder"
+)
+
+// ModelConfig describes one model. Zero values keep the defaults of the
+// model kind.
+type ModelConfig struct {
+	Kind   ModelKind `json:"kind"`
+	Path   string    `json:"path"`
+	Labels []string  `json:"labels"` // class names of detectors, labels of classifiers
+
+	InputWidth        int               `json:"input_width"`
+	InputHeight       int               `json:"input_height"`
+	Preprocess        *PreprocessConfig `json:"preprocess"`
+	PreprocessWorkers int               `json:"preprocess_workers"`
+	BatchSize         int               `json:"batch_size"`
+
+	// detectors and classifiers
+	ConfThreshold float32 `json:"conf_threshold"`
+	// detectors
+	IOUThreshold    float32            `json:"iou_threshold"`
+	ClassThresholds map[string]float32 `json:"class_thresholds"`
+	// classifiers
+	Activation string `json:"activation"` // none, softmax or sigmoid
+	TopK       int    `json:"top_k"`
+	// embedders
+	InputName  string `json:"input_name"`
+	OutputName string `json:"output_name"`
+	Normalize  *bool  `json:"normalize"`
+}
+
+// PreprocessConfig starts from a preset and overrides single settings
+type PreprocessConfig struct {
+	Preset        string      `json:"preset"`        // default, ultralytics or imagenet, empty keeps the default of the model kind
+	Resize        string      `json:"resize"`        // letterbox, stretch or center_crop
+	Interpolation string      `json:"interpolation"` // nearest, bilinear, area, catmull_rom or lanczos3
+	NoScaleUp     *bool       `json:"no_scale_up"`
+	Mean          *[3]float32 `json:"mean"`
+	Std           *[3]float32 `json:"std"`
+	Order         string      `json:"order"`  // rgb or bgr
+	Layout        string      `json:"layout"` // nchw or nhwc
+	PadColor      *[3]uint8   `json:"pad_color"`
+}
+
+// PipelineConfig wires models into the pipeline of one camera, models are
+// referenced by name
+type PipelineConfig struct {
+	Detector  string                    `json:"detector"`
+	Quality   *QualityConfig            `json:"quality"`
+	Enhance   *imageutils.EnhanceConfig `json:"enhance"`
+	Zones     *pipeline.ZoneConfig      `json:"zones"`
+	ZonesFile string                    `json:"zones_file"` // instead of zones
+	Classify  *ClassifyConfig           `json:"classify"`
+	Identify  *IdentifyConfig           `json:"identify"`
+}
+
+type QualityConfig struct {
+	Policy     string          `json:"policy"`     // flag (default) or reject
+	Thresholds json.RawMessage `json:"thresholds"` // overrides of imageutils.DefaultQualityThresholds
+}
+
+type ClassifyConfig struct {
+	Classifiers map[string]string    `json:"classifiers"` // detection class to classifier model
+	Crop        *pipeline.CropConfig `json:"crop"`
+}
+
+type IdentifyConfig struct {
+	Embedder  string               `json:"embedder"`
+	Gallery   string               `json:"gallery"` // gallery file
+	Classes   []string             `json:"classes"` // detection classes to identify, empty identifies all
+	Crop      *pipeline.CropConfig `json:"crop"`
+	Threshold float32              `json:"threshold"`
+}
+
+// output formats
+const (
+	OutputText     = "text"
+	OutputJSON     = "json"     // one line per frame
+	OutputCOCO     = "coco"     // one file for all frames
+	OutputYOLO     = "yolo"     // label files in a directory
+	OutputAnnotate = "annotate" // annotated images in a directory
+)
+
+type OutputConfig struct {
+	Format string `json:"format"`
+	Path   string `json:"path"` // file or directory, text, json and coco go to stdout when empty
+}
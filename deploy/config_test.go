@@ -0,0 +1,257 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"yolo_detection/classifier"
+	"yolo_detection/imageutils"
+)
+
+// modelDir returns a directory with empty model files, enough for Validate
+func modelDir(t *testing.T, names ...string) string {
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const validConfig = `{
+  "runtime": {"library": "/opt/onnxruntime/libonnxruntime.so"},
+  "models": {
+    "shelf": {"kind": "detector", "path": "shelf.onnx", "labels": ["cigarettes", "bottle"],
+              "conf_threshold": 0.3, "preprocess": {"preset": "ultralytics", "interpolation": "area"}},
+    "packs": {"kind": "classifier", "path": "packs.onnx", "labels": ["full", "empty"], "activation": "softmax"}
+  },
+  "pipelines": {
+    "cam1": {
+      "detector": "shelf",
+      "quality": {"policy": "reject", "thresholds": {"min_contrast": 5}},
+      "zones": {"zones": [{"name": "top", "polygon": [{"x": 0, "y": 0}, {"x": 100, "y": 0}, {"x": 100, "y": 50}]}]},
+      "classify": {"classifiers": {"cigarettes": "packs"}}
+    }
+  },
+  "outputs": [{"format": "json"}, {"format": "yolo", "path": "labels"}]
+}`
+
+func TestParse(t *testing.T) {
+	dir := modelDir(t, "shelf.onnx", "packs.onnx", "other.onnx")
+	env := []string{
+		"HOME=/root",
+		"YOLO_MODELS__SHELF__CONF_THRESHOLD=0.5",
+		"YOLO_MODELS__PACKS__PATH=other.onnx",
+		"YOLO_OUTPUTS__1__PATH=out",
+	}
+
+	cfg, err := Parse([]byte(validConfig), dir, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cfg.Models["packs"].Path; got != filepath.Join(dir, "other.onnx") {
+		t.Errorf("packs path %q, want the override resolved against the config directory", got)
+	}
+	if got := cfg.Runtime.Library; got != "/opt/onnxruntime/libonnxruntime.so" {
+		t.Errorf("absolute library path changed to %q", got)
+	}
+	if got := cfg.Outputs[1].Path; got != "out" {
+		t.Errorf("output path %q, want out", got)
+	}
+
+	shelf := cfg.Models["shelf"]
+	if shelf.ConfThreshold != 0.5 {
+		t.Errorf("conf threshold %v, want the override 0.5", shelf.ConfThreshold)
+	}
+	spec, err := shelf.Preprocess.Spec(imageutils.DefaultPreprocessSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.PadColor != imageutils.UltralyticsPreprocessSpec.PadColor || spec.Interpolation != imageutils.Area {
+		t.Errorf("unexpected spec %+v", spec)
+	}
+
+	packs, err := cfg.Models["packs"].ClassifierConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packs.Activation != classifier.ActivationSoftmax || packs.Labels[0] != "full" {
+		t.Errorf("unexpected classifier config %+v", packs)
+	}
+
+	thresholds, err := cfg.Pipelines["cam1"].Quality.thresholds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thresholds.MinContrast != 5 || thresholds.MinBlurVariance != imageutils.DefaultQualityThresholds.MinBlurVariance {
+		t.Errorf("thresholds %+v, want defaults with min_contrast 5", thresholds)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	dir := modelDir(t, "shelf.onnx", "packs.onnx")
+
+	for _, c := range []struct {
+		name   string
+		config string
+		env    []string
+		want   []string
+	}{
+		{
+			name:   "syntax",
+			config: "{\n  \"models\": {\n    \"shelf\": {\"kind\": \"detector\",}\n  }\n}",
+			want:   []string{"line 3"},
+		},
+		{
+			name:   "unknown field",
+			config: `{"modles": {}}`,
+			want:   []string{`unknown field "modles"`},
+		},
+		{
+			name:   "type",
+			config: `{"models": {"shelf": {"kind": "detector", "path": "shelf.onnx", "conf_threshold": "high"}}}`,
+			want:   []string{"models.shelf.conf_threshold"},
+		},
+		{
+			name: "references",
+			config: `{
+			  "models": {
+			    "shelf": {"kind": "detector", "path": "shelf.onnx", "iou_threshold": 1.5},
+			    "packs": {"kind": "classifier", "path": "missing.onnx", "activation": "relu"}
+			  },
+			  "pipelines": {
+			    "cam1": {"detector": "packs", "classify": {"classifiers": {"cigarettes": "nope"}}}
+			  },
+			  "outputs": [{"format": "xml"}, {"format": "yolo"}]
+			}`,
+			want: []string{
+				"models.packs.path: model not found",
+				"models.packs.activation",
+				"models.shelf.iou_threshold",
+				`pipelines.cam1.detector: model "packs" is a classifier`,
+				`pipelines.cam1.classify.classifiers.cigarettes: unknown model "nope"`,
+				"outputs[0].format",
+				"outputs[1].path",
+			},
+		},
+		{
+			name:   "bad override",
+			config: validConfig,
+			env:    []string{"YOLO_OUTPUTS__7__PATH=x"},
+			want:   []string{"YOLO_OUTPUTS__7__PATH", "not an index"},
+		},
+	} {
+		_, err := Parse([]byte(c.config), dir, c.env)
+		if err == nil {
+			t.Errorf("%s: expected an error", c.name)
+			continue
+		}
+		for _, want := range c.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: error %q does not mention %q", c.name, err, want)
+			}
+		}
+	}
+}
+
+func TestParseListOverrides(t *testing.T) {
+	dir := modelDir(t, "shelf.onnx", "packs.onnx")
+	env := []string{
+		// field of a list element, nested in two lists
+		"YOLO_PIPELINES__CAM1__ZONES__ZONES__0__POLYGON__2__Y=80",
+		"YOLO_PIPELINES__CAM1__ZONES__ZONES__0__NAME=shelf_top",
+		// a whole list element and a value inside a list of strings
+		`YOLO_OUTPUTS__0={"format": "coco", "path": "out.json"}`,
+		"YOLO_MODELS__SHELF__LABELS__1=jack_daniels",
+	}
+
+	cfg, err := Parse([]byte(validConfig), dir, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zone := cfg.Pipelines["cam1"].Zones.Zones[0]
+	if zone.Name != "shelf_top" || zone.Polygon[2].Y != 80 || zone.Polygon[2].X != 100 {
+		t.Errorf("zone %+v, want the name and the third point's y overridden", zone)
+	}
+	if out := cfg.Outputs[0]; out.Format != "coco" || out.Path != "out.json" {
+		t.Errorf("output %+v, want the replaced element", out)
+	}
+	if len(cfg.Outputs) != 2 || cfg.Outputs[1].Path != "labels" {
+		t.Errorf("outputs %+v, want the second element unchanged", cfg.Outputs)
+	}
+	if labels := cfg.Models["shelf"].Labels; len(labels) != 2 || labels[0] != "cigarettes" || labels[1] != "jack_daniels" {
+		t.Errorf("labels %v", labels)
+	}
+}
+
+func TestOverrideListIndexes(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		path []string
+		want string // error, empty for success
+	}{
+		{"element", []string{"items", "1"}, ""},
+		{"field of an element", []string{"items", "0", "name"}, ""},
+		{"past the end", []string{"items", "2"}, `"2" is not an index of a list of 2`},
+		{"negative", []string{"items", "-1"}, `"-1" is not an index`},
+		{"not a number", []string{"items", "first"}, `"first" is not an index`},
+		{"inside a value", []string{"items", "1", "name"}, `"name" is not inside an object or list`},
+	} {
+		node := map[string]interface{}{
+			"items": []interface{}{map[string]interface{}{"name": "a"}, "b"},
+		}
+		err := override(node, c.path, "x")
+		switch {
+		case c.want == "" && err != nil:
+			t.Errorf("%s: %v", c.name, err)
+		case c.want != "" && (err == nil || !strings.Contains(err.Error(), c.want)):
+			t.Errorf("%s: error %v, want %q", c.name, err, c.want)
+		}
+	}
+
+	node := map[string]interface{}{"items": []interface{}{map[string]interface{}{"name": "a"}, "b"}}
+	if err := override(node, []string{"Items", "0", "name"}, "x"); err != nil {
+		t.Fatal(err)
+	}
+	items := node["items"].([]interface{})
+	if items[0].(map[string]interface{})["name"] != "x" || items[1] != "b" {
+		t.Errorf("items %v", items)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := filepath.Join("/etc", "yolo")
+	cfg := &Config{
+		Runtime: RuntimeConfig{Library: "lib/libonnxruntime.so"},
+		Models:  map[string]ModelConfig{"shelf": {Path: "/models/shelf.onnx"}},
+		Pipelines: map[string]PipelineConfig{
+			"cam1": {ZonesFile: "zones/cam1.json", Identify: &IdentifyConfig{Gallery: "galleries/packs.json"}},
+			"cam2": {ZonesFile: "/srv/zones.json", Identify: &IdentifyConfig{Gallery: "/srv/gallery.json"}},
+			"cam3": {},
+		},
+	}
+	cfg.resolve(dir)
+
+	for _, c := range []struct {
+		name, got, want string
+	}{
+		{"library", cfg.Runtime.Library, filepath.Join(dir, "lib/libonnxruntime.so")},
+		{"absolute model", cfg.Models["shelf"].Path, "/models/shelf.onnx"},
+		{"zones file", cfg.Pipelines["cam1"].ZonesFile, filepath.Join(dir, "zones/cam1.json")},
+		{"gallery", cfg.Pipelines["cam1"].Identify.Gallery, filepath.Join(dir, "galleries/packs.json")},
+		{"absolute zones file", cfg.Pipelines["cam2"].ZonesFile, "/srv/zones.json"},
+		{"absolute gallery", cfg.Pipelines["cam2"].Identify.Gallery, "/srv/gallery.json"},
+		{"no zones file", cfg.Pipelines["cam3"].ZonesFile, ""},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: %q, want %q", c.name, c.got, c.want)
+		}
+	}
+	if cfg.Pipelines["cam3"].Identify != nil {
+		t.Error("identify config created")
+	}
+}
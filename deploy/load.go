@@ -0,0 +1,167 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the environment variables overriding config values. The
+// rest of the name is the path of the value with "__" between keys, e.g.
+// YOLO_MODELS__SHELF__CONF_THRESHOLD=0.3 or YOLO_OUTPUTS__0__PATH=out.json.
+// Values are parsed as JSON and used as strings when that fails.
+const EnvPrefix = "YOLO_"
+
+// Load reads a JSON config, applies environment overrides, resolves paths
+// relative to the config file and validates the result
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	return Parse(data, filepath.Dir(path), os.Environ())
+}
+
+// Parse decodes a config, applies the overrides in env (KEY=value entries)
+// and resolves relative paths against dir
+func Parse(data []byte, dir string, env []string) (*Config, error) {
+	var raw interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("config: %s", describe(data, err))
+	}
+	root, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config: expected a JSON object")
+	}
+
+	for _, kv := range env {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		if err := override(root, strings.Split(strings.TrimPrefix(key, EnvPrefix), "__"), envValue(value)); err != nil {
+			return nil, fmt.Errorf("config: %s: %v", key, err)
+		}
+	}
+
+	merged, err := json.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("config: %v", err)
+	}
+	var cfg Config
+	dec = json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("config: %s", describe(merged, err))
+	}
+
+	cfg.resolve(dir)
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// describe makes decode errors point at the problem
+func describe(data []byte, err error) string {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		line, col := position(data, syntax.Offset)
+		return fmt.Sprintf("line %d, column %d: %v", line, col, err)
+	}
+	var typ *json.UnmarshalTypeError
+	if errors.As(err, &typ) && typ.Field != "" {
+		return fmt.Sprintf("%s: expected %v, got %s", typ.Field, typ.Type, typ.Value)
+	}
+	return strings.TrimPrefix(err.Error(), "json: ")
+}
+
+// position returns the 1-based line and column of a byte offset
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	return line, len(before) - bytes.LastIndexByte(before, '\n')
+}
+
+func envValue(value string) interface{} {
+	var v interface{}
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return value
+	}
+	return v
+}
+
+// override sets the value at path, matching object keys case insensitively
+// and creating missing objects
+func override(node interface{}, path []string, value interface{}) error {
+	key := path[0]
+	if key == "" {
+		return fmt.Errorf("empty key")
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		name := strings.ToLower(key)
+		for k := range n {
+			if strings.EqualFold(k, key) {
+				name = k
+				break
+			}
+		}
+		if len(path) == 1 {
+			n[name] = value
+			return nil
+		}
+		child, ok := n[name]
+		if !ok || child == nil {
+			child = make(map[string]interface{})
+			n[name] = child
+		}
+		return override(child, path[1:], value)
+
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(n) {
+			return fmt.Errorf("%q is not an index of a list of %d", key, len(n))
+		}
+		if len(path) == 1 {
+			n[i] = value
+			return nil
+		}
+		return override(n[i], path[1:], value)
+	}
+	return fmt.Errorf("%q is not inside an object or list", key)
+}
+
+// resolve makes file paths relative to the config directory
+func (c *Config) resolve(dir string) {
+	abs := func(p *string) {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	abs(&c.Runtime.Library)
+	for name, m := range c.Models {
+		abs(&m.Path)
+		c.Models[name] = m
+	}
+	for name, p := range c.Pipelines {
+		abs(&p.ZonesFile)
+		if p.Identify != nil {
+			abs(&p.Identify.Gallery)
+		}
+		c.Pipelines[name] = p
+	}
+}
@@ -0,0 +1,100 @@
+package deploy
+
+import (
+	"fmt"
+	"image/color"
+	"yolo_detection/imageutils"
+)
+
+var presets = map[string]imageutils.PreprocessSpec{
+	"default":     imageutils.DefaultPreprocessSpec,
+	"ultralytics": imageutils.UltralyticsPreprocessSpec,
+	"imagenet":    imageutils.ImageNetPreprocessSpec,
+}
+
+var resizeModes = map[string]imageutils.ResizeMode{
+	"letterbox":   imageutils.ResizeLetterbox,
+	"stretch":     imageutils.ResizeStretch,
+	"center_crop": imageutils.ResizeCenterCrop,
+}
+
+var interpolations = map[string]imageutils.Interpolation{
+	"nearest":     imageutils.Nearest,
+	"bilinear":    imageutils.Bilinear,
+	"area":        imageutils.Area,
+	"catmull_rom": imageutils.CatmullRom,
+	"lanczos3":    imageutils.Lanczos3,
+}
+
+var channelOrders = map[string]imageutils.ChannelOrder{
+	"rgb": imageutils.RGB,
+	"bgr": imageutils.BGR,
+}
+
+var layouts = map[string]imageutils.Layout{
+	"nchw": imageutils.NCHW,
+	"nhwc": imageutils.NHWC,
+}
+
+// Spec applies the config to base, the default of the model kind
+func (p *PreprocessConfig) Spec(base imageutils.PreprocessSpec) (imageutils.PreprocessSpec, error) {
+	if p == nil {
+		return base, nil
+	}
+	spec := base
+	if p.Preset != "" {
+		preset, ok := presets[p.Preset]
+		if !ok {
+			return spec, fmt.Errorf("unknown preset %q, want one of %v", p.Preset, keys(presets))
+		}
+		spec = preset
+	}
+
+	if p.Resize != "" {
+		v, ok := resizeModes[p.Resize]
+		if !ok {
+			return spec, fmt.Errorf("unknown resize %q, want one of %v", p.Resize, keys(resizeModes))
+		}
+		spec.Resize = v
+	}
+	if p.Interpolation != "" {
+		v, ok := interpolations[p.Interpolation]
+		if !ok {
+			return spec, fmt.Errorf("unknown interpolation %q, want one of %v", p.Interpolation, keys(interpolations))
+		}
+		spec.Interpolation = v
+	}
+	if p.Order != "" {
+		v, ok := channelOrders[p.Order]
+		if !ok {
+			return spec, fmt.Errorf("unknown order %q, want one of %v", p.Order, keys(channelOrders))
+		}
+		spec.Order = v
+	}
+	if p.Layout != "" {
+		v, ok := layouts[p.Layout]
+		if !ok {
+			return spec, fmt.Errorf("unknown layout %q, want one of %v", p.Layout, keys(layouts))
+		}
+		spec.Layout = v
+	}
+
+	if p.NoScaleUp != nil {
+		spec.NoScaleUp = *p.NoScaleUp
+	}
+	if p.Mean != nil {
+		spec.Mean = *p.Mean
+	}
+	if p.Std != nil {
+		for c, s := range p.Std {
+			if s <= 0 {
+				return spec, fmt.Errorf("std must be positive, got %v", *p.Std)
+			}
+			spec.Std[c] = s
+		}
+	}
+	if p.PadColor != nil {
+		spec.PadColor = color.RGBA{R: p.PadColor[0], G: p.PadColor[1], B: p.PadColor[2], A: 255}
+	}
+	return spec, nil
+}
@@ -0,0 +1,205 @@
+package deploy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"yolo_detection/imageutils"
+	"yolo_detection/pipeline"
+)
+
+// ValidationError lists every problem found in a config
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid config:\n  " + strings.Join(e, "\n  ")
+}
+
+var activations = []string{"none", "softmax", "sigmoid"}
+
+// Validate checks the whole config and reports all problems at once
+func (c *Config) Validate() error {
+	var errs ValidationError
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if len(c.Models) == 0 {
+		add("models: at least one model is required")
+	}
+	for _, name := range keys(c.Models) {
+		m := c.Models[name]
+		at := "models." + name
+		switch m.Kind {
+		case KindDetector, KindClassifier, KindEmbedder:
+		case "":
+			add("%s.kind: missing, want detector, classifier or embedder", at)
+		default:
+			add("%s.kind: unknown kind %q, want detector, classifier or embedder", at, m.Kind)
+		}
+		if m.Path == "" {
+			add("%s.path: missing", at)
+		} else if _, err := os.Stat(m.Path); err != nil {
+			add("%s.path: model not found: %v", at, err)
+		}
+
+		if m.InputWidth < 0 || m.InputHeight < 0 || m.BatchSize < 0 || m.TopK < 0 || m.PreprocessWorkers < 0 {
+			add("%s: sizes and counts must not be negative", at)
+		}
+		if m.ConfThreshold < 0 || m.ConfThreshold > 1 {
+			add("%s.conf_threshold: %v is outside [0, 1]", at, m.ConfThreshold)
+		}
+		if m.IOUThreshold < 0 || m.IOUThreshold > 1 {
+			add("%s.iou_threshold: %v is outside [0, 1]", at, m.IOUThreshold)
+		}
+		for _, class := range keys(m.ClassThresholds) {
+			if t := m.ClassThresholds[class]; t < 0 || t > 1 {
+				add("%s.class_thresholds.%s: %v is outside [0, 1]", at, class, t)
+			}
+			if len(m.Labels) > 0 && !contains(m.Labels, class) {
+				add("%s.class_thresholds.%s: not one of the labels", at, class)
+			}
+		}
+		if m.Activation != "" && !contains(activations, m.Activation) {
+			add("%s.activation: unknown activation %q, want one of %v", at, m.Activation, activations)
+		}
+		if _, err := m.Preprocess.Spec(imageutils.DefaultPreprocessSpec); err != nil {
+			add("%s.preprocess: %v", at, err)
+		}
+	}
+
+	if len(c.Pipelines) == 0 {
+		add("pipelines: at least one pipeline is required")
+	}
+	for _, name := range keys(c.Pipelines) {
+		c.validatePipeline("pipelines."+name, c.Pipelines[name], add)
+	}
+
+	stdout := 0
+	for i, o := range c.Outputs {
+		at := fmt.Sprintf("outputs[%d]", i)
+		switch o.Format {
+		case OutputText, OutputJSON, OutputCOCO:
+			if o.Path == "" {
+				stdout++
+			}
+		case OutputYOLO, OutputAnnotate:
+			if o.Path == "" {
+				add("%s.path: %s output needs a directory", at, o.Format)
+			}
+		default:
+			add("%s.format: unknown format %q, want text, json, coco, yolo or annotate", at, o.Format)
+		}
+	}
+	if stdout > 1 {
+		add("outputs: only one output can write to stdout, set a path for the others")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (c *Config) validatePipeline(at string, p PipelineConfig, add func(string, ...interface{})) {
+	c.checkModel(at+".detector", p.Detector, KindDetector, add)
+
+	if q := p.Quality; q != nil {
+		if q.Policy != "" && q.Policy != "flag" && q.Policy != "reject" {
+			add("%s.quality.policy: unknown policy %q, want flag or reject", at, q.Policy)
+		}
+		if _, err := q.thresholds(); err != nil {
+			add("%s.quality.thresholds: %v", at, err)
+		}
+	}
+	if p.Enhance != nil && p.Enhance.Gamma < 0 {
+		add("%s.enhance.gamma: must not be negative", at)
+	}
+
+	if p.Zones != nil && p.ZonesFile != "" {
+		add("%s: set either zones or zones_file", at)
+	}
+	if p.Zones != nil {
+		if _, err := pipeline.NewZones(*p.Zones); err != nil {
+			add("%s.zones: %v", at, err)
+		}
+	}
+	if p.ZonesFile != "" {
+		if _, err := pipeline.LoadZones(p.ZonesFile); err != nil {
+			add("%s.zones_file: %v", at, err)
+		}
+	}
+
+	if cl := p.Classify; cl != nil {
+		if len(cl.Classifiers) == 0 {
+			add("%s.classify.classifiers: at least one classifier is required", at)
+		}
+		for _, class := range keys(cl.Classifiers) {
+			c.checkModel(at+".classify.classifiers."+class, cl.Classifiers[class], KindClassifier, add)
+		}
+	}
+
+	if id := p.Identify; id != nil {
+		c.checkModel(at+".identify.embedder", id.Embedder, KindEmbedder, add)
+		if id.Gallery == "" {
+			add("%s.identify.gallery: missing", at)
+		} else if _, err := os.Stat(id.Gallery); err != nil {
+			add("%s.identify.gallery: %v", at, err)
+		}
+		if id.Threshold < -1 || id.Threshold > 1 {
+			add("%s.identify.threshold: %v is outside [-1, 1]", at, id.Threshold)
+		}
+	}
+}
+
+// checkModel reports references to missing models or models of another kind
+func (c *Config) checkModel(at, name string, kind ModelKind, add func(string, ...interface{})) {
+	if name == "" {
+		add("%s: missing %s name", at, kind)
+		return
+	}
+	m, ok := c.Models[name]
+	if !ok {
+		add("%s: unknown model %q, have %v", at, name, keys(c.Models))
+		return
+	}
+	if m.Kind != kind && m.Kind != "" {
+		add("%s: model %q is a %s, want a %s", at, name, m.Kind, kind)
+	}
+}
+
+// thresholds returns the defaults with the configured overrides
+func (q *QualityConfig) thresholds() (imageutils.QualityThresholds, error) {
+	t := imageutils.DefaultQualityThresholds
+	if len(q.Thresholds) == 0 {
+		return t, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(q.Thresholds))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return t, fmt.Errorf("%s", strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return t, nil
+}
+
+// keys returns the sorted keys of a map
+func keys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
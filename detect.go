@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"yolo_detection/classifier"
+	"yolo_detection/deploy"
+	"yolo_detection/detector"
+	"yolo_detection/results"
+)
+
+func cmdDetect(ctx context.Context, args []string) error {
+	fs := newFlags("detect", "images...")
+	lib := runtimeFlag(fs)
+	modelPath := fs.String("model", "", "detector model (required)")
+	labels := fs.String("labels", "", "class names, comma separated or a file with one per line")
+	conf := fs.Float64("conf", 0, "confidence threshold, 0 keeps the model default")
+	iou := fs.Float64("iou", 0, "NMS IoU threshold, 0 keeps the model default")
+	format := fs.String("format", "text", "output format: text, json (one frame per line), coco or yolo")
+	out := fs.String("out", "", "directory for yolo label files")
+	annotated := fs.String("annotate", "", "directory for annotated images")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if *modelPath == "" {
+		return usagef("-model is required")
+	}
+	if err := oneOf("format", *format, "text", "json", "coco", "yolo"); err != nil {
+		return err
+	}
+	if *format == "yolo" && *out == "" {
+		return usagef("-format yolo needs -out")
+	}
+	paths, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	m := deploy.ModelConfig{Kind: deploy.KindDetector, Path: *modelPath, ConfThreshold: float32(*conf), IOUThreshold: float32(*iou)}
+	if m.Labels, err = parseLabels(*labels); err != nil {
+		return err
+	}
+	config, thresholdsPath, err := m.DetectorConfig()
+	if err != nil {
+		return err
+	}
+	if thresholdsPath != "" {
+		fmt.Printf("Loaded thresholds from %s\n", thresholdsPath)
+	}
+
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+	model, err := detector.NewWithConfig(ctx, *modelPath, config)
+	if err != nil {
+		return fmt.Errorf("error initializing detector: %v", err)
+	}
+	defer model.Destroy()
+
+	for _, dir := range []string{*out, *annotated} {
+		if dir != "" {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+		}
+	}
+
+	var frames []results.Frame
+	enc := json.NewEncoder(stdout)
+	for _, path := range paths {
+		img, err := loadImage(path)
+		if err != nil {
+			return fmt.Errorf("error loading image: %v", err)
+		}
+		detections, err := model.Detect(img)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if detections == nil {
+			detections = []detector.Detection{}
+		}
+		b := img.Bounds()
+		frame := results.Frame{SchemaVersion: results.SchemaVersion, Source: path, Width: b.Dx(), Height: b.Dy(), Detections: detections}
+
+		switch *format {
+		case "text":
+			fmt.Fprintf(stdout, "%s: %d detections\n", path, len(detections))
+			for _, det := range detections {
+				fmt.Fprintf(stdout, "  %s %.2f %.0f %.0f %.0f %.0f\n",
+					det.Class, det.Confidence, det.Box.X1, det.Box.Y1, det.Box.X2, det.Box.Y2)
+			}
+		case "json":
+			if err := enc.Encode(frame); err != nil {
+				return err
+			}
+		case "coco":
+			frames = append(frames, frame)
+		case "yolo":
+			if err := results.SaveYOLO(*out, frame, model.Classes(), true); err != nil {
+				return err
+			}
+		}
+
+		if *annotated != "" {
+			if err := DrawDebug(img, detections, filepath.Join(*annotated, filepath.Base(path))); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch *format {
+	case "coco":
+		return results.WriteCOCO(stdout, frames, model.Classes())
+	case "yolo":
+		f, err := os.Create(filepath.Join(*out, "classes.txt"))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return results.WriteYOLOClasses(f, model.Classes())
+	}
+	return nil
+}
+
+func cmdClassify(ctx context.Context, args []string) error {
+	fs := newFlags("classify", "images...")
+	lib := runtimeFlag(fs)
+	modelPath := fs.String("model", "", "classifier model (required)")
+	labels := fs.String("labels", strings.Join(classifier.DefaultConfig.Labels, ","), "labels, comma separated or a file with one per line")
+	conf := fs.Float64("conf", float64(classifier.DefaultConfig.ConfThreshold), "confidence threshold, below it the result is \""+classifier.UnknownClass+"\"")
+	topK := fs.Int("topk", classifier.DefaultConfig.TopK, "max labels per image, 0 for all passing labels")
+	activation := fs.String("activation", "none", "activation of the raw outputs: none, softmax or sigmoid")
+	format := fs.String("format", "text", "output format: text or json (one frame per line)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if *modelPath == "" {
+		return usagef("-model is required")
+	}
+	if err := oneOf("format", *format, "text", "json"); err != nil {
+		return err
+	}
+	if err := oneOf("activation", *activation, "none", "softmax", "sigmoid"); err != nil {
+		return err
+	}
+	paths, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	m := deploy.ModelConfig{Kind: deploy.KindClassifier, Path: *modelPath, ConfThreshold: float32(*conf), TopK: *topK, Activation: *activation}
+	if m.Labels, err = parseLabels(*labels); err != nil {
+		return err
+	}
+	config, err := m.ClassifierConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+	model, err := classifier.NewWithConfig(ctx, *modelPath, config)
+	if err != nil {
+		return fmt.Errorf("error initializing classifier: %v", err)
+	}
+	defer model.Destroy()
+
+	enc := json.NewEncoder(stdout)
+	for _, path := range paths {
+		img, err := loadImage(path)
+		if err != nil {
+			return fmt.Errorf("error loading image: %v", err)
+		}
+		classifications, err := model.Classify(img)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		if *format == "json" {
+			b := img.Bounds()
+			frame := results.Frame{SchemaVersion: results.SchemaVersion, Source: path, Width: b.Dx(), Height: b.Dy(),
+				Detections: []results.Detection{}, Classifications: classifications}
+			if err := enc.Encode(frame); err != nil {
+				return err
+			}
+			continue
+		}
+		parts := make([]string, len(classifications))
+		for i, c := range classifications {
+			parts[i] = fmt.Sprintf("%s %.2f", c.Class, c.Confidence)
+		}
+		fmt.Fprintf(stdout, "%s: %s\n", path, strings.Join(parts, ", "))
+	}
+	return nil
+}
@@ -5,22 +5,44 @@ import (
 	"fmt"
 	"image"
 	"os"
-	"sort"
 	"yolo_detection/imageutils"
 
 	onnxruntime "github.com/yalue/onnxruntime_go"
 )
 
-// create new detector
+// create new detector. Thresholds saved next to the model (see
+// ThresholdsPath) replace the defaults.
 func New(ctx context.Context, modelPath string) (*YOLODetector, error){
+	config, _, err := ConfigFor(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithConfig(ctx, modelPath, config)
+}
+
+// ConfigFor returns DefaultConfig with the thresholds saved next to the
+// model, if any, and the path they were loaded from, empty without
+func ConfigFor(modelPath string) (Config, string, error) {
+	config := DefaultConfig
+	path := ThresholdsPath(modelPath)
+	if _, err := os.Stat(path); err != nil {
+		return config, "", nil
+	}
+	thresholds, err := LoadThresholds(path)
+	if err != nil {
+		return config, "", err
+	}
+	return config.WithThresholds(thresholds), path, nil
+}
+
+// create new detector with a custom config
+func NewWithConfig(ctx context.Context, modelPath string, config Config) (*YOLODetector, error){
 	fmt.Println("SCOPE: Detector.New")
 	defer fmt.Println("SCOPE: Detector.New END")
-	
-	INPUT_LAYER_NAME := "images"
-	OUPUT_LAYER_NAME := "output0"
 
-	classes := []string {
-		"cigarettes", "fresh_food_counter", "generic_coffee", "jack_daniels", "redbull", "toffifee",
+	classes := DefaultClasses
+	if len(config.Classes) > 0 {
+		classes = config.Classes
 	}
 
 	// check if file exists
@@ -28,67 +50,115 @@ func New(ctx context.Context, modelPath string) (*YOLODetector, error){
 		return nil, fmt.Errorf("model file not found: %v", err)
 	}
 
-	// pre-allocate input tensor 1, 3, h, w
-	inputShape := onnxruntime.NewShape(1, 3, int64(DefaultConfig.InputHeight), int64(DefaultConfig.InputWidth))
-	inputTensor, err := onnxruntime.NewEmptyTensor[float32](inputShape)
+	inputTensor, outputTensor, session, err := newYOLOv5Session(modelPath, 1, config, len(classes))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create input tensor: %v", err)
+		return nil, err
 	}
-	go func(){
-		// fmt.Println("waiting to destroy inputtensor")
+
+	detector := newDetector(modelPath, config, classes)
+	detector.session = session
+	detector.inputTensor = inputTensor
+	detector.outputTensor = outputTensor
+	detector.inputData = inputTensor.GetData()
+
+	// second session for batched inference (tiles, TTA, ...)
+	if config.BatchSize > 1 {
+		detector.batchInputTensor, detector.batchOutputTensor, detector.batchSession, err =
+			newYOLOv5Session(modelPath, config.BatchSize, config, len(classes))
+		if err != nil {
+			detector.Destroy()
+			return nil, err
+		}
+	}
+	go func() {
 		<-ctx.Done()
-		inputTensor.Destroy()
-		// fmt.Println("destroyed inputTensor")
+		detector.Destroy()
 	}()
-	
+
+	fmt.Printf("Initialized detector with model: %s\n", modelPath)
+    fmt.Printf("Number of classes: %d\n", len(classes))
+    fmt.Printf("Input shape: %v\n", inputTensor.GetShape())
+
+    return detector, nil
+}
+
+// newDetector sets up everything of a detector that does not need the
+// runtime. The input buffer is replaced by the tensor data once the session
+// exists.
+func newDetector(modelPath string, config Config, classes []string) *YOLODetector {
+	preprocessor := newPreprocessor(config)
+	return &YOLODetector{
+		modelPath:    modelPath,
+		preprocessor: preprocessor,
+		classes:      classes,
+		config:       config,
+		inputData:    make([]float32, preprocessor.TensorLen()),
+	}
+}
+
+// newYOLOv5Session allocates input and output tensors for batchSize images
+// and creates a session bound to them. Nothing is left allocated on errors.
+func newYOLOv5Session(modelPath string, batchSize int, config Config, numClasses int) (
+	*onnxruntime.Tensor[float32], *onnxruntime.Tensor[float32], *onnxruntime.Session[float32], error) {
+	INPUT_LAYER_NAME := "images"
+	OUPUT_LAYER_NAME := "output0"
+
+	// pre-allocate input tensor n, 3, h, w (or n, h, w, 3)
+	targetSize := imageutils.ImageSize{Width: config.InputWidth, Height: config.InputHeight}
+	inputShape := onnxruntime.NewShape(config.Preprocess.TensorShape(batchSize, targetSize)...)
+	inputTensor, err := onnxruntime.NewEmptyTensor[float32](inputShape)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create input tensor: %v", err)
+	}
 
 	// pre-allocate output tensor
-	// YOLOv5 -> [1, num pred, num cl + 5]
-	outputShape := []int64{1, 10647, int64(len(classes) + 5)}  // Common YOLOv5 output shape
+	// YOLOv5 -> [n, num pred, num cl + 5], 3 anchors per grid cell
+	numPreds := 3 * numAnchors(config.InputWidth, config.InputHeight)
+	outputShape := onnxruntime.NewShape(int64(batchSize), int64(numPreds), int64(numClasses + 5))
 	outputTensor, err := onnxruntime.NewEmptyTensor[float32](outputShape)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create output tensor: %v", err)
+		inputTensor.Destroy()
+		return nil, nil, nil, fmt.Errorf("failed to create output tensor: %v", err)
 	}
-	go func (){
-		// fmt.Println("waiting to destroy ouputtensor")
-		<-ctx.Done()
-		outputTensor.Destroy()
-		// fmt.Println("destroyed outputtensonr")
-	}()
 
 	// create ONNX runtime session
 	session, err := onnxruntime.NewSession(
         modelPath,
-        []string{INPUT_LAYER_NAME},      // Run options
-        []string{OUPUT_LAYER_NAME},      // Input names (empty means use default names)
-        []*onnxruntime.Tensor[float32]{inputTensor},             // Input tensors
-        []*onnxruntime.Tensor[float32]{outputTensor},             // Output tensors
+        []string{INPUT_LAYER_NAME},
+        []string{OUPUT_LAYER_NAME},
+        []*onnxruntime.Tensor[float32]{inputTensor},
+        []*onnxruntime.Tensor[float32]{outputTensor},
     )
 	if err != nil {
-		return nil, fmt.Errorf("failed to create ONNX session :%v", err)
+		destroySession(nil, inputTensor, outputTensor)
+		return nil, nil, nil, fmt.Errorf("failed to create ONNX session :%v", err)
 	}
-	go func(){
-		// fmt.Println("waiting to destroy detector session")
-		<-ctx.Done()
-        session.Destroy()
-		// fmt.Println("destroyed detector session")	
-	}()
-
 
-	detector := &YOLODetector{
-        modelPath: modelPath,
-        classes:   classes,
-        session:   session,
-        config:    DefaultConfig,
-        inputTensor: inputTensor,
-        outputTensor: outputTensor,
-    }
+	return inputTensor, outputTensor, session, nil
+}
 
-	fmt.Printf("Initialized detector with model: %s\n", modelPath)
-    fmt.Printf("Number of classes: %d\n", len(classes))
-    fmt.Printf("Input shape: %v\n", inputShape)
+// Destroy releases the sessions and tensors of the detector. It is called
+// when the context given to New is done; calling it earlier releases them
+// right away, e.g. before onnxruntime.DestroyEnvironment. The detector can
+// not be used afterwards.
+func (d *YOLODetector) Destroy() {
+	d.destroyOnce.Do(func() {
+		destroySession(d.session, d.inputTensor, d.outputTensor)
+		destroySession(d.batchSession, d.batchInputTensor, d.batchOutputTensor)
+	})
+}
 
-    return detector, nil
+// destroySession releases a session before the tensors bound to it, nil
+// values are skipped
+func destroySession(session *onnxruntime.Session[float32], tensors ...*onnxruntime.Tensor[float32]) {
+	if session != nil {
+		session.Destroy()
+	}
+	for _, t := range tensors {
+		if t != nil {
+			t.Destroy()
+		}
+	}
 }
 
 
@@ -97,40 +167,131 @@ func (d *YOLODetector) RunInferenceOnly() error {
     return d.session.Run()
 }
 
+// Classes returns the class name of every model output
+func (d *YOLODetector) Classes() []string {
+	return d.classes
+}
 
-func (d *YOLODetector) Detect(img image.Image) ([]Detection, error){
+// Thresholds returns the thresholds Detect applies
+func (d *YOLODetector) Thresholds() Thresholds {
+	return d.config.Thresholds()
+}
 
-	targetSize := imageutils.ImageSize{
-        Width:  d.config.InputWidth,
-        Height: d.config.InputHeight,
-    }
-	// preprocessedImg, scale, padLeft, padTop := letterbox(img, targetHeight, targetWidth)
-	// _, letterboxParams := imageutils.Letterbox(img, targetSize)
-	tensorData, params := imageutils.PreprocessImage(img, targetSize)
 
-	// verify data is valid
-	if !imageutils.VerifyTensorData(tensorData) {
-		return nil, fmt.Errorf("invalid tensor data after preprocessing")
+func (d *YOLODetector) Detect(img image.Image) ([]Detection, error){
+
+	// preprocess straight into the input tensor
+	params, err := d.Preprocess(img)
+	if err != nil {
+		return nil, err
 	}
-	
-	// data to input tensor
-	copy(d.inputTensor.GetData(), tensorData)
 
 	// run inference
-	err := d.session.Run()
+	err = d.session.Run()
 	if err != nil {
 		return nil, fmt.Errorf("inference failed: %v", err)
 	}
 
-	// get output
-	outputData := d.outputTensor.GetData()
+    return d.Postprocess(params), nil
+}
+
+// Preprocess letterboxes img into the input tensor. Preprocess,
+// RunInferenceOnly and Postprocess together are Detect, split up so the
+// stages can be timed separately.
+func (d *YOLODetector) Preprocess(img image.Image) (imageutils.LetterboxParams, error) {
+	params, err := d.preprocessor.Run(d.inputData, img)
+	if err != nil {
+		return params, fmt.Errorf("preprocessing failed: %v", err)
+	}
+	return params, nil
+}
+
+// Postprocess decodes the output tensor of the last run
+func (d *YOLODetector) Postprocess(params imageutils.LetterboxParams) []Detection {
+	return d.postprocess(d.outputTensor.GetData(), params)
+}
+
+// DetectBatch runs detection on several images. With Config.BatchSize > 1
+// the images are sent through the batched session in chunks, otherwise
+// they are detected one after another.
+func (d *YOLODetector) DetectBatch(imgs []image.Image) ([][]Detection, error) {
+	results := make([][]Detection, len(imgs))
+
+	if d.batchSession == nil {
+		for i, img := range imgs {
+			detections, err := d.Detect(img)
+			if err != nil {
+				return nil, err
+			}
+			results[i] = detections
+		}
+		return results, nil
+	}
+
+	batchSize := d.config.BatchSize
+	inputData := d.batchInputTensor.GetData()
+	outputData := d.batchOutputTensor.GetData()
+	inputStride := d.preprocessor.TensorLen()
+	outputStride := len(outputData) / batchSize
+	params := make([]imageutils.LetterboxParams, batchSize)
+
+	for start := 0; start < len(imgs); start += batchSize {
+		end := start + batchSize
+		if end > len(imgs) {
+			end = len(imgs)
+		}
 
-	detections := d.processPredictions(outputData, params)
-	// fmt.Printf("found %d detections before NMS\n", len(detections))
+		for i, img := range imgs[start:end] {
+			var err error
+			params[i], err = d.preprocessor.Run(inputData[i*inputStride:(i+1)*inputStride], img)
+			if err != nil {
+				return nil, fmt.Errorf("preprocessing failed: %v", err)
+			}
+		}
+
+		// unused slots of a partial batch are zeroed
+		for i := (end - start) * inputStride; i < len(inputData); i++ {
+			inputData[i] = 0
+		}
+
+		if err := d.batchSession.Run(); err != nil {
+			return nil, fmt.Errorf("batch inference failed: %v", err)
+		}
+
+		for i := 0; i < end-start; i++ {
+			output := outputData[i*outputStride : (i+1)*outputStride]
+			results[start+i] = d.postprocess(output, params[i])
+		}
+	}
+
+	return results, nil
+}
+
+// postprocess turns the raw output of one image into detections in
+// original image coordinates
+func (d *YOLODetector) postprocess(outputData []float32, params imageutils.LetterboxParams) []Detection {
+	detections := d.candidates(outputData, params, d.confThreshold)
+	return d.applyNMS(detections)
+}
+
+// Candidates returns every box above minConfidence before NMS, in original
+// image coordinates. ApplyThresholds turns them into the detections Detect
+// would return for other thresholds, without running the model again.
+func (d *YOLODetector) Candidates(img image.Image, minConfidence float32) ([]Detection, error) {
+	params, err := d.preprocessor.Run(d.inputData, img)
+	if err != nil {
+		return nil, fmt.Errorf("preprocessing failed: %v", err)
+	}
+	if err := d.session.Run(); err != nil {
+		return nil, fmt.Errorf("inference failed: %v", err)
+	}
+	return d.candidates(d.outputTensor.GetData(), params, func(string) float32 { return minConfidence }), nil
+}
+
+// candidates decodes the boxes passing threshold and maps them back
+func (d *YOLODetector) candidates(outputData []float32, params imageutils.LetterboxParams, threshold func(class string) float32) []Detection {
+	detections := d.processPredictions(outputData, threshold)
 
-	detections = d.applyNMS(detections)
-	// fmt.Printf("found %d detections before NMS\n", len(detections))
-	
 	 // Convert back to original image coordinates using existing UnLetterbox
     for i := range detections {
         x1, y1 := imageutils.UnLetterbox(float64(detections[i].Box.X1), float64(detections[i].Box.Y1), params)
@@ -144,13 +305,13 @@ func (d *YOLODetector) Detect(img image.Image) ([]Detection, error){
         }
     }
 
-    return detections, nil
+    return detections
 }
 
 
 // PROCESSING PREDICTIONS
 
-func (d *YOLODetector) processPredictions(outputData []float32, params imageutils.LetterboxParams) []Detection {
+func (d *YOLODetector) processPredictions(outputData []float32, threshold func(class string) float32) []Detection {
 	var detections []Detection
 
 	// calculatte size of prediction
@@ -189,7 +350,7 @@ func (d *YOLODetector) processPredictions(outputData []float32, params imageutil
 
 		confidence := objectness * bestClassScore
 
-		if confidence > d.config.ConfThreshold {
+		if confidence > threshold(d.classes[bestClassIdx]) {
 			x1 := float32(x - w/2)
 			x2 := float32(x + w/2)
 			y1 := float32(y - h/2)
@@ -219,23 +380,7 @@ func (d *YOLODetector) processPredictions(outputData []float32, params imageutil
 
 // NON MAX SUPPRESSION
 func calculateIoU(box1, box2 Box) float32 {
-    x1 := max(box1.X1, box2.X1)
-    y1 := max(box1.Y1, box2.Y1)
-    x2 := min(box1.X2, box2.X2)
-    y2 := min(box1.Y2, box2.Y2)
-
-	if x1 > x2 || y1 > y2 {
-		return 0
-	}
-
-	intersection := (x2 - x1) * (y2 - y1)
-
-	box1Area := (box1.X2 - box1.X1) * (box1.Y2 - box1.Y1)
-	box2Area := (box2.X2 - box2.X1) * (box2.Y2 - box2.Y1)
-	
-	union := box1Area + box2Area - intersection
-
-	return intersection / union
+	return box1.IoU(box2)
 }
 
 func max(a, b float32) float32 {
@@ -252,39 +397,19 @@ func min(a, b float32) float32 {
 	return b
 }
 
+// applyNMS suppresses same-class boxes overlapping by more than
+// IOUThreshold, keeping the most confident one
 func (d *YOLODetector) applyNMS(detections []Detection) []Detection {
-	// no detection -> do nothing
-	if len(detections) == 0 {
-		return detections
-	}
-
-	// sort by confidence
-	sort.Slice(detections, func(i, j int) bool {
-		return detections[i].Confidence > detections[j].Confidence
-	})
-
-	var result []Detection
-	selected := make(map[int]bool)
-
-	for i := 0; i < len(detections); i++ {
-		if selected[i]{
-			continue
-		}
-
-		result = append(result, detections[i])
-		selected[i] = true
-
-		for j := i + 1; j < len(detections); j++ {
-			if selected[j]{
-				continue
-			}
+	return nonMaxSuppression(detections, d.config.IOUThreshold)
+}
 
-			if detections[i].Class == detections[j].Class &&
-				calculateIoU(detections[i].Box, detections[j].Box) > d.config.ConfThreshold {
-					selected[j] = true
-				}
-		}
-	}
-	return result
+// confThreshold returns the confidence threshold of a class
+func (d *YOLODetector) confThreshold(class string) float32 {
+	return d.config.Thresholds().For(class)
 }
 
+// newPreprocessor creates the tensor writer for the configured input
+func newPreprocessor(config Config) *imageutils.Preprocessor {
+	targetSize := imageutils.ImageSize{Width: config.InputWidth, Height: config.InputHeight}
+	return imageutils.NewPreprocessor(targetSize, config.Preprocess, config.PreprocessWorkers)
+}
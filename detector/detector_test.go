@@ -0,0 +1,41 @@
+package detector
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// uniformImage returns a w x h image of a single colour
+func uniformImage(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestPreprocessWithoutModel(t *testing.T) {
+	d := newDetector("model.onnx", DefaultConfig, DefaultClasses)
+
+	params, err := d.Preprocess(uniformImage(640, 320, color.RGBA{R: 255, A: 255}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Scale != 0.65 || params.Top != 104 || params.Bottom != 104 || params.Left != 0 {
+		t.Errorf("unexpected letterbox %+v", params)
+	}
+
+	// NCHW, red plane then green plane
+	size := DefaultConfig.InputWidth * DefaultConfig.InputHeight
+	if len(d.inputData) != 3*size {
+		t.Fatalf("input holds %d values, want %d", len(d.inputData), 3*size)
+	}
+	center := (DefaultConfig.InputHeight/2)*DefaultConfig.InputWidth + DefaultConfig.InputWidth/2
+	if r, g := d.inputData[center], d.inputData[size+center]; r != 1 || g != 0 {
+		t.Errorf("centre pixel r=%v g=%v, want 1 and 0", r, g)
+	}
+	if pad := d.inputData[0]; pad != 0 {
+		t.Errorf("padding %v, want black", pad)
+	}
+}
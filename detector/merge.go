@@ -0,0 +1,162 @@
+package detector
+
+import "sort"
+
+// how detections of overlapping passes (tiles, augmentations) are combined
+type MergeMethod int
+
+const (
+	// keep the most confident box of every overlapping group
+	MergeNMS MergeMethod = iota
+	// average overlapping boxes weighted by confidence (weighted box fusion)
+	MergeWBF
+)
+
+// MergeDetections combines the detections of several passes over the same
+// image. Boxes of the same class overlapping by more than iouThreshold are
+// considered the same object. passes is the number of passes that produced
+// the detections and only matters for MergeWBF.
+func MergeDetections(detections []Detection, method MergeMethod, iouThreshold float32, passes int) []Detection {
+	switch method {
+	case MergeWBF:
+		return fuseBoxes(detections, iouThreshold, passes)
+	default:
+		return nonMaxSuppression(detections, iouThreshold)
+	}
+}
+
+// nonMaxSuppression keeps the most confident box of every group of same-class
+// boxes overlapping by more than iouThreshold
+func nonMaxSuppression(detections []Detection, iouThreshold float32) []Detection {
+	return greedyNMS(detections, func(d Detection) (string, float32) {
+		return d.Class, d.Confidence
+	}, func(a, b Detection) float32 {
+		return calculateIoU(a.Box, b.Box)
+	}, iouThreshold)
+}
+
+// greedyNMS sorts items by confidence, most confident first, and drops every
+// item overlapping a kept item of the same class by more than iouThreshold.
+// score returns the class and confidence of an item, iou the overlap of two
+// items, so axis-aligned and rotated boxes share the same suppression.
+func greedyNMS[T any](items []T, score func(T) (string, float32), iou func(a, b T) float32, iouThreshold float32) []T {
+	// no detection -> do nothing
+	if len(items) == 0 {
+		return items
+	}
+
+	// sort by confidence
+	sort.SliceStable(items, func(i, j int) bool {
+		_, ci := score(items[i])
+		_, cj := score(items[j])
+		return ci > cj
+	})
+
+	var result []T
+	suppressed := make([]bool, len(items))
+
+	for i := range items {
+		if suppressed[i] {
+			continue
+		}
+		result = append(result, items[i])
+		class, _ := score(items[i])
+
+		for j := i + 1; j < len(items); j++ {
+			if suppressed[j] {
+				continue
+			}
+			if other, _ := score(items[j]); other == class && iou(items[i], items[j]) > iouThreshold {
+				suppressed[j] = true
+			}
+		}
+	}
+	return result
+}
+
+// fuseBoxes implements weighted box fusion: overlapping same-class boxes are
+// replaced by their confidence-weighted average. The fused confidence is the
+// mean confidence, reduced when fewer than passes boxes support it.
+func fuseBoxes(detections []Detection, iouThreshold float32, passes int) []Detection {
+	if len(detections) == 0 {
+		return detections
+	}
+	if passes < 1 {
+		passes = 1
+	}
+
+	// sort by confidence
+	sort.Slice(detections, func(i, j int) bool {
+		return detections[i].Confidence > detections[j].Confidence
+	})
+
+	type cluster struct {
+		fused   Detection
+		members []Detection
+	}
+	var clusters []*cluster
+
+	for _, det := range detections {
+		// find the best matching cluster of the same class
+		var best *cluster
+		bestIoU := iouThreshold
+		for _, c := range clusters {
+			if c.fused.Class != det.Class {
+				continue
+			}
+			if iou := calculateIoU(c.fused.Box, det.Box); iou > bestIoU {
+				best, bestIoU = c, iou
+			}
+		}
+
+		if best == nil {
+			clusters = append(clusters, &cluster{fused: det, members: []Detection{det}})
+			continue
+		}
+
+		best.members = append(best.members, det)
+		best.fused.Box = weightedBox(best.members)
+	}
+
+	result := make([]Detection, len(clusters))
+	for i, c := range clusters {
+		var sum float32
+		for _, m := range c.members {
+			sum += m.Confidence
+		}
+		n := len(c.members)
+		confidence := sum / float32(n)
+		if n < passes {
+			confidence *= float32(n) / float32(passes)
+		}
+		c.fused.Confidence = confidence
+		result[i] = c.fused
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Confidence > result[j].Confidence
+	})
+	return result
+}
+
+// weightedBox averages the boxes weighted by their confidence
+func weightedBox(detections []Detection) Box {
+	var box Box
+	var total float32
+	for _, det := range detections {
+		w := det.Confidence
+		box.X1 += det.Box.X1 * w
+		box.Y1 += det.Box.Y1 * w
+		box.X2 += det.Box.X2 * w
+		box.Y2 += det.Box.Y2 * w
+		total += w
+	}
+	if total <= 0 {
+		return detections[0].Box
+	}
+	box.X1 /= total
+	box.Y1 /= total
+	box.X2 /= total
+	box.Y2 /= total
+	return box
+}
@@ -0,0 +1,70 @@
+package detector
+
+import "testing"
+
+// box of width 100 at x, overlapping box(0) by (100-x)/(100+x)
+func shifted(x float32, class string, confidence float32) Detection {
+	return Detection{Box: Box{X1: x, Y1: 0, X2: x + 100, Y2: 100}, Class: class, Confidence: confidence}
+}
+
+func TestApplyNMSUsesIOUThreshold(t *testing.T) {
+	d := newDetector("model.onnx", DefaultConfig, DefaultClasses)
+	if d.config.IOUThreshold != 0.45 || d.config.ConfThreshold != 0.15 {
+		t.Fatalf("test assumes IoU 0.45 and confidence 0.15, got %v and %v", d.config.IOUThreshold, d.config.ConfThreshold)
+	}
+
+	for _, c := range []struct {
+		name string
+		dets []Detection
+		want int
+	}{
+		// IoU 0.54 > 0.45
+		{"overlapping", []Detection{shifted(0, "a", 0.9), shifted(30, "a", 0.8)}, 1},
+		// IoU 0.25, below IOUThreshold but above ConfThreshold
+		{"touching", []Detection{shifted(0, "a", 0.9), shifted(60, "a", 0.8)}, 2},
+		{"other class", []Detection{shifted(0, "a", 0.9), shifted(10, "b", 0.8)}, 2},
+		{"chain", []Detection{shifted(0, "a", 0.9), shifted(30, "a", 0.8), shifted(60, "a", 0.7)}, 2},
+	} {
+		got := d.applyNMS(c.dets)
+		if len(got) != c.want {
+			t.Errorf("%s: kept %d boxes, want %d", c.name, len(got), c.want)
+		}
+		if got[0].Confidence != 0.9 {
+			t.Errorf("%s: first kept box %+v, want the most confident", c.name, got[0])
+		}
+	}
+}
+
+func TestGreedyNMSUsesGivenIoU(t *testing.T) {
+	// items are positions on a line, the overlap of two is 1 - distance/10
+	type item struct {
+		class      string
+		pos        float32
+		confidence float32
+	}
+	score := func(i item) (string, float32) { return i.class, i.confidence }
+	iou := func(a, b item) float32 {
+		d := a.pos - b.pos
+		if d < 0 {
+			d = -d
+		}
+		return 1 - d/10
+	}
+
+	items := []item{{"a", 3, 0.5}, {"a", 0, 0.9}, {"b", 1, 0.8}, {"a", 8, 0.7}, {"a", 9, 0.6}}
+	got := greedyNMS(items, score, iou, 0.5)
+	want := []item{{"a", 0, 0.9}, {"b", 1, 0.8}, {"a", 8, 0.7}}
+	if len(got) != len(want) {
+		t.Fatalf("kept %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("kept %v, want %v", got, want)
+			break
+		}
+	}
+
+	if got := greedyNMS(nil, score, iou, 0.5); len(got) != 0 {
+		t.Errorf("kept %v of nothing", got)
+	}
+}
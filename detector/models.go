@@ -1,29 +1,35 @@
 package detector
 
-import onnxruntime "github.com/yalue/onnxruntime_go"
+import (
+	"sync"
+	"yolo_detection/imageutils"
+	"yolo_detection/results"
+
+	onnxruntime "github.com/yalue/onnxruntime_go"
+)
 
 // bounding box
-type Box struct {
-	X1 float32
-	Y1 float32
-	X2 float32
-	Y2 float32
-}
+type Box = results.Box
 
 // detection (one box with class and confidence)
-type Detection struct {
-	Box		Box
-	Class	string
-	Confidence float32
-}
+type Detection = results.Detection
 
 type YOLODetector struct {
 	modelPath 	string
+	preprocessor *imageutils.Preprocessor
 	classes		[]string
 	session 	*onnxruntime.Session[float32]
 	config		Config
     inputTensor   *onnxruntime.Tensor[float32]
     outputTensor  *onnxruntime.Tensor[float32]
+	inputData     []float32 // backing data of inputTensor
+
+	// only set when Config.BatchSize > 1
+	batchSession      *onnxruntime.Session[float32]
+	batchInputTensor  *onnxruntime.Tensor[float32]
+	batchOutputTensor *onnxruntime.Tensor[float32]
+
+	destroyOnce sync.Once
 }
 
 type Config struct {
@@ -31,6 +37,11 @@ type Config struct {
 	InputHeight 	int
 	ConfThreshold 	float32
 	IOUThreshold 	float32
+	BatchSize 		int // > 1 requires a model exported with a dynamic or matching batch dimension
+	ClassThresholds map[string]float32 // per class confidence thresholds overriding ConfThreshold
+	Classes 		[]string // one name per model output, nil uses DefaultClasses
+	Preprocess 		imageutils.PreprocessSpec
+	PreprocessWorkers int // goroutines used for preprocessing, <= 1 runs inline
 }
 
 var DefaultConfig = Config{
@@ -38,4 +49,86 @@ var DefaultConfig = Config{
 	InputHeight: 	416,
 	ConfThreshold:  0.15,
 	IOUThreshold: 	0.45,
-}
\ No newline at end of file
+	Preprocess: 	imageutils.DefaultPreprocessSpec,
+}
+
+// keypoint (position plus visibility score in [0, 1])
+type Keypoint struct {
+	X          float32
+	Y          float32
+	Visibility float32
+}
+
+// pose detection (one box with its keypoints)
+type PoseDetection struct {
+	Detection
+	Keypoints []Keypoint
+}
+
+type PoseDetector struct {
+	modelPath    string
+	preprocessor *imageutils.Preprocessor
+	classes      []string
+	session      *onnxruntime.Session[float32]
+	config       PoseConfig
+	inputTensor  *onnxruntime.Tensor[float32]
+	outputTensor *onnxruntime.Tensor[float32]
+	destroyOnce  sync.Once
+}
+
+type PoseConfig struct {
+	Config
+	NumKeypoints int
+	KeypointDims int // 2 (x, y) or 3 (x, y, visibility)
+}
+
+// classes of the COCO keypoint models
+var DefaultPoseClasses = []string{"person"}
+
+var DefaultPoseConfig = PoseConfig{
+	Config: Config{
+		InputWidth:    640,
+		InputHeight:   640,
+		ConfThreshold: 0.25,
+		IOUThreshold:  0.45,
+		Preprocess:    imageutils.UltralyticsPreprocessSpec,
+	},
+	NumKeypoints: 17,
+	KeypointDims: 3,
+}
+
+// oriented detection (one rotated box with class and confidence)
+type OBBDetection struct {
+	Box        RotatedBox
+	Class      string
+	Confidence float32
+}
+
+// Polygon returns the corners of the rotated box in image coordinates
+func (d OBBDetection) Polygon() Polygon {
+	return d.Box.Polygon()
+}
+
+type OBBDetector struct {
+	modelPath    string
+	preprocessor *imageutils.Preprocessor
+	classes      []string
+	session      *onnxruntime.Session[float32]
+	config       Config
+	inputTensor  *onnxruntime.Tensor[float32]
+	outputTensor *onnxruntime.Tensor[float32]
+	destroyOnce  sync.Once
+}
+
+var DefaultOBBConfig = Config{
+	InputWidth:    640,
+	InputHeight:   640,
+	ConfThreshold: 0.25,
+	IOUThreshold:  0.45,
+	Preprocess:    imageutils.UltralyticsPreprocessSpec,
+}
+
+// classes of the retail models
+var DefaultClasses = []string{
+	"cigarettes", "fresh_food_counter", "generic_coffee", "jack_daniels", "redbull", "toffifee",
+}
@@ -0,0 +1,175 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"yolo_detection/imageutils"
+
+	onnxruntime "github.com/yalue/onnxruntime_go"
+)
+
+// create new oriented bounding box detector (YOLOv8-obb style model)
+func NewOBB(ctx context.Context, modelPath string) (*OBBDetector, error) {
+	return NewOBBWithConfig(ctx, modelPath, DefaultOBBConfig)
+}
+
+// create new oriented bounding box detector with a custom config, e.g.
+// another input size or the classes of a DOTA model. Classes default to
+// DefaultClasses.
+func NewOBBWithConfig(ctx context.Context, modelPath string, config Config) (*OBBDetector, error) {
+	INPUT_LAYER_NAME := "images"
+	OUTPUT_LAYER_NAME := "output0"
+
+	classes := DefaultClasses
+	if len(config.Classes) > 0 {
+		classes = config.Classes
+	}
+
+	// check if file exists
+	if _, err := os.Stat(modelPath); err != nil {
+		return nil, fmt.Errorf("model file not found: %v", err)
+	}
+
+	// pre-allocate input tensor 1, 3, h, w (or 1, h, w, 3)
+	targetSize := imageutils.ImageSize{Width: config.InputWidth, Height: config.InputHeight}
+	inputShape := onnxruntime.NewShape(config.Preprocess.TensorShape(1, targetSize)...)
+	inputTensor, err := onnxruntime.NewEmptyTensor[float32](inputShape)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input tensor: %v", err)
+	}
+
+	// pre-allocate output tensor
+	// YOLOv8-obb -> [1, 4 + num cl + 1 (angle), num anchors]
+	channels := 4 + len(classes) + 1
+	anchors := numAnchors(config.InputWidth, config.InputHeight)
+	outputShape := onnxruntime.NewShape(1, int64(channels), int64(anchors))
+	outputTensor, err := onnxruntime.NewEmptyTensor[float32](outputShape)
+	if err != nil {
+		inputTensor.Destroy()
+		return nil, fmt.Errorf("failed to create output tensor: %v", err)
+	}
+
+	// create ONNX runtime session
+	session, err := onnxruntime.NewSession(
+		modelPath,
+		[]string{INPUT_LAYER_NAME},
+		[]string{OUTPUT_LAYER_NAME},
+		[]*onnxruntime.Tensor[float32]{inputTensor},
+		[]*onnxruntime.Tensor[float32]{outputTensor},
+	)
+	if err != nil {
+		destroySession(nil, inputTensor, outputTensor)
+		return nil, fmt.Errorf("failed to create ONNX session :%v", err)
+	}
+
+	detector := &OBBDetector{
+		modelPath:    modelPath,
+		preprocessor: newPreprocessor(config),
+		classes:      classes,
+		session:      session,
+		config:       config,
+		inputTensor:  inputTensor,
+		outputTensor: outputTensor,
+	}
+	go func() {
+		<-ctx.Done()
+		detector.Destroy()
+	}()
+	return detector, nil
+}
+
+// Destroy releases the session and tensors of the detector, see
+// YOLODetector.Destroy
+func (d *OBBDetector) Destroy() {
+	d.destroyOnce.Do(func() {
+		destroySession(d.session, d.inputTensor, d.outputTensor)
+	})
+}
+
+// RunInferenceOnly executes just the neural network session.Run() step
+func (d *OBBDetector) RunInferenceOnly() error {
+	return d.session.Run()
+}
+
+func (d *OBBDetector) Detect(img image.Image) ([]OBBDetection, error) {
+	// preprocess straight into the input tensor
+	params, err := d.preprocessor.Run(d.inputTensor.GetData(), img)
+	if err != nil {
+		return nil, fmt.Errorf("preprocessing failed: %v", err)
+	}
+
+	// run inference
+	if err := d.session.Run(); err != nil {
+		return nil, fmt.Errorf("inference failed: %v", err)
+	}
+
+	detections := d.processPredictions(d.outputTensor.GetData())
+	detections = d.applyNMS(detections)
+
+	// convert back to original image coordinates, the angle is only
+	// preserved exactly for uniform scaling (letterbox, center crop)
+	for i := range detections {
+		box := &detections[i].Box
+		cx, cy := imageutils.UnLetterbox(float64(box.CX), float64(box.CY), params)
+		w, h := imageutils.UnLetterboxSize(float64(box.W), float64(box.H), params)
+		box.CX, box.CY = float32(cx), float32(cy)
+		box.W, box.H = float32(w), float32(h)
+	}
+
+	return detections, nil
+}
+
+// processPredictions decodes the channel-first output [C, N] of an obb head
+func (d *OBBDetector) processPredictions(outputData []float32) []OBBDetection {
+	var detections []OBBDetection
+
+	numClasses := len(d.classes)
+	channels := 4 + numClasses + 1
+	numPreds := len(outputData) / channels
+
+	// value of channel c for prediction i
+	at := func(c, i int) float32 {
+		return outputData[c*numPreds+i]
+	}
+
+	for i := 0; i < numPreds; i++ {
+		bestClassScore := float32(-1)
+		bestClassIdx := 0
+		for j := 0; j < numClasses; j++ {
+			score := at(4+j, i)
+			if score > bestClassScore {
+				bestClassScore = score
+				bestClassIdx = j
+			}
+		}
+
+		if bestClassScore <= d.config.ConfThreshold {
+			continue
+		}
+
+		detections = append(detections, OBBDetection{
+			Box: RotatedBox{
+				CX:    at(0, i),
+				CY:    at(1, i),
+				W:     at(2, i),
+				H:     at(3, i),
+				Angle: at(4+numClasses, i),
+			},
+			Class:      d.classes[bestClassIdx],
+			Confidence: bestClassScore,
+		})
+	}
+
+	return detections
+}
+
+// applyNMS suppresses overlapping rotated boxes of the same class
+func (d *OBBDetector) applyNMS(detections []OBBDetection) []OBBDetection {
+	return greedyNMS(detections, func(o OBBDetection) (string, float32) {
+		return o.Class, o.Confidence
+	}, func(a, b OBBDetection) float32 {
+		return calculateRotatedIoU(a.Box, b.Box)
+	}, d.config.IOUThreshold)
+}
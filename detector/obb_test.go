@@ -0,0 +1,69 @@
+package detector
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOBBProcessPredictions(t *testing.T) {
+	d := &OBBDetector{classes: []string{"a", "b"}, config: DefaultOBBConfig}
+	output := channelFirst([][]float32{
+		// cx, cy, w, h, class a, class b, angle
+		{100, 50, 40, 20, 0.1, 0.8, 0.5},
+		{200, 60, 10, 10, 0.2, 0.1, 0}, // below the threshold
+		{30, 40, 8, 6, 0.6, 0.3, -1.2},
+	})
+
+	got := d.processPredictions(output)
+	want := []OBBDetection{
+		{Box: RotatedBox{CX: 100, CY: 50, W: 40, H: 20, Angle: 0.5}, Class: "b", Confidence: 0.8},
+		{Box: RotatedBox{CX: 30, CY: 40, W: 8, H: 6, Angle: -1.2}, Class: "a", Confidence: 0.6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("processPredictions =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestOBBApplyNMS(t *testing.T) {
+	d := &OBBDetector{classes: []string{"a", "b"}, config: DefaultOBBConfig}
+	obb := func(cx, angle float32, class string, confidence float32) OBBDetection {
+		return OBBDetection{Box: RotatedBox{CX: cx, W: 4, H: 4, Angle: angle}, Class: class, Confidence: confidence}
+	}
+
+	for _, c := range []struct {
+		name string
+		dets []OBBDetection
+		want int
+	}{
+		// IoU 0.71 of a square and its 45 degree rotation
+		{"rotated copy", []OBBDetection{obb(0, 0, "a", 0.9), obb(0, 0.785398, "a", 0.8)}, 1},
+		{"other class", []OBBDetection{obb(0, 0, "a", 0.9), obb(0, 0.785398, "b", 0.8)}, 2},
+		// IoU 0.33 when shifted by half the width
+		{"half shifted", []OBBDetection{obb(0, 0, "a", 0.9), obb(2, 0, "a", 0.8)}, 2},
+		// axis-aligned bounds of the two diagonals coincide, the boxes only
+		// share their centre
+		{"crossing thin boxes", []OBBDetection{
+			{Box: RotatedBox{W: 10, H: 1, Angle: 0.785398}, Class: "a", Confidence: 0.9},
+			{Box: RotatedBox{W: 10, H: 1, Angle: -0.785398}, Class: "a", Confidence: 0.8},
+		}, 2},
+		{"empty", nil, 0},
+	} {
+		got := d.applyNMS(c.dets)
+		if len(got) != c.want {
+			t.Errorf("%s: kept %d boxes, want %d", c.name, len(got), c.want)
+		}
+		if len(got) > 0 && got[0].Confidence != 0.9 {
+			t.Errorf("%s: best box not kept first: %+v", c.name, got)
+		}
+	}
+}
+
+func TestNewOBBWithConfigMissingModel(t *testing.T) {
+	config := DefaultOBBConfig
+	config.Classes = []string{"plane", "ship"}
+	if _, err := NewOBBWithConfig(context.Background(), "missing.onnx", config); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("got %v, want a missing model error", err)
+	}
+}
@@ -0,0 +1,149 @@
+package detector
+
+import "math"
+
+// point in image coordinates
+type Point struct {
+	X float32 `json:"x"`
+	Y float32 `json:"y"`
+}
+
+// polygon given by its vertices in order
+type Polygon []Point
+
+// Area returns the absolute area using the shoelace formula
+func (p Polygon) Area() float32 {
+	if len(p) < 3 {
+		return 0
+	}
+	var sum float32
+	for i := range p {
+		j := (i + 1) % len(p)
+		sum += p[i].X*p[j].Y - p[j].X*p[i].Y
+	}
+	if sum < 0 {
+		sum = -sum
+	}
+	return sum / 2
+}
+
+// Bounds returns the axis-aligned box enclosing the polygon
+func (p Polygon) Bounds() Box {
+	if len(p) == 0 {
+		return Box{}
+	}
+	box := Box{X1: p[0].X, Y1: p[0].Y, X2: p[0].X, Y2: p[0].Y}
+	for _, pt := range p[1:] {
+		box.X1 = min(box.X1, pt.X)
+		box.Y1 = min(box.Y1, pt.Y)
+		box.X2 = max(box.X2, pt.X)
+		box.Y2 = max(box.Y2, pt.Y)
+	}
+	return box
+}
+
+// Contains reports whether pt lies inside the polygon (even-odd rule)
+func (p Polygon) Contains(pt Point) bool {
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Y > pt.Y) != (b.Y > pt.Y) &&
+			pt.X < (b.X-a.X)*(pt.Y-a.Y)/(b.Y-a.Y)+a.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// orientation returns +1 for counter-clockwise vertex order (in a y-down
+// image that is clockwise on screen), -1 otherwise
+func (p Polygon) orientation() float32 {
+	var sum float32
+	for i := range p {
+		j := (i + 1) % len(p)
+		sum += p[i].X*p[j].Y - p[j].X*p[i].Y
+	}
+	if sum < 0 {
+		return -1
+	}
+	return 1
+}
+
+// ClipConvex returns the intersection of p with the convex polygon clip
+// (Sutherland-Hodgman)
+func (p Polygon) ClipConvex(clip Polygon) Polygon {
+	if len(p) < 3 || len(clip) < 3 {
+		return nil
+	}
+	sign := clip.orientation()
+
+	// side > 0 when pt is on the inner side of edge a->b
+	side := func(a, b, pt Point) float32 {
+		return sign * ((b.X-a.X)*(pt.Y-a.Y) - (b.Y-a.Y)*(pt.X-a.X))
+	}
+	intersect := func(a, b, s, e Point) Point {
+		da := side(a, b, s)
+		db := side(a, b, e)
+		t := da / (da - db)
+		return Point{X: s.X + t*(e.X-s.X), Y: s.Y + t*(e.Y-s.Y)}
+	}
+
+	output := append(Polygon(nil), p...)
+	for i := range clip {
+		a, b := clip[i], clip[(i+1)%len(clip)]
+		input := output
+		output = nil
+		if len(input) == 0 {
+			break
+		}
+		s := input[len(input)-1]
+		for _, e := range input {
+			if side(a, b, e) >= 0 {
+				if side(a, b, s) < 0 {
+					output = append(output, intersect(a, b, s, e))
+				}
+				output = append(output, e)
+			} else if side(a, b, s) >= 0 {
+				output = append(output, intersect(a, b, s, e))
+			}
+			s = e
+		}
+	}
+	return output
+}
+
+// rotated bounding box, angle in radians
+type RotatedBox struct {
+	CX    float32
+	CY    float32
+	W     float32
+	H     float32
+	Angle float32
+}
+
+// Polygon returns the four corners of the rotated box
+func (r RotatedBox) Polygon() Polygon {
+	cos := float32(math.Cos(float64(r.Angle)))
+	sin := float32(math.Sin(float64(r.Angle)))
+	hw, hh := r.W/2, r.H/2
+
+	corners := [4][2]float32{{-hw, -hh}, {hw, -hh}, {hw, hh}, {-hw, hh}}
+	poly := make(Polygon, 4)
+	for i, c := range corners {
+		poly[i] = Point{
+			X: r.CX + c[0]*cos - c[1]*sin,
+			Y: r.CY + c[0]*sin + c[1]*cos,
+		}
+	}
+	return poly
+}
+
+// calculateRotatedIoU computes the IoU of two rotated boxes via polygon clipping
+func calculateRotatedIoU(box1, box2 RotatedBox) float32 {
+	intersection := box1.Polygon().ClipConvex(box2.Polygon()).Area()
+	union := box1.W*box1.H + box2.W*box2.H - intersection
+	if union <= 0 {
+		return 0
+	}
+	return intersection / union
+}
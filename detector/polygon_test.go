@@ -0,0 +1,125 @@
+package detector
+
+import (
+	"math"
+	"testing"
+)
+
+func square(x1, y1, x2, y2 float32) Polygon {
+	return Polygon{{X: x1, Y: y1}, {X: x2, Y: y1}, {X: x2, Y: y2}, {X: x1, Y: y2}}
+}
+
+func reversed(p Polygon) Polygon {
+	r := make(Polygon, len(p))
+	for i, pt := range p {
+		r[len(p)-1-i] = pt
+	}
+	return r
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+func TestPolygonArea(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		p    Polygon
+		want float32
+	}{
+		{"square", square(0, 0, 2, 3), 6},
+		{"reversed square", reversed(square(0, 0, 2, 3)), 6},
+		{"triangle", Polygon{{0, 0}, {4, 0}, {0, 3}}, 6},
+		{"line", Polygon{{0, 0}, {4, 0}}, 0},
+	} {
+		if got := tc.p.Area(); got != tc.want {
+			t.Errorf("%s: Area = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPolygonContains(t *testing.T) {
+	p := Polygon{{0, 0}, {4, 0}, {0, 4}}
+	for _, tc := range []struct {
+		pt   Point
+		want bool
+	}{
+		{Point{1, 1}, true},
+		{Point{3, 3}, false},
+		{Point{-1, 1}, false},
+		{Point{1, 5}, false},
+	} {
+		if got := p.Contains(tc.pt); got != tc.want {
+			t.Errorf("Contains(%v) = %v, want %v", tc.pt, got, tc.want)
+		}
+	}
+}
+
+func TestClipConvex(t *testing.T) {
+	unit := square(0, 0, 2, 2)
+	for _, tc := range []struct {
+		name       string
+		p, clip    Polygon
+		wantArea   float32
+		wantBounds Box
+	}{
+		{"quarter overlap", unit, square(1, 1, 3, 3), 1, Box{X1: 1, Y1: 1, X2: 2, Y2: 2}},
+		{"reversed clip", unit, reversed(square(1, 1, 3, 3)), 1, Box{X1: 1, Y1: 1, X2: 2, Y2: 2}},
+		{"reversed subject", reversed(unit), square(1, 1, 3, 3), 1, Box{X1: 1, Y1: 1, X2: 2, Y2: 2}},
+		{"inside", square(0.5, 0.5, 1.5, 1), unit, 0.5, Box{X1: 0.5, Y1: 0.5, X2: 1.5, Y2: 1}},
+		{"enclosing", square(-1, -1, 3, 3), unit, 4, Box{X1: 0, Y1: 0, X2: 2, Y2: 2}},
+		{"triangle cut by a strip", Polygon{{0, 0}, {4, 0}, {0, 4}}, square(-1, 1, 5, 2), 2.5, Box{X1: 0, Y1: 1, X2: 3, Y2: 2}},
+		{"disjoint", unit, square(5, 5, 6, 6), 0, Box{}},
+	} {
+		got := tc.p.ClipConvex(tc.clip)
+		if area := got.Area(); !near(area, tc.wantArea) {
+			t.Errorf("%s: area %v, want %v (%v)", tc.name, area, tc.wantArea, got)
+		}
+		b := got.Bounds()
+		if !near(b.X1, tc.wantBounds.X1) || !near(b.Y1, tc.wantBounds.Y1) || !near(b.X2, tc.wantBounds.X2) || !near(b.Y2, tc.wantBounds.Y2) {
+			t.Errorf("%s: bounds %+v, want %+v", tc.name, b, tc.wantBounds)
+		}
+	}
+
+	if got := unit.ClipConvex(Polygon{{0, 0}, {1, 1}}); got != nil {
+		t.Errorf("clip by a line = %v, want nil", got)
+	}
+}
+
+func TestRotatedBoxPolygon(t *testing.T) {
+	p := RotatedBox{CX: 10, CY: 20, W: 4, H: 2, Angle: math.Pi / 2}.Polygon()
+	// a quarter turn swaps width and height
+	b := p.Bounds()
+	if !near(b.X1, 9) || !near(b.X2, 11) || !near(b.Y1, 18) || !near(b.Y2, 22) {
+		t.Errorf("bounds %+v, want 9,18 - 11,22", b)
+	}
+	if !near(p.Area(), 8) {
+		t.Errorf("area %v, want 8", p.Area())
+	}
+}
+
+func TestCalculateRotatedIoU(t *testing.T) {
+	box := RotatedBox{CX: 0, CY: 0, W: 2, H: 2}
+	for _, tc := range []struct {
+		name string
+		a, b RotatedBox
+		want float32
+	}{
+		{"identical", box, box, 1},
+		{"square turned a quarter", box, RotatedBox{W: 2, H: 2, Angle: math.Pi / 2}, 1},
+		{"rectangle turned a quarter", RotatedBox{W: 4, H: 2}, RotatedBox{W: 4, H: 2, Angle: math.Pi / 2}, 4.0 / 12},
+		// the overlap is a regular octagon of area 8(sqrt2-1)
+		{"square turned 45 degrees", box, RotatedBox{W: 2, H: 2, Angle: math.Pi / 4}, math.Sqrt2 / 2},
+		{"half shifted", box, RotatedBox{CX: 1, W: 2, H: 2}, 2.0 / 6},
+		{"contained", RotatedBox{W: 4, H: 4, Angle: 0.3}, RotatedBox{W: 2, H: 2, Angle: 0.3}, 0.25},
+		{"disjoint", box, RotatedBox{CX: 5, W: 2, H: 2, Angle: 0.5}, 0},
+		{"empty", RotatedBox{}, RotatedBox{}, 0},
+	} {
+		if got := calculateRotatedIoU(tc.a, tc.b); !near(got, tc.want) {
+			t.Errorf("%s: IoU = %v, want %v", tc.name, got, tc.want)
+		}
+		if got := calculateRotatedIoU(tc.b, tc.a); !near(got, tc.want) {
+			t.Errorf("%s swapped: IoU = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
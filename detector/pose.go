@@ -0,0 +1,208 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"os"
+	"yolo_detection/imageutils"
+
+	onnxruntime "github.com/yalue/onnxruntime_go"
+)
+
+// create new pose detector (YOLOv8-pose style model)
+func NewPose(ctx context.Context, modelPath string) (*PoseDetector, error) {
+	return NewPoseWithConfig(ctx, modelPath, DefaultPoseConfig)
+}
+
+// create new pose detector with a custom config, e.g. another input size or
+// keypoint layout. Classes default to DefaultPoseClasses.
+func NewPoseWithConfig(ctx context.Context, modelPath string, config PoseConfig) (*PoseDetector, error) {
+	INPUT_LAYER_NAME := "images"
+	OUTPUT_LAYER_NAME := "output0"
+
+	classes := DefaultPoseClasses
+	if len(config.Classes) > 0 {
+		classes = config.Classes
+	}
+	if config.NumKeypoints <= 0 {
+		return nil, fmt.Errorf("invalid number of keypoints %d", config.NumKeypoints)
+	}
+	if config.KeypointDims != 2 && config.KeypointDims != 3 {
+		return nil, fmt.Errorf("keypoint dims must be 2 or 3, got %d", config.KeypointDims)
+	}
+
+	// check if file exists
+	if _, err := os.Stat(modelPath); err != nil {
+		return nil, fmt.Errorf("model file not found: %v", err)
+	}
+
+	// pre-allocate input tensor 1, 3, h, w (or 1, h, w, 3)
+	targetSize := imageutils.ImageSize{Width: config.InputWidth, Height: config.InputHeight}
+	inputShape := onnxruntime.NewShape(config.Preprocess.TensorShape(1, targetSize)...)
+	inputTensor, err := onnxruntime.NewEmptyTensor[float32](inputShape)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input tensor: %v", err)
+	}
+
+	// pre-allocate output tensor
+	// YOLOv8-pose -> [1, 4 + num cl + num kpt * kpt dims, num anchors]
+	channels := 4 + len(classes) + config.NumKeypoints*config.KeypointDims
+	anchors := numAnchors(config.InputWidth, config.InputHeight)
+	outputShape := onnxruntime.NewShape(1, int64(channels), int64(anchors))
+	outputTensor, err := onnxruntime.NewEmptyTensor[float32](outputShape)
+	if err != nil {
+		inputTensor.Destroy()
+		return nil, fmt.Errorf("failed to create output tensor: %v", err)
+	}
+
+	// create ONNX runtime session
+	session, err := onnxruntime.NewSession(
+		modelPath,
+		[]string{INPUT_LAYER_NAME},
+		[]string{OUTPUT_LAYER_NAME},
+		[]*onnxruntime.Tensor[float32]{inputTensor},
+		[]*onnxruntime.Tensor[float32]{outputTensor},
+	)
+	if err != nil {
+		destroySession(nil, inputTensor, outputTensor)
+		return nil, fmt.Errorf("failed to create ONNX session :%v", err)
+	}
+
+	detector := &PoseDetector{
+		modelPath:    modelPath,
+		preprocessor: newPreprocessor(config.Config),
+		classes:      classes,
+		session:      session,
+		config:       config,
+		inputTensor:  inputTensor,
+		outputTensor: outputTensor,
+	}
+	go func() {
+		<-ctx.Done()
+		detector.Destroy()
+	}()
+	return detector, nil
+}
+
+// Destroy releases the session and tensors of the detector, see
+// YOLODetector.Destroy
+func (d *PoseDetector) Destroy() {
+	d.destroyOnce.Do(func() {
+		destroySession(d.session, d.inputTensor, d.outputTensor)
+	})
+}
+
+// numAnchors returns the number of predictions of an anchor-free YOLOv8 head
+// with strides 8, 16 and 32
+func numAnchors(width, height int) int {
+	n := 0
+	for _, stride := range []int{8, 16, 32} {
+		n += (width / stride) * (height / stride)
+	}
+	return n
+}
+
+// RunInferenceOnly executes just the neural network session.Run() step
+func (d *PoseDetector) RunInferenceOnly() error {
+	return d.session.Run()
+}
+
+func (d *PoseDetector) Detect(img image.Image) ([]PoseDetection, error) {
+	// preprocess straight into the input tensor
+	params, err := d.preprocessor.Run(d.inputTensor.GetData(), img)
+	if err != nil {
+		return nil, fmt.Errorf("preprocessing failed: %v", err)
+	}
+
+	// run inference
+	if err := d.session.Run(); err != nil {
+		return nil, fmt.Errorf("inference failed: %v", err)
+	}
+
+	detections := d.processPredictions(d.outputTensor.GetData())
+	detections = d.applyNMS(detections)
+
+	// convert back to original image coordinates
+	for i := range detections {
+		x1, y1 := imageutils.UnLetterbox(float64(detections[i].Box.X1), float64(detections[i].Box.Y1), params)
+		x2, y2 := imageutils.UnLetterbox(float64(detections[i].Box.X2), float64(detections[i].Box.Y2), params)
+		detections[i].Box = Box{X1: float32(x1), Y1: float32(y1), X2: float32(x2), Y2: float32(y2)}
+
+		for k := range detections[i].Keypoints {
+			kp := &detections[i].Keypoints[k]
+			x, y := imageutils.UnLetterbox(float64(kp.X), float64(kp.Y), params)
+			kp.X, kp.Y = float32(x), float32(y)
+		}
+	}
+
+	return detections, nil
+}
+
+// processPredictions decodes the channel-first output [C, N] of a pose head
+func (d *PoseDetector) processPredictions(outputData []float32) []PoseDetection {
+	var detections []PoseDetection
+
+	numClasses := len(d.classes)
+	channels := 4 + numClasses + d.config.NumKeypoints*d.config.KeypointDims
+	numPreds := len(outputData) / channels
+
+	// value of channel c for prediction i
+	at := func(c, i int) float32 {
+		return outputData[c*numPreds+i]
+	}
+
+	for i := 0; i < numPreds; i++ {
+		// best class (no objectness in YOLOv8)
+		bestClassScore := float32(-1)
+		bestClassIdx := 0
+		for j := 0; j < numClasses; j++ {
+			score := at(4+j, i)
+			if score > bestClassScore {
+				bestClassScore = score
+				bestClassIdx = j
+			}
+		}
+
+		if bestClassScore <= d.config.ConfThreshold {
+			continue
+		}
+
+		x, y, w, h := at(0, i), at(1, i), at(2, i), at(3, i)
+
+		keypoints := make([]Keypoint, d.config.NumKeypoints)
+		base := 4 + numClasses
+		for k := range keypoints {
+			c := base + k*d.config.KeypointDims
+			keypoints[k] = Keypoint{X: at(c, i), Y: at(c+1, i), Visibility: 1}
+			if d.config.KeypointDims > 2 {
+				keypoints[k].Visibility = at(c+2, i)
+			}
+		}
+
+		detections = append(detections, PoseDetection{
+			Detection: Detection{
+				Box: Box{
+					X1: x - w/2,
+					Y1: y - h/2,
+					X2: x + w/2,
+					Y2: y + h/2,
+				},
+				Class:      d.classes[bestClassIdx],
+				Confidence: bestClassScore,
+			},
+			Keypoints: keypoints,
+		})
+	}
+
+	return detections
+}
+
+// applyNMS suppresses overlapping poses of the same class by their boxes
+func (d *PoseDetector) applyNMS(detections []PoseDetection) []PoseDetection {
+	return greedyNMS(detections, func(p PoseDetection) (string, float32) {
+		return p.Class, p.Confidence
+	}, func(a, b PoseDetection) float32 {
+		return calculateIoU(a.Box, b.Box)
+	}, d.config.IOUThreshold)
+}
@@ -0,0 +1,109 @@
+package detector
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// channelFirst lays out one row of values per prediction as a [C, N] output
+// tensor
+func channelFirst(preds [][]float32) []float32 {
+	channels := len(preds[0])
+	out := make([]float32, channels*len(preds))
+	for i, pred := range preds {
+		for c, v := range pred {
+			out[c*len(preds)+i] = v
+		}
+	}
+	return out
+}
+
+func testPoseDetector(keypointDims int) *PoseDetector {
+	config := DefaultPoseConfig
+	config.NumKeypoints = 2
+	config.KeypointDims = keypointDims
+	return &PoseDetector{classes: []string{"person"}, config: config}
+}
+
+func TestPoseProcessPredictions(t *testing.T) {
+	d := testPoseDetector(3)
+	output := channelFirst([][]float32{
+		// cx, cy, w, h, person, 2 keypoints of x, y, visibility
+		{100, 100, 40, 80, 0.9, 90, 80, 0.8, 110, 120, 0.1},
+		{300, 200, 20, 20, 0.2, 0, 0, 0, 0, 0, 0}, // below the threshold
+		{50, 60, 10, 20, 0.5, 52, 55, 1, 48, 65, 0.5},
+	})
+
+	got := d.processPredictions(output)
+	want := []PoseDetection{
+		{
+			Detection: Detection{Box: Box{X1: 80, Y1: 60, X2: 120, Y2: 140}, Class: "person", Confidence: 0.9},
+			Keypoints: []Keypoint{{X: 90, Y: 80, Visibility: 0.8}, {X: 110, Y: 120, Visibility: 0.1}},
+		},
+		{
+			Detection: Detection{Box: Box{X1: 45, Y1: 50, X2: 55, Y2: 70}, Class: "person", Confidence: 0.5},
+			Keypoints: []Keypoint{{X: 52, Y: 55, Visibility: 1}, {X: 48, Y: 65, Visibility: 0.5}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("processPredictions =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestPoseProcessPredictionsWithoutVisibility(t *testing.T) {
+	d := testPoseDetector(2)
+	output := channelFirst([][]float32{
+		{100, 100, 40, 80, 0.9, 90, 80, 110, 120},
+	})
+
+	got := d.processPredictions(output)
+	if len(got) != 1 {
+		t.Fatalf("got %d detections, want 1", len(got))
+	}
+	want := []Keypoint{{X: 90, Y: 80, Visibility: 1}, {X: 110, Y: 120, Visibility: 1}}
+	if !reflect.DeepEqual(got[0].Keypoints, want) {
+		t.Errorf("keypoints = %+v, want %+v", got[0].Keypoints, want)
+	}
+}
+
+func TestPoseApplyNMS(t *testing.T) {
+	d := testPoseDetector(3)
+	pose := func(x float32, confidence float32) PoseDetection {
+		return PoseDetection{Detection: shifted(x, "person", confidence), Keypoints: []Keypoint{{X: x}}}
+	}
+
+	// IoU 0.54 with the best box, the third only touches it
+	got := d.applyNMS([]PoseDetection{pose(30, 0.8), pose(0, 0.9), pose(60, 0.7)})
+	if len(got) != 2 {
+		t.Fatalf("kept %d poses, want 2", len(got))
+	}
+	// the keypoints stay with their box
+	if got[0].Confidence != 0.9 || got[0].Keypoints[0].X != 0 || got[1].Keypoints[0].X != 60 {
+		t.Errorf("kept %+v", got)
+	}
+}
+
+func TestNewPoseWithConfigRejectsInvalidConfig(t *testing.T) {
+	for _, c := range []struct {
+		name         string
+		keypoints    int
+		keypointDims int
+	}{
+		{"no keypoints", 0, 3},
+		{"one dim", 17, 1},
+		{"four dims", 17, 4},
+	} {
+		config := DefaultPoseConfig
+		config.NumKeypoints = c.keypoints
+		config.KeypointDims = c.keypointDims
+		if _, err := NewPoseWithConfig(context.Background(), "missing.onnx", config); err == nil || !strings.Contains(err.Error(), "keypoint") {
+			t.Errorf("%s: got %v, want a keypoint error", c.name, err)
+		}
+	}
+
+	if _, err := NewPoseWithConfig(context.Background(), "missing.onnx", DefaultPoseConfig); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("got %v, want a missing model error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Thresholds are the tunable decision thresholds of a detector, saved next
+// to the model so New picks them up
+type Thresholds struct {
+	ConfThreshold   float32            `json:"conf_threshold"`
+	IOUThreshold    float32            `json:"iou_threshold"`
+	ClassThresholds map[string]float32 `json:"class_thresholds,omitempty"` // override ConfThreshold per class
+}
+
+// For returns the confidence threshold of a class
+func (t Thresholds) For(class string) float32 {
+	if c, ok := t.ClassThresholds[class]; ok {
+		return c
+	}
+	return t.ConfThreshold
+}
+
+// Thresholds returns the thresholds of the config
+func (c Config) Thresholds() Thresholds {
+	return Thresholds{
+		ConfThreshold:   c.ConfThreshold,
+		IOUThreshold:    c.IOUThreshold,
+		ClassThresholds: c.ClassThresholds,
+	}
+}
+
+// WithThresholds returns a copy of the config using the given thresholds
+func (c Config) WithThresholds(t Thresholds) Config {
+	c.ConfThreshold = t.ConfThreshold
+	c.IOUThreshold = t.IOUThreshold
+	c.ClassThresholds = t.ClassThresholds
+	return c
+}
+
+// ThresholdsPath returns where the thresholds of a model are stored,
+// models/yolo.onnx -> models/yolo.thresholds.json
+func ThresholdsPath(modelPath string) string {
+	return strings.TrimSuffix(modelPath, filepath.Ext(modelPath)) + ".thresholds.json"
+}
+
+// LoadThresholds reads thresholds written by Save
+func LoadThresholds(path string) (Thresholds, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Thresholds{}, fmt.Errorf("failed to read thresholds: %v", err)
+	}
+	var t Thresholds
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Thresholds{}, fmt.Errorf("failed to parse thresholds %s: %v", path, err)
+	}
+	if err := t.Validate(); err != nil {
+		return Thresholds{}, fmt.Errorf("invalid thresholds %s: %v", path, err)
+	}
+	return t, nil
+}
+
+// Save writes the thresholds as JSON
+func (t Thresholds) Save(path string) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode thresholds: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save thresholds: %v", err)
+	}
+	return nil
+}
+
+// Validate checks that all thresholds lie in [0, 1]
+func (t Thresholds) Validate() error {
+	if t.ConfThreshold < 0 || t.ConfThreshold > 1 {
+		return fmt.Errorf("conf_threshold %v outside [0, 1]", t.ConfThreshold)
+	}
+	if t.IOUThreshold <= 0 || t.IOUThreshold > 1 {
+		return fmt.Errorf("iou_threshold %v outside (0, 1]", t.IOUThreshold)
+	}
+	for class, c := range t.ClassThresholds {
+		if c < 0 || c > 1 {
+			return fmt.Errorf("threshold %v of class %q outside [0, 1]", c, class)
+		}
+	}
+	return nil
+}
+
+// ApplyThresholds filters candidates (see YOLODetector.Candidates) by their
+// class threshold and suppresses overlaps, giving what Detect returns with
+// these thresholds. Filtering before NMS is equivalent, a suppressed box
+// always has a more confident box of its class passing the same threshold.
+func ApplyThresholds(candidates []Detection, t Thresholds) []Detection {
+	var kept []Detection
+	for _, det := range candidates {
+		if det.Confidence > t.For(det.Class) {
+			kept = append(kept, det)
+		}
+	}
+	return nonMaxSuppression(kept, t.IOUThreshold)
+}
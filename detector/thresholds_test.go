@@ -0,0 +1,33 @@
+package detector
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigFor(t *testing.T) {
+	modelPath := filepath.Join(t.TempDir(), "yolo.onnx")
+
+	config, path, err := ConfigFor(modelPath)
+	if err != nil || path != "" {
+		t.Fatalf("without thresholds got path %q, error %v", path, err)
+	}
+	if config.ConfThreshold != DefaultConfig.ConfThreshold {
+		t.Errorf("confidence %v, want the default", config.ConfThreshold)
+	}
+
+	saved := Thresholds{ConfThreshold: 0.6, IOUThreshold: 0.3, ClassThresholds: map[string]float32{"redbull": 0.8}}
+	if err := saved.Save(ThresholdsPath(modelPath)); err != nil {
+		t.Fatal(err)
+	}
+	config, path, err = ConfigFor(modelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != ThresholdsPath(modelPath) {
+		t.Errorf("path %q, want %q", path, ThresholdsPath(modelPath))
+	}
+	if config.ConfThreshold != 0.6 || config.IOUThreshold != 0.3 || config.Thresholds().For("redbull") != 0.8 {
+		t.Errorf("thresholds not applied: %+v", config.Thresholds())
+	}
+}
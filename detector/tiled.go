@@ -0,0 +1,128 @@
+package detector
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// settings for sliced inference on high resolution images (SAHI-style)
+type TileConfig struct {
+	TileWidth  int
+	TileHeight int
+	Overlap    float64 // fraction of a tile shared with its neighbour, in [0, 1)
+	FullFrame  bool    // additionally detect on the whole (downscaled) image
+	Merge      MergeMethod
+	MergeIoU   float32
+}
+
+var DefaultTileConfig = TileConfig{
+	TileWidth:  640,
+	TileHeight: 640,
+	Overlap:    0.2,
+	FullFrame:  true,
+	Merge:      MergeNMS,
+	MergeIoU:   0.5,
+}
+
+// DetectTiled cuts the image into overlapping tiles, detects on every tile
+// (batched if the detector has a batch session) and merges the results in
+// original image coordinates
+func (d *YOLODetector) DetectTiled(img image.Image, cfg TileConfig) ([]Detection, error) {
+	if cfg.TileWidth <= 0 || cfg.TileHeight <= 0 {
+		return nil, fmt.Errorf("invalid tile size %dx%d", cfg.TileWidth, cfg.TileHeight)
+	}
+	if cfg.Overlap < 0 || cfg.Overlap >= 1 {
+		return nil, fmt.Errorf("tile overlap must be in [0, 1), got %v", cfg.Overlap)
+	}
+
+	bounds := img.Bounds()
+	rects := TileRects(bounds, cfg)
+
+	tiles := make([]image.Image, len(rects))
+	for i, r := range rects {
+		tiles[i] = subImage(img, r)
+	}
+	if cfg.FullFrame && len(rects) > 1 {
+		tiles = append(tiles, img)
+		rects = append(rects, bounds)
+	}
+
+	results, err := d.DetectBatch(tiles)
+	if err != nil {
+		return nil, fmt.Errorf("tiled detection failed: %v", err)
+	}
+
+	detections := untile(results, rects, bounds)
+	return MergeDetections(detections, cfg.Merge, cfg.MergeIoU, 1), nil
+}
+
+// untile shifts the detections of every tile, relative to the tile's top
+// left corner, into coordinates relative to the top left corner of bounds
+func untile(results [][]Detection, rects []image.Rectangle, bounds image.Rectangle) []Detection {
+	var detections []Detection
+	for i, tileDetections := range results {
+		offsetX := float32(rects[i].Min.X - bounds.Min.X)
+		offsetY := float32(rects[i].Min.Y - bounds.Min.Y)
+		for _, det := range tileDetections {
+			det.Box.X1 += offsetX
+			det.Box.X2 += offsetX
+			det.Box.Y1 += offsetY
+			det.Box.Y2 += offsetY
+			detections = append(detections, det)
+		}
+	}
+	return detections
+}
+
+// TileRects splits bounds into overlapping tiles covering the whole area.
+// The last tile of a row or column is shifted inwards so every tile has the
+// full size unless the image itself is smaller.
+func TileRects(bounds image.Rectangle, cfg TileConfig) []image.Rectangle {
+	xs := tileStarts(bounds.Min.X, bounds.Dx(), cfg.TileWidth, cfg.Overlap)
+	ys := tileStarts(bounds.Min.Y, bounds.Dy(), cfg.TileHeight, cfg.Overlap)
+
+	rects := make([]image.Rectangle, 0, len(xs)*len(ys))
+	for _, y := range ys {
+		for _, x := range xs {
+			r := image.Rect(x, y, x+cfg.TileWidth, y+cfg.TileHeight)
+			rects = append(rects, r.Intersect(bounds))
+		}
+	}
+	return rects
+}
+
+// tileStarts returns the tile offsets along one axis
+func tileStarts(origin, length, tile int, overlap float64) []int {
+	if length <= tile {
+		return []int{origin}
+	}
+
+	step := int(float64(tile) * (1 - overlap))
+	if step < 1 {
+		step = 1
+	}
+
+	var starts []int
+	for pos := 0; ; pos += step {
+		if pos+tile >= length {
+			starts = append(starts, origin+length-tile)
+			break
+		}
+		starts = append(starts, origin+pos)
+	}
+	return starts
+}
+
+// subImage returns the part of img inside r without copying when possible
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	if s, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+
+	dst := image.NewRGBA(r)
+	draw.Draw(dst, r, img, r.Min, draw.Src)
+	return dst
+}
@@ -0,0 +1,132 @@
+package detector
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestTileStarts(t *testing.T) {
+	for _, c := range []struct {
+		name                 string
+		origin, length, tile int
+		overlap              float64
+		want                 []int
+	}{
+		{"smaller than a tile", 0, 500, 640, 0.2, []int{0}},
+		{"exactly one tile", 0, 640, 640, 0.2, []int{0}},
+		// step 512, the second tile is pulled back to end at the border
+		{"last tile shifted inwards", 0, 1000, 640, 0.2, []int{0, 360}},
+		{"several steps", 0, 2000, 640, 0.25, []int{0, 480, 960, 1360}},
+		{"no overlap", 0, 1280, 640, 0, []int{0, 640}},
+		{"origin", 100, 1000, 640, 0.2, []int{100, 460}},
+		{"step at least one pixel", 0, 4, 2, 0.999, []int{0, 1, 2}},
+	} {
+		if got := tileStarts(c.origin, c.length, c.tile, c.overlap); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: tileStarts = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestTileRects(t *testing.T) {
+	cfg := TileConfig{TileWidth: 640, TileHeight: 640, Overlap: 0.2}
+	for _, c := range []struct {
+		name   string
+		bounds image.Rectangle
+		want   []image.Rectangle
+	}{
+		{"smaller than a tile", image.Rect(0, 0, 300, 200), []image.Rectangle{image.Rect(0, 0, 300, 200)}},
+		{"wider than a tile", image.Rect(0, 0, 1000, 500), []image.Rectangle{
+			image.Rect(0, 0, 640, 500), image.Rect(360, 0, 1000, 500),
+		}},
+		{"rows and columns", image.Rect(0, 0, 1000, 700), []image.Rectangle{
+			image.Rect(0, 0, 640, 640), image.Rect(360, 0, 1000, 640),
+			image.Rect(0, 60, 640, 700), image.Rect(360, 60, 1000, 700),
+		}},
+		{"offset bounds", image.Rect(50, 20, 1050, 520), []image.Rectangle{
+			image.Rect(50, 20, 690, 520), image.Rect(410, 20, 1050, 520),
+		}},
+	} {
+		if got := TileRects(c.bounds, cfg); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: TileRects = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestTileRectsCoverImage(t *testing.T) {
+	cfg := TileConfig{TileWidth: 64, TileHeight: 48, Overlap: 0.25}
+	minOverlap := int(float64(cfg.TileWidth) * cfg.Overlap)
+
+	for w := 1; w <= 300; w += 37 {
+		for h := 1; h <= 200; h += 29 {
+			bounds := image.Rect(7, 3, 7+w, 3+h)
+			rects := TileRects(bounds, cfg)
+
+			covered := make([]bool, w*h)
+			for i, r := range rects {
+				if !r.In(bounds) {
+					t.Fatalf("%v: tile %v outside the image", bounds, r)
+				}
+				// full tiles unless the image is smaller
+				if r.Dx() != cfg.TileWidth && r.Dx() != w || r.Dy() != cfg.TileHeight && r.Dy() != h {
+					t.Fatalf("%v: tile %v is not full size", bounds, r)
+				}
+				// horizontal neighbours share at least the overlap
+				if i > 0 && rects[i-1].Min.Y == r.Min.Y && rects[i-1].Max.X-r.Min.X < minOverlap {
+					t.Fatalf("%v: tiles %v and %v overlap by less than %d", bounds, rects[i-1], r, minOverlap)
+				}
+				for y := r.Min.Y; y < r.Max.Y; y++ {
+					for x := r.Min.X; x < r.Max.X; x++ {
+						covered[(y-bounds.Min.Y)*w+x-bounds.Min.X] = true
+					}
+				}
+			}
+			for i, ok := range covered {
+				if !ok {
+					t.Fatalf("%v: pixel %d,%d not covered by %v", bounds, i%w, i/w, rects)
+				}
+			}
+		}
+	}
+}
+
+func TestUntile(t *testing.T) {
+	bounds := image.Rect(100, 50, 1100, 750)
+	rects := []image.Rectangle{image.Rect(100, 50, 740, 690), image.Rect(460, 110, 1100, 750), bounds}
+	results := [][]Detection{
+		{{Box: Box{X1: 10, Y1: 20, X2: 30, Y2: 40}, Class: "a"}},
+		{{Box: Box{X1: 0, Y1: 0, X2: 5, Y2: 5}, Class: "b"}, {Box: Box{X1: 630, Y1: 630, X2: 640, Y2: 640}, Class: "c"}},
+		{{Box: Box{X1: 1, Y1: 2, X2: 3, Y2: 4}, Class: "full"}},
+	}
+
+	want := []Box{
+		{X1: 10, Y1: 20, X2: 30, Y2: 40},
+		{X1: 360, Y1: 60, X2: 365, Y2: 65},
+		{X1: 990, Y1: 690, X2: 1000, Y2: 700},
+		{X1: 1, Y1: 2, X2: 3, Y2: 4},
+	}
+	got := untile(results, rects, bounds)
+	if len(got) != len(want) {
+		t.Fatalf("got %d detections, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Box != want[i] {
+			t.Errorf("detection %d (%s): box %+v, want %+v", i, got[i].Class, got[i].Box, want[i])
+		}
+	}
+}
+
+func TestDetectTiledRejectsInvalidConfig(t *testing.T) {
+	d := newDetector("model.onnx", DefaultConfig, DefaultClasses)
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for _, cfg := range []TileConfig{
+		{TileWidth: 0, TileHeight: 640},
+		{TileWidth: 640, TileHeight: -1},
+		{TileWidth: 640, TileHeight: 640, Overlap: 1},
+		{TileWidth: 640, TileHeight: 640, Overlap: -0.1},
+	} {
+		if _, err := d.DetectTiled(img, cfg); err == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+	}
+}
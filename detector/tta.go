@@ -0,0 +1,50 @@
+package detector
+
+import (
+	"fmt"
+	"image"
+	"yolo_detection/imageutils"
+)
+
+// DetectTTA runs detection on every augmentation of the image, maps the
+// boxes back onto the original image and fuses them with weighted box
+// fusion. A nil augmentation set uses imageutils.DefaultAugmentations.
+func (d *YOLODetector) DetectTTA(img image.Image, augmentations []imageutils.Augmentation) ([]Detection, error) {
+	if augmentations == nil {
+		augmentations = imageutils.DefaultAugmentations
+	}
+	if len(augmentations) == 0 {
+		return d.Detect(img)
+	}
+
+	bounds := img.Bounds()
+	size := imageutils.ImageSize{Width: bounds.Dx(), Height: bounds.Dy()}
+
+	augmented := make([]image.Image, len(augmentations))
+	for i, aug := range augmentations {
+		augmented[i] = aug.Apply(img, d.config.Preprocess.Interpolation)
+	}
+
+	results, err := d.DetectBatch(augmented)
+	if err != nil {
+		return nil, fmt.Errorf("tta detection failed: %v", err)
+	}
+
+	var detections []Detection
+	for i, passDetections := range results {
+		aug := augmentations[i]
+		for _, det := range passDetections {
+			x1, y1 := aug.InvertPoint(float64(det.Box.X1), float64(det.Box.Y1), size)
+			x2, y2 := aug.InvertPoint(float64(det.Box.X2), float64(det.Box.Y2), size)
+
+			// flipping swaps left and right edge
+			if x1 > x2 {
+				x1, x2 = x2, x1
+			}
+			det.Box = Box{X1: float32(x1), Y1: float32(y1), X2: float32(x2), Y2: float32(y2)}
+			detections = append(detections, det)
+		}
+	}
+
+	return MergeDetections(detections, MergeWBF, d.config.IOUThreshold, len(augmentations)), nil
+}
@@ -0,0 +1,170 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"yolo_detection/imageutils"
+
+	onnxruntime "github.com/yalue/onnxruntime_go"
+)
+
+// create new embedder
+func New(ctx context.Context, modelPath string) (*Embedder, error) {
+	return NewWithConfig(ctx, modelPath, DefaultConfig)
+}
+
+// create new embedder with a custom config. The shape of the chosen output
+// is read from the model: [1, D] is used as is, feature maps [1, D, H, W]
+// are averaged over H and W.
+func NewWithConfig(ctx context.Context, modelPath string, config Config) (*Embedder, error) {
+	// check if file exists
+	if _, err := os.Stat(modelPath); err != nil {
+		return nil, fmt.Errorf("model file not found: %v", err)
+	}
+
+	_, outputs, err := onnxruntime.GetInputOutputInfo(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model outputs: %v", err)
+	}
+	var outputShape onnxruntime.Shape
+	var names []string
+	for _, o := range outputs {
+		names = append(names, o.Name)
+		if o.Name == config.OutputName {
+			outputShape = o.Dimensions
+		}
+	}
+	if outputShape == nil {
+		return nil, fmt.Errorf("model has no output %q, available: %v", config.OutputName, names)
+	}
+	if len(outputShape) < 2 {
+		return nil, fmt.Errorf("output %q has shape %v, expected [batch, dim, ...]", config.OutputName, outputShape)
+	}
+	outputShape = append(onnxruntime.Shape{1}, outputShape[1:]...)
+	spatial := int64(1)
+	for _, d := range outputShape[2:] {
+		if d <= 0 {
+			return nil, fmt.Errorf("output %q has dynamic shape %v", config.OutputName, outputShape)
+		}
+		spatial *= d
+	}
+	if outputShape[1] <= 0 {
+		return nil, fmt.Errorf("output %q has dynamic shape %v", config.OutputName, outputShape)
+	}
+
+	// pre-allocate input tensor 1, 3, h, w (or 1, h, w, 3)
+	targetSize := imageutils.ImageSize{Width: config.InputWidth, Height: config.InputHeight}
+	inputShape := onnxruntime.NewShape(config.Preprocess.TensorShape(1, targetSize)...)
+	inputTensor, err := onnxruntime.NewEmptyTensor[float32](inputShape)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create input tensor: %v", err)
+	}
+
+	outputTensor, err := onnxruntime.NewEmptyTensor[float32](outputShape)
+	if err != nil {
+		inputTensor.Destroy()
+		return nil, fmt.Errorf("failed to create output tensor: %v", err)
+	}
+
+	// create ONNX runtime session
+	session, err := onnxruntime.NewSession(
+		modelPath,
+		[]string{config.InputName},
+		[]string{config.OutputName},
+		[]*onnxruntime.Tensor[float32]{inputTensor},
+		[]*onnxruntime.Tensor[float32]{outputTensor},
+	)
+	if err != nil {
+		destroySession(nil, inputTensor, outputTensor)
+		return nil, fmt.Errorf("failed to create ONNX session :%v", err)
+	}
+
+	embedder := &Embedder{
+		modelPath:    modelPath,
+		preprocessor: imageutils.NewPreprocessor(targetSize, config.Preprocess, config.PreprocessWorkers),
+		session:      session,
+		config:       config,
+		inputTensor:  inputTensor,
+		outputTensor: outputTensor,
+		dim:          int(outputShape[1]),
+		spatial:      int(spatial),
+	}
+	go func() {
+		<-ctx.Done()
+		embedder.Destroy()
+	}()
+	return embedder, nil
+}
+
+// Destroy releases the session and tensors of the embedder. It is called
+// when the context given to New is done; calling it earlier releases them
+// right away, e.g. before onnxruntime.DestroyEnvironment.
+func (e *Embedder) Destroy() {
+	e.destroyOnce.Do(func() {
+		destroySession(e.session, e.inputTensor, e.outputTensor)
+	})
+}
+
+// destroySession releases a session before the tensors bound to it, nil
+// values are skipped
+func destroySession(session *onnxruntime.Session[float32], tensors ...*onnxruntime.Tensor[float32]) {
+	if session != nil {
+		session.Destroy()
+	}
+	for _, t := range tensors {
+		if t != nil {
+			t.Destroy()
+		}
+	}
+}
+
+// Dim returns the length of the vectors
+func (e *Embedder) Dim() int {
+	return e.dim
+}
+
+// Embed returns the feature vector of the image
+func (e *Embedder) Embed(img image.Image) ([]float32, error) {
+	// preprocess straight into the input tensor
+	if _, err := e.preprocessor.Run(e.inputTensor.GetData(), img); err != nil {
+		return nil, fmt.Errorf("preprocessing failed: %v", err)
+	}
+
+	if err := e.session.Run(); err != nil {
+		return nil, fmt.Errorf("inference failed: %v", err)
+	}
+
+	// global average pooling of feature maps, channel first
+	output := e.outputTensor.GetData()
+	vector := make([]float32, e.dim)
+	for c := range vector {
+		var sum float32
+		for _, v := range output[c*e.spatial : (c+1)*e.spatial] {
+			sum += v
+		}
+		vector[c] = sum / float32(e.spatial)
+	}
+
+	if e.config.Normalize {
+		Normalize(vector)
+	}
+	return vector, nil
+}
+
+// Normalize scales v to unit length in place
+func Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	scale := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= scale
+	}
+}
@@ -0,0 +1,217 @@
+package embedding
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// label reported when no reference is similar enough
+const UnknownLabel = "unknown"
+
+// version of the gallery file format
+const galleryVersion = 1
+
+// labelled reference embedding
+type Entry struct {
+	Label  string    `json:"label"`
+	Source string    `json:"source,omitempty"` // e.g. the reference image
+	Vector []float32 `json:"vector"`
+}
+
+// result of a gallery lookup
+type Match struct {
+	Label      string  `json:"label"`
+	Similarity float32 `json:"similarity"` // cosine similarity, 1 is identical
+}
+
+// Gallery holds reference embeddings and finds the most similar one. New
+// products are added without retraining. Safe for concurrent use.
+type Gallery struct {
+	mu      sync.RWMutex
+	dim     int
+	entries []Entry
+}
+
+type galleryFile struct {
+	Version int     `json:"version"`
+	Dim     int     `json:"dim"`
+	Entries []Entry `json:"entries"`
+}
+
+// create new empty gallery for vectors of length dim
+func NewGallery(dim int) *Gallery {
+	return &Gallery{dim: dim}
+}
+
+// LoadGallery reads a gallery saved with Save
+func LoadGallery(path string) (*Gallery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gallery: %v", err)
+	}
+	var file galleryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse gallery %s: %v", path, err)
+	}
+	if file.Version != galleryVersion {
+		return nil, fmt.Errorf("unsupported gallery version %d", file.Version)
+	}
+
+	g := NewGallery(file.Dim)
+	for i, e := range file.Entries {
+		if err := g.Add(e.Label, e.Source, e.Vector); err != nil {
+			return nil, fmt.Errorf("gallery entry %d: %v", i, err)
+		}
+	}
+	return g, nil
+}
+
+// Save writes the gallery as JSON. The file is replaced atomically, so a
+// crash never leaves a truncated gallery behind.
+func (g *Gallery) Save(path string) error {
+	g.mu.RLock()
+	data, err := json.Marshal(galleryFile{Version: galleryVersion, Dim: g.dim, Entries: g.entries})
+	g.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode gallery: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to save gallery: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save gallery: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save gallery: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save gallery: %v", err)
+	}
+	return nil
+}
+
+// Dim returns the vector length of the gallery
+func (g *Gallery) Dim() int {
+	return g.dim
+}
+
+// Len returns the number of references
+func (g *Gallery) Len() int {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return len(g.entries)
+}
+
+// Labels returns the distinct labels, sorted
+func (g *Gallery) Labels() []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	seen := make(map[string]bool)
+	var labels []string
+	for _, e := range g.entries {
+		if !seen[e.Label] {
+			seen[e.Label] = true
+			labels = append(labels, e.Label)
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Add stores a normalized copy of vector as reference for label
+func (g *Gallery) Add(label, source string, vector []float32) error {
+	if label == "" || label == UnknownLabel {
+		return fmt.Errorf("invalid label %q", label)
+	}
+	if len(vector) != g.dim {
+		return fmt.Errorf("vector has length %d, gallery expects %d", len(vector), g.dim)
+	}
+	v := make([]float32, len(vector))
+	copy(v, vector)
+	Normalize(v)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.entries = append(g.entries, Entry{Label: label, Source: source, Vector: v})
+	return nil
+}
+
+// Remove deletes all references of label and returns how many were removed
+func (g *Gallery) Remove(label string) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	kept := g.entries[:0]
+	for _, e := range g.entries {
+		if e.Label != label {
+			kept = append(kept, e)
+		}
+	}
+	removed := len(g.entries) - len(kept)
+	g.entries = kept
+	return removed
+}
+
+// Nearest returns the k most similar labels, each label once with the
+// similarity of its best reference
+func (g *Gallery) Nearest(vector []float32, k int) []Match {
+	if len(vector) != g.dim {
+		return nil
+	}
+	v := make([]float32, len(vector))
+	copy(v, vector)
+	Normalize(v)
+
+	g.mu.RLock()
+	best := make(map[string]float32)
+	for _, e := range g.entries {
+		sim := dot(v, e.Vector)
+		if s, ok := best[e.Label]; !ok || sim > s {
+			best[e.Label] = sim
+		}
+	}
+	g.mu.RUnlock()
+
+	matches := make([]Match, 0, len(best))
+	for label, sim := range best {
+		matches = append(matches, Match{Label: label, Similarity: sim})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Similarity != matches[j].Similarity {
+			return matches[i].Similarity > matches[j].Similarity
+		}
+		return matches[i].Label < matches[j].Label
+	})
+	if k > 0 && len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches
+}
+
+// Lookup returns the most similar label, or UnknownLabel with the best
+// similarity when it is below threshold
+func (g *Gallery) Lookup(vector []float32, threshold float32) Match {
+	matches := g.Nearest(vector, 1)
+	if len(matches) == 0 {
+		return Match{Label: UnknownLabel}
+	}
+	if matches[0].Similarity < threshold {
+		return Match{Label: UnknownLabel, Similarity: matches[0].Similarity}
+	}
+	return matches[0]
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,185 @@
+package embedding
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testGallery(t *testing.T) *Gallery {
+	t.Helper()
+	g := NewGallery(3)
+	for _, e := range []Entry{
+		{Label: "cola", Source: "cola_front.jpg", Vector: []float32{1, 0, 0}},
+		{Label: "cola", Source: "cola_side.jpg", Vector: []float32{2, 2, 0}},
+		{Label: "water", Source: "water.jpg", Vector: []float32{0, 3, 0}},
+		{Label: "juice", Source: "juice.jpg", Vector: []float32{0, 0, 1}},
+	} {
+		if err := g.Add(e.Label, e.Source, e.Vector); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return g
+}
+
+func TestNormalize(t *testing.T) {
+	v := []float32{3, 4, 0}
+	Normalize(v)
+	if want := []float32{0.6, 0.8, 0}; !reflect.DeepEqual(v, want) {
+		t.Errorf("Normalize = %v, want %v", v, want)
+	}
+
+	big := []float32{1e20, 1e20}
+	Normalize(big)
+	if n := math.Hypot(float64(big[0]), float64(big[1])); math.Abs(n-1) > 1e-6 {
+		t.Errorf("large vector normalized to length %v", n)
+	}
+
+	zero := []float32{0, 0, 0}
+	Normalize(zero)
+	if want := []float32{0, 0, 0}; !reflect.DeepEqual(zero, want) {
+		t.Errorf("zero vector changed to %v", zero)
+	}
+}
+
+func TestGalleryAdd(t *testing.T) {
+	g := NewGallery(3)
+	if err := g.Add("cola", "", []float32{1, 2}); err == nil {
+		t.Error("expected an error for a vector of the wrong length")
+	}
+	for _, label := range []string{"", UnknownLabel} {
+		if err := g.Add(label, "", []float32{1, 0, 0}); err == nil {
+			t.Errorf("expected an error for label %q", label)
+		}
+	}
+
+	// the stored reference is a normalized copy
+	v := []float32{0, 0, 2}
+	if err := g.Add("juice", "", v); err != nil {
+		t.Fatal(err)
+	}
+	if v[2] != 2 {
+		t.Errorf("Add modified its argument: %v", v)
+	}
+	if m := g.Lookup([]float32{0, 0, 5}, 0.99); m.Label != "juice" || m.Similarity != 1 {
+		t.Errorf("Lookup = %+v", m)
+	}
+}
+
+func TestGalleryNearest(t *testing.T) {
+	g := testGallery(t)
+
+	// closest to the diagonal cola reference, then water, juice is orthogonal
+	got := g.Nearest([]float32{1, 1.2, 0}, 0)
+	var labels []string
+	for _, m := range got {
+		labels = append(labels, m.Label)
+	}
+	if want := []string{"cola", "water", "juice"}; !reflect.DeepEqual(labels, want) {
+		t.Fatalf("Nearest labels = %v, want %v", labels, want)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i].Similarity > got[i-1].Similarity {
+			t.Errorf("matches not sorted by similarity: %+v", got)
+		}
+	}
+	if got[2].Similarity != 0 {
+		t.Errorf("orthogonal juice similarity = %v", got[2].Similarity)
+	}
+
+	if top := g.Nearest([]float32{1, 1.2, 0}, 2); len(top) != 2 || top[0].Label != "cola" {
+		t.Errorf("Nearest k=2 = %+v", top)
+	}
+	if m := g.Nearest([]float32{1, 0}, 1); m != nil {
+		t.Errorf("expected no matches for a vector of the wrong length, got %+v", m)
+	}
+}
+
+func TestGalleryLookup(t *testing.T) {
+	g := testGallery(t)
+
+	if m := g.Lookup([]float32{0, 5, 0}, 0.9); m.Label != "water" {
+		t.Errorf("Lookup = %+v, want water", m)
+	}
+
+	// halfway between water and juice, similarity 0.71 is below the threshold
+	m := g.Lookup([]float32{0, 1, 1}, 0.9)
+	if m.Label != UnknownLabel {
+		t.Errorf("Lookup = %+v, want %s", m, UnknownLabel)
+	}
+	if math.Abs(float64(m.Similarity)-math.Sqrt2/2) > 1e-6 {
+		t.Errorf("unknown match keeps similarity %v, want %v", m.Similarity, math.Sqrt2/2)
+	}
+
+	if m := NewGallery(3).Lookup([]float32{1, 0, 0}, 0); m.Label != UnknownLabel {
+		t.Errorf("empty gallery Lookup = %+v", m)
+	}
+}
+
+func TestGalleryRemove(t *testing.T) {
+	g := testGallery(t)
+
+	if n := g.Remove("cola"); n != 2 {
+		t.Errorf("Remove(cola) = %d, want 2", n)
+	}
+	if n := g.Remove("cola"); n != 0 {
+		t.Errorf("second Remove(cola) = %d, want 0", n)
+	}
+	if want := []string{"juice", "water"}; !reflect.DeepEqual(g.Labels(), want) {
+		t.Errorf("Labels = %v, want %v", g.Labels(), want)
+	}
+	if m := g.Lookup([]float32{1, 0, 0}, 0.5); m.Label != UnknownLabel {
+		t.Errorf("removed label still matches: %+v", m)
+	}
+}
+
+func TestGallerySaveLoad(t *testing.T) {
+	g := testGallery(t)
+	path := filepath.Join(t.TempDir(), "gallery.json")
+	if err := g.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadGallery(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Dim() != g.Dim() || loaded.Len() != g.Len() {
+		t.Fatalf("loaded dim %d len %d, want dim %d len %d", loaded.Dim(), loaded.Len(), g.Dim(), g.Len())
+	}
+	if !reflect.DeepEqual(loaded.entries, g.entries) {
+		t.Errorf("entries changed in round trip:\n%+v\n%+v", loaded.entries, g.entries)
+	}
+
+	// no temporary files are left next to the gallery
+	files, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the gallery file, found %d files", len(files))
+	}
+}
+
+func TestLoadGalleryErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"version": `{"version": 2, "dim": 3, "entries": []}`,
+		"json":    `{"version": 1,`,
+		"entry":   `{"version": 1, "dim": 3, "entries": [{"label": "cola", "vector": [1, 0]}]}`,
+	}
+	for name, content := range tests {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadGallery(path); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := LoadGallery(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,42 @@
+package embedding
+
+import (
+	"sync"
+	"yolo_detection/imageutils"
+
+	onnxruntime "github.com/yalue/onnxruntime_go"
+)
+
+// Embedder turns images into feature vectors
+type Embedder struct {
+	modelPath    string
+	preprocessor *imageutils.Preprocessor
+	session      *onnxruntime.Session[float32]
+	config       Config
+	inputTensor  *onnxruntime.Tensor[float32]
+	outputTensor *onnxruntime.Tensor[float32]
+
+	dim     int // vector length
+	spatial int // values averaged into one vector component, > 1 for feature maps
+
+	destroyOnce sync.Once
+}
+
+type Config struct {
+	InputWidth        int
+	InputHeight       int
+	InputName         string
+	OutputName        string // layer used as embedding, must be a model output
+	Normalize         bool   // scale vectors to unit length
+	Preprocess        imageutils.PreprocessSpec
+	PreprocessWorkers int // goroutines used for preprocessing, <= 1 runs inline
+}
+
+var DefaultConfig = Config{
+	InputWidth:  224,
+	InputHeight: 224,
+	InputName:   "input",
+	OutputName:  "embedding",
+	Normalize:   true,
+	Preprocess:  imageutils.ImageNetPreprocessSpec,
+}
@@ -0,0 +1,362 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"yolo_detection/classifier"
+	"yolo_detection/imageloader"
+)
+
+// ClassSample is one image of a folder-per-class dataset
+type ClassSample struct {
+	ImagePath string
+	Label     string
+}
+
+// metrics of one classifier label, one-vs-rest
+type LabelMetrics struct {
+	Class     string  `json:"class"`
+	Support   int     `json:"support"` // samples with this label
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	F1        float64 `json:"f1"`
+	AUC       float64 `json:"auc"` // ROC AUC of the label's probability
+}
+
+// ReliabilityBin groups predictions by their top confidence
+type ReliabilityBin struct {
+	Lower      float64 `json:"lower"`
+	Upper      float64 `json:"upper"`
+	Count      int     `json:"count"`
+	Confidence float64 `json:"confidence"` // mean top confidence
+	Accuracy   float64 `json:"accuracy"`
+}
+
+// Misclassified is a wrong prediction, listed for review
+type Misclassified struct {
+	Path       string  `json:"path"`
+	Label      string  `json:"label"`
+	Predicted  string  `json:"predicted"`
+	Confidence float64 `json:"confidence"` // of the predicted label
+}
+
+// ClassifierReport holds the evaluation of a classifier
+type ClassifierReport struct {
+	Samples     int              `json:"samples"`
+	Accuracy    float64          `json:"accuracy"`
+	Labels      []LabelMetrics   `json:"labels"`
+	MacroAUC    float64          `json:"macro_auc"`
+	ECE         float64          `json:"ece"` // expected calibration error
+	Reliability []ReliabilityBin `json:"reliability"`
+	Confusion   [][]int          `json:"confusion"` // [predicted][actual], label order
+	Worst       []Misclassified  `json:"worst"`     // most confident mistakes first
+}
+
+// LoadClassFolders reads a dataset where every subdirectory of root is a
+// label holding its images. Returns the samples and the sorted labels.
+func LoadClassFolders(root string) ([]ClassSample, []string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read dataset: %v", err)
+	}
+
+	var samples []ClassSample
+	var labels []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		labels = append(labels, e.Name())
+		dir := filepath.Join(root, e.Name())
+		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && imageloader.IsImage(path) {
+				samples = append(samples, ClassSample{ImagePath: path, Label: e.Name()})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %v", dir, err)
+		}
+	}
+	if len(samples) == 0 {
+		return nil, nil, fmt.Errorf("no images found below %s", root)
+	}
+	return samples, labels, nil
+}
+
+// RunClassifier classifies every sample and evaluates the results. Sample
+// labels must be classifier labels.
+func RunClassifier(c *classifier.Classifier, samples []ClassSample, bins, worst int) (ClassifierReport, error) {
+	index := make(map[string]int)
+	for i, l := range c.Labels() {
+		index[l] = i
+	}
+
+	probabilities := make([][]float32, len(samples))
+	labels := make([]int, len(samples))
+	paths := make([]string, len(samples))
+	for i, s := range samples {
+		label, ok := index[s.Label]
+		if !ok {
+			return ClassifierReport{}, fmt.Errorf("dataset label %q is not a classifier label %v", s.Label, c.Labels())
+		}
+		img, err := imageloader.Load(s.ImagePath)
+		if err != nil {
+			return ClassifierReport{}, fmt.Errorf("%s: %v", s.ImagePath, err)
+		}
+		probabilities[i], err = c.Probabilities(img)
+		if err != nil {
+			return ClassifierReport{}, fmt.Errorf("%s: %v", s.ImagePath, err)
+		}
+		labels[i] = label
+		paths[i] = s.ImagePath
+	}
+
+	return EvaluateClassifier(probabilities, labels, paths, c.Labels(), bins, worst), nil
+}
+
+// EvaluateClassifier computes the metrics from per-sample probabilities and
+// true label indices. Predictions are the most probable label.
+func EvaluateClassifier(probabilities [][]float32, labels []int, paths []string, classes []string, bins, worst int) ClassifierReport {
+	if bins <= 0 {
+		bins = 10
+	}
+	n := len(classes)
+	report := ClassifierReport{
+		Samples:     len(labels),
+		Confusion:   make([][]int, n),
+		Reliability: make([]ReliabilityBin, bins),
+	}
+	for i := range report.Confusion {
+		report.Confusion[i] = make([]int, n)
+	}
+	for b := range report.Reliability {
+		report.Reliability[b].Lower = float64(b) / float64(bins)
+		report.Reliability[b].Upper = float64(b+1) / float64(bins)
+	}
+
+	var correct int
+	var mistakes []Misclassified
+	for i, probs := range probabilities {
+		predicted := argmax(probs)
+		confidence := float64(probs[predicted])
+		report.Confusion[predicted][labels[i]]++
+
+		b := int(confidence * float64(bins))
+		if b >= bins {
+			b = bins - 1
+		}
+		if b < 0 {
+			b = 0
+		}
+		bin := &report.Reliability[b]
+		bin.Count++
+		bin.Confidence += confidence
+
+		if predicted == labels[i] {
+			correct++
+			bin.Accuracy++
+		} else {
+			mistakes = append(mistakes, Misclassified{
+				Path:       paths[i],
+				Label:      classes[labels[i]],
+				Predicted:  classes[predicted],
+				Confidence: confidence,
+			})
+		}
+	}
+	if report.Samples > 0 {
+		report.Accuracy = float64(correct) / float64(report.Samples)
+	}
+
+	for b := range report.Reliability {
+		bin := &report.Reliability[b]
+		if bin.Count == 0 {
+			continue
+		}
+		bin.Confidence /= float64(bin.Count)
+		bin.Accuracy /= float64(bin.Count)
+		report.ECE += float64(bin.Count) / float64(report.Samples) * abs(bin.Accuracy-bin.Confidence)
+	}
+
+	var aucs int
+	for c, class := range classes {
+		m := LabelMetrics{Class: class}
+		var predicted int
+		for p := 0; p < n; p++ {
+			m.Support += report.Confusion[p][c]
+			predicted += report.Confusion[c][p]
+		}
+		tp := report.Confusion[c][c]
+		if predicted > 0 {
+			m.Precision = float64(tp) / float64(predicted)
+		}
+		if m.Support > 0 {
+			m.Recall = float64(tp) / float64(m.Support)
+		}
+		if m.Precision+m.Recall > 0 {
+			m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+		}
+
+		scores := make([]float64, len(probabilities))
+		positive := make([]bool, len(probabilities))
+		for i, probs := range probabilities {
+			scores[i] = float64(probs[c])
+			positive[i] = labels[i] == c
+		}
+		if auc, ok := rocAUC(scores, positive); ok {
+			m.AUC = auc
+			report.MacroAUC += auc
+			aucs++
+		}
+		report.Labels = append(report.Labels, m)
+	}
+	if aucs > 0 {
+		report.MacroAUC /= float64(aucs)
+	}
+
+	sort.SliceStable(mistakes, func(i, j int) bool { return mistakes[i].Confidence > mistakes[j].Confidence })
+	if worst >= 0 && len(mistakes) > worst {
+		mistakes = mistakes[:worst]
+	}
+	report.Worst = mistakes
+	return report
+}
+
+// rocAUC is the probability that a random positive scores above a random
+// negative (Mann-Whitney U), ties count half. Not defined without both
+// positives and negatives.
+func rocAUC(scores []float64, positive []bool) (float64, bool) {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+
+	var rankSum float64
+	var pos, neg int
+	for i := 0; i < len(order); {
+		// average rank of a run of ties
+		j := i
+		for j < len(order) && scores[order[j]] == scores[order[i]] {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if positive[order[k]] {
+				rankSum += rank
+				pos++
+			} else {
+				neg++
+			}
+		}
+		i = j
+	}
+	if pos == 0 || neg == 0 {
+		return 0, false
+	}
+	return (rankSum - float64(pos*(pos+1))/2) / float64(pos*neg), true
+}
+
+func argmax(values []float32) int {
+	best := 0
+	for i, v := range values {
+		if v > values[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// String renders the report as text
+func (r ClassifierReport) String() string {
+	var b strings.Builder
+	r.Print(&b)
+	return b.String()
+}
+
+// Print writes the metrics, confusion matrix, reliability diagram and the
+// worst mistakes as text
+func (r ClassifierReport) Print(w io.Writer) error {
+	fmt.Fprintf(w, "samples %d  accuracy %.3f  macro AUC %.3f  ECE %.3f\n\n", r.Samples, r.Accuracy, r.MacroAUC, r.ECE)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "label\tsupport\tP\tR\tF1\tAUC\t\n")
+	for _, m := range r.Labels {
+		fmt.Fprintf(tw, "%s\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t\n", m.Class, m.Support, m.Precision, m.Recall, m.F1, m.AUC)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nconfusion matrix (rows predicted, columns actual)\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	names := make([]string, len(r.Labels))
+	for i, m := range r.Labels {
+		names[i] = m.Class
+	}
+	fmt.Fprintf(tw, "\t%s\t\n", strings.Join(names, "\t"))
+	for i, row := range r.Confusion {
+		fmt.Fprintf(tw, "%s", names[i])
+		for _, v := range row {
+			fmt.Fprintf(tw, "\t%d", v)
+		}
+		fmt.Fprintf(tw, "\t\n")
+	}
+	tw.Flush()
+
+	// accuracy bars, '|' marks perfect calibration
+	fmt.Fprintf(w, "\nreliability (confidence bin: accuracy)\n")
+	const width = 40
+	for _, bin := range r.Reliability {
+		if bin.Count == 0 {
+			continue
+		}
+		bar := []byte(strings.Repeat(" ", width+1))
+		for i := 0; i < int(bin.Accuracy*width+0.5); i++ {
+			bar[i] = '#'
+		}
+		// scores of a model without activation may lie outside [0, 1]
+		mark := int(bin.Confidence*width + 0.5)
+		if mark < 0 {
+			mark = 0
+		}
+		if mark > width {
+			mark = width
+		}
+		bar[mark] = '|'
+		fmt.Fprintf(w, "%.2f-%.2f %s %.3f (n=%d)\n", bin.Lower, bin.Upper, bar, bin.Accuracy, bin.Count)
+	}
+
+	if len(r.Worst) > 0 {
+		fmt.Fprintf(w, "\nworst misclassified\n")
+		for _, m := range r.Worst {
+			fmt.Fprintf(w, "%.3f  %s -> %s  %s\n", m.Confidence, m.Label, m.Predicted, m.Path)
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as indented JSON
+func (r ClassifierReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	return nil
+}
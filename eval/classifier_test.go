@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRocAUC(t *testing.T) {
+	// perfectly separated, reversed and all tied
+	if auc, _ := rocAUC([]float64{0.1, 0.2, 0.8, 0.9}, []bool{false, false, true, true}); !near(auc, 1) {
+		t.Errorf("separated: %v", auc)
+	}
+	if auc, _ := rocAUC([]float64{0.9, 0.8, 0.2, 0.1}, []bool{false, false, true, true}); !near(auc, 0) {
+		t.Errorf("reversed: %v", auc)
+	}
+	if auc, _ := rocAUC([]float64{0.5, 0.5, 0.5, 0.5}, []bool{false, true, false, true}); !near(auc, 0.5) {
+		t.Errorf("tied: %v", auc)
+	}
+	// one of four pairs ordered wrong
+	if auc, _ := rocAUC([]float64{0.1, 0.6, 0.5, 0.9}, []bool{false, false, true, true}); !near(auc, 0.75) {
+		t.Errorf("mixed: %v", auc)
+	}
+	if _, ok := rocAUC([]float64{0.1, 0.2}, []bool{true, true}); ok {
+		t.Error("AUC without negatives should be undefined")
+	}
+}
+
+func TestEvaluateClassifier(t *testing.T) {
+	classes := []string{"empty", "loaded"}
+	probabilities := [][]float32{
+		{0.9, 0.1},   // empty, correct
+		{0.7, 0.3},   // empty, correct
+		{0.2, 0.8},   // loaded, correct
+		{0.95, 0.05}, // loaded, confidently wrong
+		{0.6, 0.4},   // loaded, wrong
+	}
+	labels := []int{0, 0, 1, 1, 1}
+	paths := []string{"a", "b", "c", "d", "e"}
+
+	r := EvaluateClassifier(probabilities, labels, paths, classes, 10, 1)
+	if !near(r.Accuracy, 0.6) {
+		t.Errorf("accuracy %v", r.Accuracy)
+	}
+	empty := r.Labels[0]
+	if empty.Support != 2 || !near(empty.Precision, 0.5) || !near(empty.Recall, 1) {
+		t.Errorf("empty metrics %+v", empty)
+	}
+	if r.Confusion[0][1] != 2 || r.Confusion[1][1] != 1 {
+		t.Errorf("confusion %v", r.Confusion)
+	}
+	if len(r.Worst) != 1 || r.Worst[0].Path != "d" {
+		t.Errorf("worst %+v", r.Worst)
+	}
+
+	var counted int
+	for _, b := range r.Reliability {
+		counted += b.Count
+	}
+	if counted != 5 || r.ECE <= 0 {
+		t.Errorf("reliability %+v, ECE %v", r.Reliability, r.ECE)
+	}
+	if r.String() == "" {
+		t.Error("empty report text")
+	}
+}
+
+func TestClassifierReportPrintLogits(t *testing.T) {
+	// raw logits of a model without activation, far outside [0, 1]
+	probabilities := [][]float32{{3.2, -1}, {-2, -0.5}, {0.4, 7}}
+	labels := []int{0, 1, 0}
+	r := EvaluateClassifier(probabilities, labels, []string{"a", "b", "c"}, []string{"empty", "loaded"}, 10, 2)
+
+	if !strings.Contains(r.String(), "reliability") {
+		t.Errorf("report without reliability section:\n%s", r.String())
+	}
+}
@@ -0,0 +1,90 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"yolo_detection/imageloader"
+	"yolo_detection/results"
+)
+
+// Sample is one image with its ground truth
+type Sample struct {
+	ImagePath   string
+	GroundTruth []results.Detection
+	Crowd       []results.Detection // COCO crowd regions, predictions inside are not scored
+	Normalized  bool                // boxes are in [0, 1] and scaled by the image size when loaded
+}
+
+// LoadYOLODataset pairs every image in imageDir with the label file of the
+// same name in labelDir (imageDir when empty). Images without label file
+// have no objects.
+func LoadYOLODataset(imageDir, labelDir string, classes []string) ([]Sample, error) {
+	if labelDir == "" {
+		labelDir = imageDir
+	}
+	entries, err := os.ReadDir(imageDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image directory: %v", err)
+	}
+
+	var samples []Sample
+	for _, e := range entries {
+		if e.IsDir() || !imageloader.IsImage(e.Name()) {
+			continue
+		}
+		sample := Sample{ImagePath: filepath.Join(imageDir, e.Name()), Normalized: true}
+
+		labelPath := filepath.Join(labelDir, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))+".txt")
+		f, err := os.Open(labelPath)
+		if os.IsNotExist(err) {
+			samples = append(samples, sample)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open labels: %v", err)
+		}
+		sample.GroundTruth, err = results.ReadYOLO(f, classes)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", labelPath, err)
+		}
+		samples = append(samples, sample)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no images found in %s", imageDir)
+	}
+	return samples, nil
+}
+
+// LoadCOCODataset reads a COCO annotation file, image file names are
+// resolved relative to imageDir. Returns the category names ordered by id.
+func LoadCOCODataset(annotationPath, imageDir string) ([]Sample, []string, error) {
+	f, err := os.Open(annotationPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open annotations: %v", err)
+	}
+	defer f.Close()
+
+	dataset, err := results.ReadCOCO(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	frames, classes, err := dataset.Frames()
+	if err != nil {
+		return nil, nil, err
+	}
+	crowds, err := dataset.Crowds()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	samples := make([]Sample, len(frames))
+	for i, frame := range frames {
+		samples[i] = Sample{ImagePath: filepath.Join(imageDir, frame.Source), GroundTruth: frame.Detections, Crowd: crowds[i]}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].ImagePath < samples[j].ImagePath })
+	return samples, classes, nil
+}
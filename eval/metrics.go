@@ -0,0 +1,265 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"yolo_detection/results"
+)
+
+// IoU thresholds of COCO mAP@0.5:0.95
+var IoUThresholds = []float32{0.5, 0.55, 0.6, 0.65, 0.7, 0.75, 0.8, 0.85, 0.9, 0.95}
+
+// name of the background row and column of the confusion matrix
+const Background = "background"
+
+// metrics of one class
+type ClassMetrics struct {
+	Class       string  `json:"class"`
+	Instances   int     `json:"instances"`   // ground truth boxes
+	Predictions int     `json:"predictions"` // predicted boxes
+	Precision   float64 `json:"precision"`   // at IoU 0.5 over all predictions
+	Recall      float64 `json:"recall"`
+	F1          float64 `json:"f1"`
+	AP50        float64 `json:"ap50"`
+	AP50_95     float64 `json:"ap50_95"`
+}
+
+// ConfusionMatrix counts matches at IoU 0.5. Missed ground truth is
+// counted as predicted background, false positives as actual background.
+type ConfusionMatrix struct {
+	Classes []string `json:"classes"` // the last entry is Background
+	Matrix  [][]int  `json:"matrix"`  // [predicted][actual]
+}
+
+// Report holds the evaluation of a dataset
+type Report struct {
+	Images    int             `json:"images"`
+	Classes   []ClassMetrics  `json:"classes"`
+	Precision float64         `json:"precision"` // means over classes with instances
+	Recall    float64         `json:"recall"`
+	MAP50     float64         `json:"map50"`
+	MAP50_95  float64         `json:"map50_95"`
+	Confusion ConfusionMatrix `json:"confusion"`
+}
+
+// one prediction of a class, by image
+type prediction struct {
+	image      int
+	box        results.Box
+	confidence float32
+}
+
+// Evaluate compares predictions with ground truth, both indexed by image
+func Evaluate(predictions, groundTruth [][]results.Detection, classes []string) (Report, error) {
+	if len(predictions) != len(groundTruth) {
+		return Report{}, fmt.Errorf("%d prediction sets for %d images", len(predictions), len(groundTruth))
+	}
+	index := make(map[string]int, len(classes))
+	for i, c := range classes {
+		index[c] = i
+	}
+
+	// group by class
+	preds := make([][]prediction, len(classes))
+	truth := make([][][]results.Box, len(classes)) // [class][image]
+	for c := range truth {
+		truth[c] = make([][]results.Box, len(groundTruth))
+	}
+	for img := range groundTruth {
+		for _, det := range groundTruth[img] {
+			c, ok := index[det.Class]
+			if !ok {
+				return Report{}, fmt.Errorf("ground truth class %q not in class list", det.Class)
+			}
+			truth[c][img] = append(truth[c][img], det.Box)
+		}
+		for _, det := range predictions[img] {
+			c, ok := index[det.Class]
+			if !ok {
+				return Report{}, fmt.Errorf("predicted class %q not in class list", det.Class)
+			}
+			preds[c] = append(preds[c], prediction{image: img, box: det.Box, confidence: det.Confidence})
+		}
+	}
+
+	report := Report{Images: len(groundTruth)}
+	var evaluated int
+	for c, class := range classes {
+		m := classMetrics(preds[c], truth[c])
+		m.Class = class
+		report.Classes = append(report.Classes, m)
+
+		if m.Instances > 0 {
+			evaluated++
+			report.Precision += m.Precision
+			report.Recall += m.Recall
+			report.MAP50 += m.AP50
+			report.MAP50_95 += m.AP50_95
+		}
+	}
+	if evaluated > 0 {
+		n := float64(evaluated)
+		report.Precision /= n
+		report.Recall /= n
+		report.MAP50 /= n
+		report.MAP50_95 /= n
+	}
+
+	report.Confusion = confusion(predictions, groundTruth, classes, index)
+	return report, nil
+}
+
+func classMetrics(preds []prediction, truth [][]results.Box) ClassMetrics {
+	var m ClassMetrics
+	for _, boxes := range truth {
+		m.Instances += len(boxes)
+	}
+	m.Predictions = len(preds)
+
+	sort.SliceStable(preds, func(i, j int) bool {
+		return preds[i].confidence > preds[j].confidence
+	})
+
+	for i, t := range IoUThresholds {
+		tp := matchPredictions(preds, truth, t)
+		ap := averagePrecision(tp, m.Instances)
+		m.AP50_95 += ap / float64(len(IoUThresholds))
+
+		if i == 0 {
+			m.AP50 = ap
+			var hits int
+			for _, ok := range tp {
+				if ok {
+					hits++
+				}
+			}
+			if m.Predictions > 0 {
+				m.Precision = float64(hits) / float64(m.Predictions)
+			}
+			if m.Instances > 0 {
+				m.Recall = float64(hits) / float64(m.Instances)
+			}
+			if m.Precision+m.Recall > 0 {
+				m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+			}
+		}
+	}
+	return m
+}
+
+// matchPredictions greedily matches predictions, sorted by confidence, to
+// the unmatched ground truth box of their image with the highest IoU and
+// reports which are true positives
+func matchPredictions(preds []prediction, truth [][]results.Box, threshold float32) []bool {
+	matched := make([][]bool, len(truth))
+	for img, boxes := range truth {
+		matched[img] = make([]bool, len(boxes))
+	}
+
+	tp := make([]bool, len(preds))
+	for i, p := range preds {
+		best, bestIoU := -1, threshold
+		for j, box := range truth[p.image] {
+			if matched[p.image][j] {
+				continue
+			}
+			if iou := p.box.IoU(box); iou >= bestIoU {
+				best, bestIoU = j, iou
+			}
+		}
+		if best >= 0 {
+			matched[p.image][best] = true
+			tp[i] = true
+		}
+	}
+	return tp
+}
+
+// averagePrecision is the area under the interpolated precision-recall
+// curve, sampled at 101 recall points like COCO
+func averagePrecision(tp []bool, instances int) float64 {
+	if instances == 0 || len(tp) == 0 {
+		return 0
+	}
+
+	precision := make([]float64, len(tp))
+	recall := make([]float64, len(tp))
+	var hits int
+	for i, ok := range tp {
+		if ok {
+			hits++
+		}
+		precision[i] = float64(hits) / float64(i+1)
+		recall[i] = float64(hits) / float64(instances)
+	}
+
+	// precision envelope, monotonically decreasing
+	for i := len(precision) - 2; i >= 0; i-- {
+		if precision[i+1] > precision[i] {
+			precision[i] = precision[i+1]
+		}
+	}
+
+	var sum float64
+	k := 0
+	for r := 0; r <= 100; r++ {
+		level := float64(r) / 100
+		for k < len(recall) && recall[k] < level {
+			k++
+		}
+		if k < len(recall) {
+			sum += precision[k]
+		}
+	}
+	return sum / 101
+}
+
+// confusion matches predictions of any class to ground truth by IoU
+func confusion(predictions, groundTruth [][]results.Detection, classes []string, index map[string]int) ConfusionMatrix {
+	n := len(classes) + 1
+	bg := len(classes)
+	cm := ConfusionMatrix{Classes: append(append([]string{}, classes...), Background), Matrix: make([][]int, n)}
+	for i := range cm.Matrix {
+		cm.Matrix[i] = make([]int, n)
+	}
+
+	for img := range groundTruth {
+		gts, preds := groundTruth[img], predictions[img]
+
+		// all candidate pairs, best overlaps first
+		type pair struct {
+			p, g int
+			iou  float32
+		}
+		var pairs []pair
+		for p := range preds {
+			for g := range gts {
+				if iou := preds[p].Box.IoU(gts[g].Box); iou >= IoUThresholds[0] {
+					pairs = append(pairs, pair{p, g, iou})
+				}
+			}
+		}
+		sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].iou > pairs[j].iou })
+
+		usedP := make([]bool, len(preds))
+		usedG := make([]bool, len(gts))
+		for _, pr := range pairs {
+			if usedP[pr.p] || usedG[pr.g] {
+				continue
+			}
+			usedP[pr.p], usedG[pr.g] = true, true
+			cm.Matrix[index[preds[pr.p].Class]][index[gts[pr.g].Class]]++
+		}
+		for p, used := range usedP {
+			if !used {
+				cm.Matrix[index[preds[p].Class]][bg]++
+			}
+		}
+		for g, used := range usedG {
+			if !used {
+				cm.Matrix[bg][index[gts[g].Class]]++
+			}
+		}
+	}
+	return cm
+}
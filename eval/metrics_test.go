@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"yolo_detection/results"
+)
+
+func det(class string, x1, y1, x2, y2, conf float32) results.Detection {
+	return results.Detection{Box: results.Box{X1: x1, Y1: y1, X2: x2, Y2: y2}, Class: class, Confidence: conf}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestEvaluatePerfect(t *testing.T) {
+	truth := [][]results.Detection{
+		{det("a", 0, 0, 10, 10, 1), det("b", 20, 20, 40, 40, 1)},
+		{det("a", 5, 5, 15, 15, 1)},
+	}
+	report, err := Evaluate(truth, truth, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !near(report.MAP50, 1) || !near(report.MAP50_95, 1) || !near(report.Precision, 1) || !near(report.Recall, 1) {
+		t.Errorf("expected perfect scores, got %+v", report)
+	}
+	if report.Confusion.Matrix[0][0] != 2 || report.Confusion.Matrix[1][1] != 1 {
+		t.Errorf("confusion %v", report.Confusion.Matrix)
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	classes := []string{"a", "b"}
+	truth := [][]results.Detection{
+		{det("a", 0, 0, 10, 10, 1), det("a", 50, 50, 60, 60, 1)},
+	}
+	preds := [][]results.Detection{{
+		det("a", 0, 0, 10, 10, 0.9),       // true positive
+		det("a", 100, 100, 110, 110, 0.8), // false positive
+		det("b", 50, 50, 60, 60, 0.7),     // wrong class for the second box
+	}}
+
+	report, err := Evaluate(preds, truth, classes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := report.Classes[0]
+	if a.Instances != 2 || a.Predictions != 2 || !near(a.Precision, 0.5) || !near(a.Recall, 0.5) {
+		t.Errorf("class a: %+v", a)
+	}
+	// precision 1 up to recall 0.5, then nothing: 51 of 101 recall points
+	if !near(a.AP50, 51.0/101) {
+		t.Errorf("AP50 %v", a.AP50)
+	}
+	// b has no instances and is left out of the means
+	if !near(report.MAP50, a.AP50) {
+		t.Errorf("mAP50 %v", report.MAP50)
+	}
+
+	m := report.Confusion.Matrix
+	bg := len(classes)
+	if m[0][0] != 1 || m[1][0] != 1 || m[0][bg] != 1 || m[bg][0] != 0 {
+		t.Errorf("confusion %v", m)
+	}
+
+	if !strings.Contains(report.String(), "background") {
+		t.Error("report misses confusion matrix")
+	}
+}
+
+func TestAveragePrecisionOrdering(t *testing.T) {
+	// a false positive ranked first lowers AP, ranked last it does not
+	if ap := averagePrecision([]bool{true, false}, 1); !near(ap, 1) {
+		t.Errorf("fp last: %v", ap)
+	}
+	if ap := averagePrecision([]bool{false, true}, 1); !near(ap, 0.5) {
+		t.Errorf("fp first: %v", ap)
+	}
+}
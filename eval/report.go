@@ -0,0 +1,56 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// String renders the per-class table followed by the confusion matrix
+func (r Report) String() string {
+	var b strings.Builder
+	r.Print(&b)
+	return b.String()
+}
+
+// Print writes the report as aligned text tables
+func (r Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "class\tinstances\tpredictions\tP\tR\tF1\tAP50\tAP50-95\t\n")
+	var instances, predictions int
+	for _, c := range r.Classes {
+		instances += c.Instances
+		predictions += c.Predictions
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t\n",
+			c.Class, c.Instances, c.Predictions, c.Precision, c.Recall, c.F1, c.AP50, c.AP50_95)
+	}
+	fmt.Fprintf(tw, "all (%d images)\t%d\t%d\t%.3f\t%.3f\t\t%.3f\t%.3f\t\n",
+		r.Images, instances, predictions, r.Precision, r.Recall, r.MAP50, r.MAP50_95)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nconfusion matrix (rows predicted, columns actual)\n")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "\t%s\t\n", strings.Join(r.Confusion.Classes, "\t"))
+	for i, row := range r.Confusion.Matrix {
+		cells := make([]string, len(row))
+		for j, v := range row {
+			cells[j] = fmt.Sprint(v)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t\n", r.Confusion.Classes[i], strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	return nil
+}
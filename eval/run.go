@@ -0,0 +1,98 @@
+package eval
+
+import (
+	"fmt"
+	"image"
+	"yolo_detection/detector"
+	"yolo_detection/imageloader"
+	"yolo_detection/results"
+)
+
+// confidence down to which predictions are scored, low enough for the
+// precision-recall curve to reach full recall
+const evalMinConfidence = 0.001
+
+// Run detects every sample and evaluates the predictions against the
+// ground truth. As for mAP in COCO, predictions are kept down to a
+// confidence of 0.001 and only the detector's IoU threshold is applied, and
+// predictions inside crowd regions are ignored. Classes default to the
+// detector's classes.
+func Run(d *detector.YOLODetector, samples []Sample, classes []string) (Report, error) {
+	if classes == nil {
+		classes = d.Classes()
+	}
+	nms := detector.Thresholds{IOUThreshold: d.Thresholds().IOUThreshold}
+
+	predictions := make([][]results.Detection, len(samples))
+	truth := make([][]results.Detection, len(samples))
+	for i, s := range samples {
+		img, err := imageloader.Load(s.ImagePath)
+		if err != nil {
+			return Report{}, fmt.Errorf("%s: %v", s.ImagePath, err)
+		}
+
+		candidates, err := d.Candidates(img, evalMinConfidence)
+		if err != nil {
+			return Report{}, fmt.Errorf("%s: %v", s.ImagePath, err)
+		}
+
+		truth[i] = s.pixelGroundTruth(img.Bounds())
+		predictions[i] = dropCrowd(detector.ApplyThresholds(candidates, nms), truth[i], s.Crowd)
+	}
+
+	return Evaluate(predictions, truth, classes)
+}
+
+// pixelGroundTruth returns the ground truth in pixel coordinates of an
+// image with the given bounds, after EXIF orientation as labelled
+func (s Sample) pixelGroundTruth(b image.Rectangle) []results.Detection {
+	if !s.Normalized {
+		return s.GroundTruth
+	}
+	truth := make([]results.Detection, len(s.GroundTruth))
+	for k, det := range s.GroundTruth {
+		det.Box = det.Box.Denormalize(float32(b.Dx()), float32(b.Dy()))
+		truth[k] = det
+	}
+	return truth
+}
+
+// dropCrowd removes the predictions COCO leaves unscored: those lying
+// mostly inside a crowd region of their class without matching a regular
+// ground truth box
+func dropCrowd(predictions, truth, crowd []results.Detection) []results.Detection {
+	if len(crowd) == 0 {
+		return predictions
+	}
+
+	kept := predictions[:0:0]
+	for _, p := range predictions {
+		if !inCrowd(p, crowd) || matchesTruth(p, truth) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// inCrowd reports whether at least half of the prediction lies inside a
+// crowd region of its class
+func inCrowd(p results.Detection, crowd []results.Detection) bool {
+	area := p.Box.Area()
+	for _, c := range crowd {
+		if c.Class == p.Class && area > 0 && p.Box.Intersect(c.Box).Area() >= IoUThresholds[0]*area {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesTruth reports whether the prediction overlaps a ground truth box
+// of its class at the lowest evaluated IoU
+func matchesTruth(p results.Detection, truth []results.Detection) bool {
+	for _, t := range truth {
+		if t.Class == p.Class && p.Box.IoU(t.Box) >= IoUThresholds[0] {
+			return true
+		}
+	}
+	return false
+}
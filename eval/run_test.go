@@ -0,0 +1,101 @@
+package eval
+
+import (
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"yolo_detection/results"
+)
+
+func TestDropCrowd(t *testing.T) {
+	crowd := []results.Detection{det("person", 0, 0, 100, 100, 0.8)}
+	truth := []results.Detection{det("person", 150, 0, 200, 50, 0.8), det("person", 10, 10, 30, 30, 0.8)}
+
+	for _, c := range []struct {
+		name string
+		pred results.Detection
+		kept bool
+	}{
+		{"inside the crowd", det("person", 40, 40, 60, 60, 0.8), false},
+		{"half inside", det("person", 90, 0, 110, 10, 0.8), false},
+		{"mostly outside", det("person", 95, 0, 115, 10, 0.8), true},
+		{"other class", det("car", 40, 40, 60, 60, 0.8), true},
+		{"matches truth inside the crowd", det("person", 10, 10, 30, 32, 0.8), true},
+		{"outside", det("person", 150, 0, 200, 50, 0.8), true},
+	} {
+		got := dropCrowd([]results.Detection{c.pred}, truth, crowd)
+		if kept := len(got) == 1; kept != c.kept {
+			t.Errorf("%s: kept %v, want %v", c.name, kept, c.kept)
+		}
+	}
+
+	preds := []results.Detection{det("person", 40, 40, 60, 60, 0.8)}
+	if got := dropCrowd(preds, nil, nil); len(got) != 1 {
+		t.Error("predictions dropped without crowd regions")
+	}
+	if dropCrowd(preds, nil, crowd); len(preds) != 1 || preds[0].Box.X1 != 40 {
+		t.Error("dropCrowd modified its input")
+	}
+}
+
+func TestCrowdNotCountedAsMissed(t *testing.T) {
+	classes := []string{"person"}
+	truth := []results.Detection{det("person", 150, 0, 200, 50, 0.8)}
+	crowd := []results.Detection{det("person", 0, 0, 100, 100, 0.8)}
+	preds := []results.Detection{det("person", 150, 0, 200, 50, 0.8), det("person", 20, 20, 60, 60, 0.8)}
+
+	report, err := Evaluate([][]results.Detection{dropCrowd(preds, truth, crowd)}, [][]results.Detection{truth}, classes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := report.Classes[0]
+	if m.Instances != 1 || m.Precision != 1 || m.Recall != 1 {
+		t.Errorf("metrics %+v, want one instance found without false positives", m)
+	}
+}
+
+func TestPixelGroundTruth(t *testing.T) {
+	s := Sample{GroundTruth: []results.Detection{det("a", 0.1, 0.2, 0.5, 1, 0.8)}, Normalized: true}
+	got := s.pixelGroundTruth(image.Rect(0, 0, 200, 100))
+	if got[0].Box != (results.Box{X1: 20, Y1: 20, X2: 100, Y2: 100}) {
+		t.Errorf("box %+v", got[0].Box)
+	}
+	if s.GroundTruth[0].Box.X2 != 0.5 {
+		t.Error("sample ground truth modified")
+	}
+
+	s.Normalized = false
+	if got := s.pixelGroundTruth(image.Rect(0, 0, 200, 100)); got[0].Box.X2 != 0.5 {
+		t.Errorf("pixel boxes scaled: %+v", got[0].Box)
+	}
+}
+
+func TestLoadCOCODatasetCrowd(t *testing.T) {
+	dir := t.TempDir()
+	annotations := filepath.Join(dir, "annotations.json")
+	data := `{
+		"images": [{"id": 1, "file_name": "b.jpg"}, {"id": 2, "file_name": "a.jpg"}],
+		"categories": [{"id": 1, "name": "person"}],
+		"annotations": [
+			{"id": 1, "image_id": 1, "category_id": 1, "bbox": [0, 0, 10, 10]},
+			{"id": 2, "image_id": 1, "category_id": 1, "bbox": [20, 20, 50, 50], "iscrowd": 1}
+		]
+	}`
+	if err := os.WriteFile(annotations, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, _, err := LoadCOCODataset(annotations, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sorted by path, b.jpg second
+	b := samples[1]
+	if filepath.Base(b.ImagePath) != "b.jpg" || len(b.GroundTruth) != 1 || len(b.Crowd) != 1 {
+		t.Fatalf("sample %+v", b)
+	}
+	if len(samples[0].GroundTruth) != 0 || len(samples[0].Crowd) != 0 {
+		t.Errorf("sample %+v", samples[0])
+	}
+}
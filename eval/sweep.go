@@ -0,0 +1,374 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"yolo_detection/detector"
+	"yolo_detection/imageloader"
+	"yolo_detection/results"
+)
+
+// CachedImage holds the pre-NMS candidates and the ground truth of one
+// image, both in pixel coordinates
+type CachedImage struct {
+	Path        string              `json:"path"`
+	Candidates  []results.Detection `json:"candidates"`
+	GroundTruth []results.Detection `json:"ground_truth"`
+}
+
+// CacheSource identifies the model and dataset a cache was built from
+type CacheSource struct {
+	ModelHash string `json:"model_hash"`
+	ImageDir  string `json:"image_dir"`
+	LabelDir  string `json:"label_dir"`
+}
+
+// CandidateCache stores the model output of a dataset, so thresholds can
+// be swept without running the model again
+type CandidateCache struct {
+	Source        CacheSource   `json:"source"`
+	MinConfidence float32       `json:"min_confidence"`
+	Classes       []string      `json:"classes"`
+	Images        []CachedImage `json:"images"`
+}
+
+// BuildCache runs the detector once over the samples, keeping every
+// candidate above minConfidence that is not inside a crowd region
+func BuildCache(d *detector.YOLODetector, samples []Sample, source CacheSource, minConfidence float32) (*CandidateCache, error) {
+	cache := &CandidateCache{Source: source, MinConfidence: minConfidence, Classes: d.Classes()}
+	for _, s := range samples {
+		img, err := imageloader.Load(s.ImagePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.ImagePath, err)
+		}
+		candidates, err := d.Candidates(img, minConfidence)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.ImagePath, err)
+		}
+
+		truth := s.pixelGroundTruth(img.Bounds())
+		candidates = dropCrowd(candidates, truth, s.Crowd)
+		cache.Images = append(cache.Images, CachedImage{Path: s.ImagePath, Candidates: candidates, GroundTruth: truth})
+	}
+	return cache, nil
+}
+
+// LoadCache reads a cache written by Save
+func LoadCache(path string) (*CandidateCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %v", err)
+	}
+	var cache CandidateCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("failed to parse cache %s: %v", path, err)
+	}
+	return &cache, nil
+}
+
+// Check reports why the cache was not built from source with these
+// classes, nil when it was
+func (c *CandidateCache) Check(source CacheSource, classes []string) error {
+	switch {
+	case c.Source.ModelHash != source.ModelHash:
+		return fmt.Errorf("cache was built with model %q, not %q", c.Source.ModelHash, source.ModelHash)
+	case c.Source.ImageDir != source.ImageDir || c.Source.LabelDir != source.LabelDir:
+		return fmt.Errorf("cache was built from %s and %s, not %s and %s",
+			c.Source.ImageDir, c.Source.LabelDir, source.ImageDir, source.LabelDir)
+	case strings.Join(c.Classes, "\n") != strings.Join(classes, "\n"):
+		return fmt.Errorf("cache classes %v differ from the detector classes %v", c.Classes, classes)
+	}
+	return nil
+}
+
+// Save writes the cache as JSON
+func (c *CandidateCache) Save(path string) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to save cache: %v", err)
+	}
+	return nil
+}
+
+// SweepOptions select the thresholds tried by Sweep
+type SweepOptions struct {
+	ConfThresholds []float32 // confidence thresholds, per class and global
+	IoUThresholds  []float32 // NMS thresholds
+	RecallTargets  []float64 // report the best precision reaching these recalls
+	MatchIoU       float32   // IoU with the ground truth counting as a hit
+}
+
+var DefaultSweepOptions = SweepOptions{
+	ConfThresholds: steps(0.05, 0.95, 0.01),
+	IoUThresholds:  steps(0.3, 0.8, 0.05),
+	RecallTargets:  []float64{0.5, 0.8, 0.9, 0.95},
+	MatchIoU:       0.5,
+}
+
+// steps returns from, from+step, ... up to to
+func steps(from, to, step float32) []float32 {
+	var values []float32
+	for i := 0; ; i++ {
+		v := from + float32(i)*step
+		if v > to+step/2 {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// OperatingPoint is the outcome of one confidence threshold
+type OperatingPoint struct {
+	Confidence float32 `json:"confidence"`
+	Precision  float64 `json:"precision"`
+	Recall     float64 `json:"recall"`
+	F1         float64 `json:"f1"`
+}
+
+// PrecisionAtRecall is the most precise operating point reaching Target
+type PrecisionAtRecall struct {
+	Target  float64        `json:"target"`
+	Reached bool           `json:"reached"`
+	Point   OperatingPoint `json:"point"`
+}
+
+// ClassSweep is the sweep of one class at one NMS threshold
+type ClassSweep struct {
+	Class     string              `json:"class"`
+	Instances int                 `json:"instances"`
+	Best      OperatingPoint      `json:"best"` // highest F1
+	AtRecall  []PrecisionAtRecall `json:"at_recall"`
+}
+
+// IoUSweep is the sweep at one NMS threshold
+type IoUSweep struct {
+	IoU     float32        `json:"iou"`
+	Global  OperatingPoint `json:"global"`  // best single threshold for all classes
+	MeanF1  float64        `json:"mean_f1"` // mean best F1 of classes with instances
+	Classes []ClassSweep   `json:"classes"`
+}
+
+// SweepReport holds all sweeps and the chosen thresholds
+type SweepReport struct {
+	Sweeps []IoUSweep          `json:"sweeps"`
+	Best   int                 `json:"best"` // index of the chosen sweep
+	Chosen detector.Thresholds `json:"chosen"`
+}
+
+// one step of a precision-recall curve
+type curve struct {
+	confidences []float32 // descending
+	hits        []int     // cumulative true positives
+	instances   int
+}
+
+// at returns the counts for predictions above threshold
+func (c curve) at(threshold float32) (kept, hits int) {
+	kept = sort.Search(len(c.confidences), func(i int) bool { return c.confidences[i] <= threshold })
+	if kept > 0 {
+		hits = c.hits[kept-1]
+	}
+	return kept, hits
+}
+
+func point(threshold float32, kept, hits, instances int) OperatingPoint {
+	p := OperatingPoint{Confidence: threshold}
+	if kept > 0 {
+		p.Precision = float64(hits) / float64(kept)
+	}
+	if instances > 0 {
+		p.Recall = float64(hits) / float64(instances)
+	}
+	if p.Precision+p.Recall > 0 {
+		p.F1 = 2 * p.Precision * p.Recall / (p.Precision + p.Recall)
+	}
+	return p
+}
+
+// Sweep evaluates every combination of NMS and confidence thresholds and
+// picks the NMS threshold with the best mean class F1, using the best
+// confidence per class there
+func Sweep(cache *CandidateCache, opts SweepOptions) SweepReport {
+	var report SweepReport
+	for _, iou := range opts.IoUThresholds {
+		report.Sweeps = append(report.Sweeps, sweepIoU(cache, iou, opts))
+	}
+
+	for i, s := range report.Sweeps {
+		if s.MeanF1 > report.Sweeps[report.Best].MeanF1 {
+			report.Best = i
+		}
+	}
+	if len(report.Sweeps) == 0 {
+		return report
+	}
+
+	best := report.Sweeps[report.Best]
+	report.Chosen = detector.Thresholds{
+		ConfThreshold:   best.Global.Confidence,
+		IOUThreshold:    best.IoU,
+		ClassThresholds: make(map[string]float32),
+	}
+	for _, c := range best.Classes {
+		if c.Instances > 0 {
+			report.Chosen.ClassThresholds[c.Class] = c.Best.Confidence
+		}
+	}
+	return report
+}
+
+func sweepIoU(cache *CandidateCache, iou float32, opts SweepOptions) IoUSweep {
+	sweep := IoUSweep{IoU: iou}
+	index := make(map[string]int, len(cache.Classes))
+	for i, c := range cache.Classes {
+		index[c] = i
+	}
+
+	// NMS once at the lowest confidence, higher thresholds keep a prefix
+	preds := make([][]prediction, len(cache.Classes))
+	truth := make([][][]results.Box, len(cache.Classes))
+	for c := range truth {
+		truth[c] = make([][]results.Box, len(cache.Images))
+	}
+	for img, cached := range cache.Images {
+		for _, det := range detector.ApplyThresholds(cached.Candidates, detector.Thresholds{IOUThreshold: iou}) {
+			if c, ok := index[det.Class]; ok {
+				preds[c] = append(preds[c], prediction{image: img, box: det.Box, confidence: det.Confidence})
+			}
+		}
+		for _, det := range cached.GroundTruth {
+			if c, ok := index[det.Class]; ok {
+				truth[c][img] = append(truth[c][img], det.Box)
+			}
+		}
+	}
+
+	curves := make([]curve, len(cache.Classes))
+	for c := range cache.Classes {
+		sort.SliceStable(preds[c], func(i, j int) bool { return preds[c][i].confidence > preds[c][j].confidence })
+		tp := matchPredictions(preds[c], truth[c], opts.MatchIoU)
+
+		cv := curve{confidences: make([]float32, len(tp)), hits: make([]int, len(tp))}
+		var hits int
+		for i, ok := range tp {
+			if ok {
+				hits++
+			}
+			cv.confidences[i] = preds[c][i].confidence
+			cv.hits[i] = hits
+		}
+		for _, boxes := range truth[c] {
+			cv.instances += len(boxes)
+		}
+		curves[c] = cv
+	}
+
+	var evaluated int
+	for c, class := range cache.Classes {
+		cs := ClassSweep{Class: class, Instances: curves[c].instances}
+		for _, target := range opts.RecallTargets {
+			cs.AtRecall = append(cs.AtRecall, PrecisionAtRecall{Target: target})
+		}
+		for _, t := range opts.ConfThresholds {
+			kept, hits := curves[c].at(t)
+			p := point(t, kept, hits, cs.Instances)
+			if p.F1 > cs.Best.F1 || cs.Best.Confidence == 0 {
+				cs.Best = p
+			}
+			for i := range cs.AtRecall {
+				a := &cs.AtRecall[i]
+				if p.Recall >= a.Target && (!a.Reached || p.Precision > a.Point.Precision) {
+					a.Reached = true
+					a.Point = p
+				}
+			}
+		}
+		if cs.Instances > 0 {
+			sweep.MeanF1 += cs.Best.F1
+			evaluated++
+		}
+		sweep.Classes = append(sweep.Classes, cs)
+	}
+	if evaluated > 0 {
+		sweep.MeanF1 /= float64(evaluated)
+	}
+
+	// one threshold for all classes, micro averaged
+	for _, t := range opts.ConfThresholds {
+		var kept, hits, instances int
+		for _, cv := range curves {
+			k, h := cv.at(t)
+			kept += k
+			hits += h
+			instances += cv.instances
+		}
+		p := point(t, kept, hits, instances)
+		if p.F1 > sweep.Global.F1 || sweep.Global.Confidence == 0 {
+			sweep.Global = p
+		}
+	}
+	return sweep
+}
+
+// Print writes the NMS overview and the per-class details of the chosen
+// NMS threshold
+func (r SweepReport) Print(w io.Writer) error {
+	if len(r.Sweeps) == 0 {
+		_, err := fmt.Fprintln(w, "no thresholds swept")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "NMS IoU\tglobal conf\tP\tR\tF1\tmean class F1\t\n")
+	for i, s := range r.Sweeps {
+		mark := ""
+		if i == r.Best {
+			mark = " *"
+		}
+		fmt.Fprintf(tw, "%.2f%s\t%.2f\t%.3f\t%.3f\t%.3f\t%.3f\t\n",
+			s.IoU, mark, s.Global.Confidence, s.Global.Precision, s.Global.Recall, s.Global.F1, s.MeanF1)
+	}
+	tw.Flush()
+
+	best := r.Sweeps[r.Best]
+	fmt.Fprintf(w, "\nper class at NMS IoU %.2f\n", best.IoU)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := []string{"class", "instances", "conf", "P", "R", "F1"}
+	// every class has the same recall targets
+	if len(best.Classes) > 0 {
+		for _, a := range best.Classes[0].AtRecall {
+			header = append(header, fmt.Sprintf("P@R%.2f", a.Target))
+		}
+	}
+	fmt.Fprintf(tw, "%s\t\n", strings.Join(header, "\t"))
+	for _, c := range best.Classes {
+		fmt.Fprintf(tw, "%s\t%d\t%.2f\t%.3f\t%.3f\t%.3f", c.Class, c.Instances, c.Best.Confidence, c.Best.Precision, c.Best.Recall, c.Best.F1)
+		for _, a := range c.AtRecall {
+			if a.Reached {
+				fmt.Fprintf(tw, "\t%.3f@%.2f", a.Point.Precision, a.Point.Confidence)
+			} else {
+				fmt.Fprintf(tw, "\t-")
+			}
+		}
+		fmt.Fprintf(tw, "\t\n")
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as indented JSON
+func (r SweepReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("failed to encode report: %v", err)
+	}
+	return nil
+}
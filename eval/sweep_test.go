@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"yolo_detection/results"
+)
+
+func TestSweepPicksSeparatingThreshold(t *testing.T) {
+	cat := func(x, conf float32) results.Detection {
+		return results.Detection{Box: results.Box{X1: x, Y1: 0, X2: x + 10, Y2: 10}, Class: "cat", Confidence: conf}
+	}
+	cache := &CandidateCache{
+		MinConfidence: 0.01,
+		Classes:       []string{"cat", "dog"},
+		Images: []CachedImage{{
+			// two hits above 0.6, a duplicate removed by NMS and a false
+			// positive at 0.3
+			Candidates:  []results.Detection{cat(0, 0.9), cat(1, 0.5), cat(50, 0.7), cat(100, 0.3)},
+			GroundTruth: []results.Detection{cat(0, 1), cat(50, 1)},
+		}},
+	}
+
+	report := Sweep(cache, SweepOptions{
+		ConfThresholds: []float32{0.1, 0.4, 0.8},
+		IoUThresholds:  []float32{0.5},
+		RecallTargets:  []float64{1},
+		MatchIoU:       0.5,
+	})
+
+	best := report.Sweeps[0].Classes[0]
+	if best.Best.Confidence != 0.4 || best.Best.F1 != 1 {
+		t.Errorf("best point %+v, want confidence 0.4 with F1 1", best.Best)
+	}
+	if a := best.AtRecall[0]; !a.Reached || a.Point.Precision != 1 {
+		t.Errorf("precision at full recall %+v, want 1", a)
+	}
+	if c := report.Chosen; c.IOUThreshold != 0.5 || c.ClassThresholds["cat"] != 0.4 {
+		t.Errorf("chosen thresholds %+v", c)
+	}
+	if _, ok := report.Chosen.ClassThresholds["dog"]; ok {
+		t.Error("class without instances should keep the global threshold")
+	}
+}
+
+func TestCacheCheck(t *testing.T) {
+	source := CacheSource{ModelHash: "abc", ImageDir: "/data/images", LabelDir: "/data/labels"}
+	cache := &CandidateCache{Source: source, Classes: []string{"cat", "dog"}}
+
+	if err := cache.Check(source, []string{"cat", "dog"}); err != nil {
+		t.Errorf("matching cache rejected: %v", err)
+	}
+	for name, c := range map[string]struct {
+		source  CacheSource
+		classes []string
+	}{
+		"model":   {CacheSource{ModelHash: "def", ImageDir: "/data/images", LabelDir: "/data/labels"}, []string{"cat", "dog"}},
+		"images":  {CacheSource{ModelHash: "abc", ImageDir: "/other", LabelDir: "/data/labels"}, []string{"cat", "dog"}},
+		"labels":  {CacheSource{ModelHash: "abc", ImageDir: "/data/images", LabelDir: "/other"}, []string{"cat", "dog"}},
+		"classes": {source, []string{"dog", "cat"}},
+	} {
+		if err := cache.Check(c.source, c.classes); err == nil {
+			t.Errorf("%s: stale cache accepted", name)
+		}
+	}
+}
+
+func TestSweepReportPrintWithoutClasses(t *testing.T) {
+	report := Sweep(&CandidateCache{MinConfidence: 0.01}, SweepOptions{
+		ConfThresholds: []float32{0.5},
+		IoUThresholds:  []float32{0.5},
+		RecallTargets:  []float64{0.9},
+		MatchIoU:       0.5,
+	})
+	var buf bytes.Buffer
+	if err := report.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "per class at NMS IoU 0.50") {
+		t.Errorf("output %q", buf.String())
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"yolo_detection/bench"
+	"yolo_detection/classifier"
+	"yolo_detection/detector"
+	"yolo_detection/eval"
+)
+
+func cmdEvaluate(ctx context.Context, args []string) error {
+	fs := newFlags("evaluate", "dataset-dir")
+	lib := runtimeFlag(fs)
+	modelPath := fs.String("model", "", "classifier model (required)")
+	labels := fs.String("labels", "", "model labels in output order, comma separated or a file with one per line, defaults to the dataset folders")
+	jsonPath := fs.String("json", "", "also write the report as JSON to this file")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" {
+		return usagef("-model is required")
+	}
+	if fs.NArg() != 1 {
+		return usagef("expected one dataset directory, got %d arguments", fs.NArg())
+	}
+	modelLabels, err := parseLabels(*labels)
+	if err != nil {
+		return err
+	}
+
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+	return RunClassifierEval(ctx, *modelPath, fs.Arg(0), modelLabels, *jsonPath)
+}
+
+func cmdSweep(ctx context.Context, args []string) error {
+	fs := newFlags("sweep", "")
+	lib := runtimeFlag(fs)
+	modelPath := fs.String("model", "", "detector model (required)")
+	images := fs.String("images", "", "image directory (required)")
+	labels := fs.String("labels", "", "YOLO label directory, defaults to the image directory")
+	cache := fs.String("cache", "sweep_cache.json", "candidate cache, reused when it exists")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" || *images == "" {
+		return usagef("-model and -images are required")
+	}
+	if *labels == "" {
+		*labels = *images
+	}
+
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+	return RunThresholdSweep(ctx, *modelPath, *images, *labels, *cache)
+}
+
+// RunClassifierEval evaluates a classifier on a folder-per-class dataset
+// (datasetDir/<label>/*.jpg), prints the report and optionally writes it
+// as JSON. modelLabels name the model outputs in order, the sorted dataset
+// labels are used when it is empty.
+func RunClassifierEval(ctx context.Context, modelPath, datasetDir string, modelLabels []string, jsonPath string) error {
+	samples, labels, err := eval.LoadClassFolders(datasetDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Evaluating %d images of labels %v\n", len(samples), labels)
+
+	if len(modelLabels) == 0 {
+		modelLabels = labels
+	}
+	// RunClassifier fails on images of a label the model cannot output,
+	// report that before loading the model
+	known := make(map[string]bool, len(modelLabels))
+	for _, l := range modelLabels {
+		known[l] = true
+	}
+	for _, l := range labels {
+		if !known[l] {
+			return fmt.Errorf("dataset label %q is not a model label %v", l, modelLabels)
+		}
+	}
+
+	config := classifier.DefaultConfig
+	config.Labels = modelLabels
+	model, err := classifier.NewWithConfig(ctx, modelPath, config)
+	if err != nil {
+		return fmt.Errorf("error initializing classifier: %v", err)
+	}
+	defer model.Destroy()
+
+	report, err := eval.RunClassifier(model, samples, 10, 20)
+	if err != nil {
+		return err
+	}
+	report.Print(stdout)
+
+	if jsonPath == "" {
+		return nil
+	}
+	f, err := os.Create(jsonPath)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %v", err)
+	}
+	defer f.Close()
+	return report.WriteJSON(f)
+}
+
+// RunThresholdSweep sweeps the confidence and NMS thresholds of a detector
+// over a YOLO labelled dataset and saves the best operating point next to
+// the model, where detector.New picks it up. The model outputs are cached in
+// cachePath, so later sweeps skip inference.
+func RunThresholdSweep(ctx context.Context, modelPath, imageDir, labelDir, cachePath string) error {
+	d, err := detector.New(ctx, modelPath)
+	if err != nil {
+		return fmt.Errorf("error initializing detector: %v", err)
+	}
+	defer d.Destroy()
+	var source eval.CacheSource
+	if source.ModelHash, err = bench.ModelHash(modelPath); err != nil {
+		return err
+	}
+	if source.ImageDir, err = filepath.Abs(imageDir); err != nil {
+		return err
+	}
+	if source.LabelDir, err = filepath.Abs(labelDir); err != nil {
+		return err
+	}
+
+	// a cache of another model or dataset would tune the wrong thresholds
+	cache, err := eval.LoadCache(cachePath)
+	if err == nil {
+		err = cache.Check(source, d.Classes())
+	}
+	if err != nil {
+		fmt.Printf("No usable candidate cache (%v), running the model\n", err)
+
+		samples, err := eval.LoadYOLODataset(imageDir, labelDir, d.Classes())
+		if err != nil {
+			return err
+		}
+		if cache, err = eval.BuildCache(d, samples, source, 0.01); err != nil {
+			return err
+		}
+		if err := cache.Save(cachePath); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Sweeping thresholds over %d images\n", len(cache.Images))
+
+	report := eval.Sweep(cache, eval.DefaultSweepOptions)
+	report.Print(stdout)
+
+	path := detector.ThresholdsPath(modelPath)
+	if err := report.Chosen.Save(path); err != nil {
+		return err
+	}
+	fmt.Printf("Saved thresholds to %s\n", path)
+	return nil
+}
@@ -0,0 +1,144 @@
+package imageloader
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// EXIF orientation tag
+const orientationTag = 0x0112
+
+// Orientation returns the EXIF orientation (1-8) stored in an encoded JPEG,
+// PNG, TIFF or WebP file, or 1 if there is none
+func Orientation(data []byte) int {
+	var tiff []byte
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		tiff = jpegExif(data)
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		tiff = data
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		tiff = pngExif(data)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		tiff = webpExif(data)
+	}
+
+	o := tiffOrientation(bytes.TrimPrefix(tiff, []byte("Exif\x00\x00")))
+	if o < 1 || o > 8 {
+		return 1
+	}
+	return o
+}
+
+// jpegExif returns the payload of the APP1 Exif segment
+func jpegExif(data []byte) []byte {
+	pos := 2
+	for pos+2 <= len(data) {
+		if data[pos] != 0xFF {
+			return nil
+		}
+		marker := data[pos+1]
+		// fill bytes may pad any marker
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+		// start of scan, no more metadata
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		// standalone markers (TEM, RSTn) have no length
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			pos += 2
+			continue
+		}
+		if pos+4 > len(data) {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil
+		}
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment
+		}
+		pos = end
+	}
+	return nil
+}
+
+// pngExif returns the payload of the eXIf chunk
+func pngExif(data []byte) []byte {
+	pos := 8
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		kind := string(data[pos+4 : pos+8])
+		end := pos + 8 + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+		if kind == "eXIf" {
+			return data[pos+8 : end]
+		}
+		if kind == "IDAT" || kind == "IEND" {
+			return nil
+		}
+		pos = end + 4 // skip crc
+	}
+	return nil
+}
+
+// webpExif returns the payload of the EXIF chunk
+func webpExif(data []byte) []byte {
+	pos := 12
+	for pos+8 <= len(data) {
+		kind := string(data[pos : pos+4])
+		length := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + length
+		if length < 0 || end > len(data) {
+			return nil
+		}
+		if kind == "EXIF" {
+			return data[pos+8 : end]
+		}
+		// chunks are padded to even sizes
+		pos = end + length%2
+	}
+	return nil
+}
+
+// tiffOrientation reads the orientation tag from IFD0 of a TIFF structure
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[0:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == orientationTag {
+			// SHORT value stored in the first two bytes of the value field
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,118 @@
+package imageloader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// tiffHeader builds a TIFF structure whose IFD0 holds a single orientation
+// entry
+func tiffHeader(order binary.ByteOrder, orientation int) []byte {
+	buf := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(buf, "II*\x00")
+	} else {
+		copy(buf, "MM\x00*")
+	}
+	order.PutUint32(buf[4:], 8)
+	order.PutUint16(buf[8:], 1)
+	order.PutUint16(buf[10:], orientationTag)
+	order.PutUint16(buf[12:], 3) // SHORT
+	order.PutUint32(buf[14:], 1)
+	order.PutUint16(buf[18:], uint16(orientation))
+	return buf
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func exifPayload(orientation int) []byte {
+	return append([]byte("Exif\x00\x00"), tiffHeader(binary.BigEndian, orientation)...)
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func pngChunk(kind string, payload []byte) []byte {
+	chunk := make([]byte, 8, 8+len(payload)+4)
+	binary.BigEndian.PutUint32(chunk, uint32(len(payload)))
+	copy(chunk[4:], kind)
+	chunk = append(chunk, payload...)
+	return append(chunk, 0, 0, 0, 0) // crc is not checked
+}
+
+func webpChunk(kind string, payload []byte) []byte {
+	chunk := make([]byte, 8, 8+len(payload)+1)
+	copy(chunk, kind)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(payload)))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	body := concat(chunks...)
+	header := make([]byte, 12)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+len(body)))
+	copy(header[8:], "WEBP")
+	return concat(header, body)
+}
+
+var (
+	soi     = []byte{0xFF, 0xD8}
+	sos     = []byte{0xFF, 0xDA, 0x00, 0x08}
+	pngSig  = []byte("\x89PNG\r\n\x1a\n")
+	jfif    = jpegSegment(0xE0, []byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"))
+	ihdr    = pngChunk("IHDR", make([]byte, 13))
+	vp8x    = webpChunk("VP8X", make([]byte, 10))
+	oddData = webpChunk("ICCP", []byte{1, 2, 3})
+)
+
+func TestOrientation(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"empty", nil, 1},
+		{"unknown format", []byte("GIF89a"), 1},
+
+		{"tiff little endian", tiffHeader(binary.LittleEndian, 6), 6},
+		{"tiff big endian", tiffHeader(binary.BigEndian, 8), 8},
+		{"tiff out of range", tiffHeader(binary.BigEndian, 9), 1},
+		{"tiff truncated", tiffHeader(binary.BigEndian, 6)[:12], 1},
+
+		{"jpeg app1", concat(soi, jpegSegment(0xE1, exifPayload(3)), sos), 3},
+		{"jpeg app1 after jfif", concat(soi, jfif, jpegSegment(0xE1, exifPayload(5)), sos), 5},
+		{"jpeg xmp app1", concat(soi, jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00")), sos), 1},
+		{"jpeg fill bytes", concat(soi, []byte{0xFF, 0xFF, 0xFF}, jpegSegment(0xE1, exifPayload(6)), sos), 6},
+		{"jpeg standalone markers", concat(soi, []byte{0xFF, 0x01, 0xFF, 0xD0, 0xFF, 0xD7}, jpegSegment(0xE1, exifPayload(7)), sos), 7},
+		{"jpeg exif after scan", concat(soi, sos, jpegSegment(0xE1, exifPayload(6))), 1},
+		{"jpeg truncated segment", concat(soi, jpegSegment(0xE1, exifPayload(6)))[:20], 1},
+		{"jpeg garbage", concat(soi, []byte{0x00, 0x01, 0x02, 0x03}), 1},
+
+		{"png exif", concat(pngSig, ihdr, pngChunk("eXIf", tiffHeader(binary.LittleEndian, 6))), 6},
+		{"png exif after idat", concat(pngSig, ihdr, pngChunk("IDAT", []byte{0}), pngChunk("eXIf", tiffHeader(binary.LittleEndian, 6))), 1},
+		{"png without exif", concat(pngSig, ihdr, pngChunk("IEND", nil)), 1},
+
+		{"webp exif", webpFile(vp8x, webpChunk("EXIF", tiffHeader(binary.LittleEndian, 8))), 8},
+		{"webp exif with prefix", webpFile(vp8x, webpChunk("EXIF", exifPayload(2))), 2},
+		{"webp exif after odd chunk", webpFile(vp8x, oddData, webpChunk("EXIF", tiffHeader(binary.BigEndian, 4))), 4},
+		{"webp without exif", webpFile(vp8x), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Orientation(tt.data); got != tt.want {
+				t.Errorf("Orientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package imageloader
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	// registered decoders
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// Extensions are the lower case file extensions of the registered decoders
+var Extensions = []string{".bmp", ".gif", ".jpeg", ".jpg", ".png", ".tif", ".tiff", ".webp"}
+
+// IsImage reports whether the path has one of Extensions, ignoring case
+func IsImage(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrFileTooLarge  = errors.New("image file too large")
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// Limits protect against decompression bombs. Zero disables a limit.
+type Limits struct {
+	MaxFileSize int64 // bytes
+	MaxPixels   int64 // width * height
+}
+
+var DefaultLimits = Limits{
+	MaxFileSize: 50 << 20,
+	MaxPixels:   50_000_000,
+}
+
+// Load reads an image file with DefaultLimits and applies its EXIF orientation
+func Load(path string) (image.Image, error) {
+	return LoadWithLimits(path, DefaultLimits)
+}
+
+// LoadWithLimits reads an image file and applies its EXIF orientation. The
+// file size is checked before reading and the pixel count before decoding.
+func LoadWithLimits(path string, limits Limits) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if limits.MaxFileSize > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if info.Size() > limits.MaxFileSize {
+			return nil, fmt.Errorf("%s: %w (%d > %d bytes)", path, ErrFileTooLarge, info.Size(), limits.MaxFileSize)
+		}
+	}
+
+	img, _, err := Decode(f, limits)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Decode reads an image from r within the limits and applies its EXIF
+// orientation. It returns the format name like image.Decode.
+func Decode(r io.Reader, limits Limits) (image.Image, string, error) {
+	// read at most one byte more than allowed to detect oversized input
+	if limits.MaxFileSize > 0 {
+		r = io.LimitReader(r, limits.MaxFileSize+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if limits.MaxFileSize > 0 && int64(len(data)) > limits.MaxFileSize {
+		return nil, "", fmt.Errorf("%w (more than %d bytes)", ErrFileTooLarge, limits.MaxFileSize)
+	}
+
+	// check dimensions from the header before allocating pixels
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read image header: %v", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, format, fmt.Errorf("invalid image size %dx%d", config.Width, config.Height)
+	}
+	if pixels := int64(config.Width) * int64(config.Height); limits.MaxPixels > 0 && pixels > limits.MaxPixels {
+		return nil, format, fmt.Errorf("%w (%dx%d > %d pixels)", ErrTooManyPixels, config.Width, config.Height, limits.MaxPixels)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, fmt.Errorf("failed to decode %s image: %v", format, err)
+	}
+
+	return ApplyOrientation(img, Orientation(data)), format, nil
+}
@@ -0,0 +1,109 @@
+package imageloader
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeLimits(t *testing.T) {
+	data := encodePNG(t, 8, 4)
+	size := int64(len(data))
+
+	tests := []struct {
+		name   string
+		limits Limits
+		want   error
+	}{
+		{"no limits", Limits{}, nil},
+		{"defaults", DefaultLimits, nil},
+		{"exact size", Limits{MaxFileSize: size, MaxPixels: 32}, nil},
+		{"one byte over", Limits{MaxFileSize: size - 1}, ErrFileTooLarge},
+		{"one pixel over", Limits{MaxPixels: 31}, ErrTooManyPixels},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, format, err := Decode(bytes.NewReader(data), tt.limits)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil {
+				return
+			}
+			if format != "png" || img.Bounds().Dx() != 8 || img.Bounds().Dy() != 4 {
+				t.Errorf("got %s %v", format, img.Bounds())
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	if _, _, err := Decode(bytes.NewReader([]byte("not an image")), DefaultLimits); err == nil {
+		t.Error("expected an error for garbage input")
+	}
+}
+
+func TestDecodeAppliesOrientation(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 16, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	// insert the Exif segment right after SOI
+	data := concat(soi, jpegSegment(0xE1, exifPayload(6)), buf.Bytes()[2:])
+
+	img, format, err := Decode(bytes.NewReader(data), DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != "jpeg" || img.Bounds().Dx() != 8 || img.Bounds().Dy() != 16 {
+		t.Errorf("got %s %v, want a rotated 8x16 jpeg", format, img.Bounds())
+	}
+}
+
+func TestLoadWithLimits(t *testing.T) {
+	data := encodePNG(t, 8, 4)
+	path := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if _, err := LoadWithLimits(path, Limits{MaxFileSize: int64(len(data)) - 1}); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("file size limit: err = %v, want %v", err, ErrFileTooLarge)
+	}
+	if _, err := LoadWithLimits(path, Limits{MaxPixels: 16}); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("pixel limit: err = %v, want %v", err, ErrTooManyPixels)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.png")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+func TestIsImage(t *testing.T) {
+	for _, path := range []string{"a.jpg", "b.JPEG", "dir/c.png", "d.gif", "e.bmp", "f.tif", "g.TIFF", "h.webp"} {
+		if !IsImage(path) {
+			t.Errorf("%s not recognised as an image", path)
+		}
+	}
+	for _, path := range []string{"notes.txt", "jpg", "archive.png.zip", "dir.png/file", ""} {
+		if IsImage(path) {
+			t.Errorf("%s recognised as an image", path)
+		}
+	}
+}
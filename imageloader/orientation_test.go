@@ -0,0 +1,66 @@
+package imageloader
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// numbered returns a 3x2 image whose pixels are 1..6 in reading order
+func numbered() image.Image {
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i + 1)
+	}
+	return img
+}
+
+// pixels returns the gray values of img row by row
+func pixels(img image.Image) [][]uint8 {
+	b := img.Bounds()
+	rows := make([][]uint8, b.Dy())
+	for y := range rows {
+		rows[y] = make([]uint8, b.Dx())
+		for x := range rows[y] {
+			rows[y][x] = color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+		}
+	}
+	return rows
+}
+
+func TestApplyOrientation(t *testing.T) {
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+		{2, [][]uint8{{3, 2, 1}, {6, 5, 4}}},
+		{3, [][]uint8{{6, 5, 4}, {3, 2, 1}}},
+		{4, [][]uint8{{4, 5, 6}, {1, 2, 3}}},
+		{5, [][]uint8{{1, 4}, {2, 5}, {3, 6}}},
+		{6, [][]uint8{{4, 1}, {5, 2}, {6, 3}}},
+		{7, [][]uint8{{6, 3}, {5, 2}, {4, 1}}},
+		{8, [][]uint8{{3, 6}, {2, 5}, {1, 4}}},
+		{9, [][]uint8{{1, 2, 3}, {4, 5, 6}}},
+	}
+	for _, tt := range tests {
+		if got := pixels(ApplyOrientation(numbered(), tt.orientation)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("orientation %d: got %v, want %v", tt.orientation, got, tt.want)
+		}
+	}
+}
+
+func TestApplyOrientationOffsetBounds(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 5, 4))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i + 1)
+	}
+	// the 3x2 window starting at (1, 1) holds 7 8 9 / 12 13 14
+	sub := img.SubImage(image.Rect(1, 1, 4, 3))
+
+	want := [][]uint8{{12, 7}, {13, 8}, {14, 9}}
+	if got := pixels(ApplyOrientation(sub, 6)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
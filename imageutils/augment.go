@@ -0,0 +1,111 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Augmentation describes one test-time augmentation pass. The image keeps
+// its size: with Scale < 1 the content shrinks and is padded, with Scale > 1
+// the centre is zoomed in and the border cropped.
+type Augmentation struct {
+	FlipHorizontal bool
+	Scale          float64 // 0 is treated as 1
+}
+
+// DefaultAugmentations mirrors the ultralytics TTA set (scales 1, 0.83, 0.67
+// with the middle pass flipped) plus a flipped full-scale pass
+var DefaultAugmentations = []Augmentation{
+	{Scale: 1},
+	{Scale: 1, FlipHorizontal: true},
+	{Scale: 0.83, FlipHorizontal: true},
+	{Scale: 0.67},
+}
+
+// padding used for shrunk content
+var augmentPadColor = color.RGBA{R: 114, G: 114, B: 114, A: 255}
+
+func (a Augmentation) scale() float64 {
+	if a.Scale <= 0 {
+		return 1
+	}
+	return a.Scale
+}
+
+// offset returns where the scaled content starts on a canvas of w x h
+func (a Augmentation) offset(w, h int) (int, int) {
+	s := a.scale()
+	newW, newH := int(float64(w)*s+0.5), int(float64(h)*s+0.5)
+	return (w - newW) / 2, (h - newH) / 2
+}
+
+// Apply returns the augmented image with bounds starting at 0, 0. Scaled
+// passes resample with interp, normally the model's PreprocessSpec one.
+func (a Augmentation) Apply(img image.Image, interp Interpolation) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	s := a.scale()
+
+	out := img
+	if s != 1 {
+		newW, newH := int(float64(w)*s+0.5), int(float64(h)*s+0.5)
+		resized := ResizeWith(img, newW, newH, interp)
+
+		canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(augmentPadColor), image.Point{}, draw.Src)
+
+		offX, offY := a.offset(w, h)
+		draw.Draw(canvas, image.Rect(offX, offY, offX+newW, offY+newH), resized, image.Point{}, draw.Src)
+		out = canvas
+	}
+
+	if a.FlipHorizontal {
+		out = FlipHorizontal(out)
+	}
+	return out
+}
+
+// InvertPoint maps a point of the augmented image back onto the original
+// image of the given size
+func (a Augmentation) InvertPoint(x, y float64, size ImageSize) (float64, float64) {
+	if a.FlipHorizontal {
+		x = float64(size.Width) - x
+	}
+	offX, offY := a.offset(size.Width, size.Height)
+	s := a.scale()
+	return (x - float64(offX)) / s, (y - float64(offY)) / s
+}
+
+// FlipHorizontal mirrors the image left to right
+func FlipHorizontal(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(w-1-x, y, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// Resize scales the image to width x height using nearest neighbour
+func Resize(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 || bounds.Empty() {
+		return dst
+	}
+
+	scaleX := float64(bounds.Dx()) / float64(width)
+	scaleY := float64(bounds.Dy()) / float64(height)
+	for y := 0; y < height; y++ {
+		origY := bounds.Min.Y + int(float64(y)*scaleY)
+		for x := 0; x < width; x++ {
+			origX := bounds.Min.X + int(float64(x)*scaleX)
+			dst.Set(x, y, img.At(origX, origY))
+		}
+	}
+	return dst
+}
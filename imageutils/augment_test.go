@@ -0,0 +1,121 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// markedImage is black with a white rectangle at r
+func markedImage(w, h int, r image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{A: 255}
+			if (image.Point{X: x, Y: y}).In(r) {
+				c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// brightBounds returns the bounding rectangle of pixels brighter than the
+// augmentation padding
+func brightBounds(img image.Image) image.Rectangle {
+	var r image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if v, _, _, _ := img.At(x, y).RGBA(); v>>8 > 192 {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestAugmentationRoundTrip(t *testing.T) {
+	size := ImageSize{Width: 120, Height: 90}
+	mark := image.Rect(20, 10, 60, 50)
+	img := markedImage(size.Width, size.Height, mark)
+
+	for _, aug := range []Augmentation{
+		{},
+		{Scale: 1},
+		{FlipHorizontal: true},
+		{Scale: 0.83},
+		{Scale: 0.67, FlipHorizontal: true},
+		{Scale: 0.5},
+		{Scale: 1.25},
+		{Scale: 1.25, FlipHorizontal: true},
+	} {
+		out := aug.Apply(img, Nearest)
+		if out.Bounds() != image.Rect(0, 0, size.Width, size.Height) {
+			t.Errorf("%+v: bounds %v", aug, out.Bounds())
+			continue
+		}
+
+		found := brightBounds(out)
+		x1, y1 := aug.InvertPoint(float64(found.Min.X), float64(found.Min.Y), size)
+		x2, y2 := aug.InvertPoint(float64(found.Max.X), float64(found.Max.Y), size)
+		if x1 > x2 {
+			x1, x2 = x2, x1
+		}
+
+		// rounding of the scaled size and nearest sampling shift edges by
+		// up to a pixel of the augmented image
+		tolerance := 1.5 / aug.scale()
+		for _, edge := range []struct {
+			got  float64
+			want int
+		}{{x1, mark.Min.X}, {y1, mark.Min.Y}, {x2, mark.Max.X}, {y2, mark.Max.Y}} {
+			if math.Abs(edge.got-float64(edge.want)) > tolerance {
+				t.Errorf("%+v: box %v inverted to (%.1f, %.1f, %.1f, %.1f), want %v", aug, found, x1, y1, x2, y2, mark)
+				break
+			}
+		}
+	}
+}
+
+func TestAugmentationInvertPoint(t *testing.T) {
+	size := ImageSize{Width: 100, Height: 60}
+	for _, tc := range []struct {
+		aug          Augmentation
+		x, y         float64
+		wantX, wantY float64
+	}{
+		{Augmentation{}, 30, 20, 30, 20},
+		{Augmentation{FlipHorizontal: true}, 30, 20, 70, 20},
+		// content of 50 x 30 centred at 25, 15
+		{Augmentation{Scale: 0.5}, 25, 15, 0, 0},
+		{Augmentation{Scale: 0.5}, 75, 45, 100, 60},
+		{Augmentation{Scale: 0.5, FlipHorizontal: true}, 75, 15, 0, 0},
+		// content of 200 x 120 cropped to its centre
+		{Augmentation{Scale: 2}, 0, 0, 25, 15},
+	} {
+		x, y := tc.aug.InvertPoint(tc.x, tc.y, size)
+		if math.Abs(x-tc.wantX) > 1e-9 || math.Abs(y-tc.wantY) > 1e-9 {
+			t.Errorf("%+v: InvertPoint(%v, %v) = %v, %v, want %v, %v", tc.aug, tc.x, tc.y, x, y, tc.wantX, tc.wantY)
+		}
+	}
+}
+
+func TestAugmentationInterpolation(t *testing.T) {
+	// a one pixel checker averages to grey unless sampled nearest
+	img := checkerImage(64, 64, 1)
+	aug := Augmentation{Scale: 0.5}
+
+	level := func(interp Interpolation) uint32 {
+		r, _, _, _ := aug.Apply(img, interp).At(32, 32).RGBA()
+		return r >> 8
+	}
+	if v := level(Nearest); v != 30 && v != 220 {
+		t.Errorf("nearest pass gives %d, want a checker level", v)
+	}
+	if v := level(Area); v < 115 || v > 135 {
+		t.Errorf("area pass gives %d, want grey", v)
+	}
+}
@@ -0,0 +1,270 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Enhancer corrects an image before it is preprocessed, e.g. to even out
+// lighting differences between stores
+type Enhancer interface {
+	Enhance(img image.Image) image.Image
+}
+
+// Chain applies enhancers in order
+type Chain []Enhancer
+
+func (c Chain) Enhance(img image.Image) image.Image {
+	for _, e := range c {
+		img = e.Enhance(img)
+	}
+	return img
+}
+
+// EnhanceConfig selects the enhancement steps of one camera. Steps run in
+// the order white balance, gamma, equalization, CLAHE.
+type EnhanceConfig struct {
+	WhiteBalance bool    `json:"white_balance"`
+	Gamma        float64 `json:"gamma"` // 0 or 1 disables, > 1 brightens
+	Equalize     bool    `json:"equalize"`
+	CLAHE        *CLAHE  `json:"clahe"`
+}
+
+// Chain builds the enhancers of the config, nil when nothing is enabled
+func (c EnhanceConfig) Chain() Chain {
+	var chain Chain
+	if c.WhiteBalance {
+		chain = append(chain, GrayWorld{})
+	}
+	if c.Gamma > 0 && c.Gamma != 1 {
+		chain = append(chain, Gamma{Gamma: c.Gamma})
+	}
+	if c.Equalize {
+		chain = append(chain, HistogramEqualization{})
+	}
+	if c.CLAHE != nil {
+		chain = append(chain, *c.CLAHE)
+	}
+	return chain
+}
+
+// GrayWorld white balance scales the channels so their means are equal,
+// removing colour casts of warm or cold lighting
+type GrayWorld struct{}
+
+func (GrayWorld) Enhance(img image.Image) image.Image {
+	dst := toRGBA(img)
+
+	var sum [3]float64
+	for i := 0; i < len(dst.Pix); i += 4 {
+		sum[0] += float64(dst.Pix[i])
+		sum[1] += float64(dst.Pix[i+1])
+		sum[2] += float64(dst.Pix[i+2])
+	}
+	gray := (sum[0] + sum[1] + sum[2]) / 3
+
+	var lut [3][256]uint8
+	for c := 0; c < 3; c++ {
+		gain := 1.0
+		if sum[c] > 0 {
+			gain = gray / sum[c]
+		}
+		for v := range lut[c] {
+			lut[c][v] = clampUint8(float64(v) * gain)
+		}
+	}
+
+	for i := 0; i < len(dst.Pix); i += 4 {
+		dst.Pix[i] = lut[0][dst.Pix[i]]
+		dst.Pix[i+1] = lut[1][dst.Pix[i+1]]
+		dst.Pix[i+2] = lut[2][dst.Pix[i+2]]
+	}
+	return dst
+}
+
+// Gamma correction, out = in^(1/Gamma) on [0, 1]: values above 1 brighten
+// dim scenes, values below 1 darken
+type Gamma struct {
+	Gamma float64
+}
+
+func (g Gamma) Enhance(img image.Image) image.Image {
+	dst := toRGBA(img)
+	if g.Gamma <= 0 || g.Gamma == 1 {
+		return dst
+	}
+
+	var lut [256]uint8
+	for v := range lut {
+		lut[v] = clampUint8(255 * math.Pow(float64(v)/255, 1/g.Gamma))
+	}
+	for i := 0; i < len(dst.Pix); i += 4 {
+		dst.Pix[i] = lut[dst.Pix[i]]
+		dst.Pix[i+1] = lut[dst.Pix[i+1]]
+		dst.Pix[i+2] = lut[dst.Pix[i+2]]
+	}
+	return dst
+}
+
+// HistogramEqualization spreads the luminance histogram over the full range,
+// colours are kept
+type HistogramEqualization struct{}
+
+func (HistogramEqualization) Enhance(img image.Image) image.Image {
+	dst := toRGBA(img)
+	b := dst.Bounds()
+
+	var hist [256]float64
+	eachLuma(dst, func(_, _ int, y uint8) uint8 {
+		hist[y]++
+		return y
+	})
+	lut := equalizeLUT(hist, float64(b.Dx()*b.Dy()))
+
+	eachLuma(dst, func(_, _ int, y uint8) uint8 {
+		return lut[y]
+	})
+	return dst
+}
+
+// CLAHE (contrast limited adaptive histogram equalization) equalizes the
+// luminance per tile with a clipped histogram and blends neighbouring tiles,
+// brightening dark shelves without blowing out lit areas
+type CLAHE struct {
+	ClipLimit float64 `json:"clip_limit"` // relative to a flat histogram, e.g. 2
+	TilesX    int     `json:"tiles_x"`
+	TilesY    int     `json:"tiles_y"`
+}
+
+var DefaultCLAHE = CLAHE{ClipLimit: 2, TilesX: 8, TilesY: 8}
+
+func (c CLAHE) Enhance(img image.Image) image.Image {
+	dst := toRGBA(img)
+	b := dst.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w == 0 || h == 0 {
+		return dst
+	}
+	tileW, tilesX := tileGrid(w, c.TilesX)
+	tileH, tilesY := tileGrid(h, c.TilesY)
+
+	// histogram per tile
+	hists := make([][256]float64, tilesX*tilesY)
+	eachLuma(dst, func(x, y int, l uint8) uint8 {
+		hists[(y/tileH)*tilesX+x/tileW][l]++
+		return l
+	})
+
+	// clipped mapping per tile
+	luts := make([][256]uint8, len(hists))
+	for i, hist := range hists {
+		var area float64
+		for _, n := range hist {
+			area += n
+		}
+		if c.ClipLimit > 0 {
+			clipHistogram(&hist, math.Max(1, c.ClipLimit*area/256))
+		}
+		luts[i] = equalizeLUT(hist, area)
+	}
+
+	// bilinear blend between the four nearest tile centres
+	eachLuma(dst, func(x, y int, l uint8) uint8 {
+		tx, ax := tileCoord(x, tileW, tilesX)
+		ty, ay := tileCoord(y, tileH, tilesY)
+		tx1, ty1 := min(tx+1, tilesX-1), min(ty+1, tilesY-1)
+
+		top := (1-ax)*float64(luts[ty*tilesX+tx][l]) + ax*float64(luts[ty*tilesX+tx1][l])
+		bottom := (1-ax)*float64(luts[ty1*tilesX+tx][l]) + ax*float64(luts[ty1*tilesX+tx1][l])
+		return clampUint8((1-ay)*top + ay*bottom)
+	})
+	return dst
+}
+
+// tileGrid splits size pixels into at most tiles tiles, returning the tile
+// size and the number of tiles that actually cover pixels
+func tileGrid(size, tiles int) (int, int) {
+	if tiles < 1 {
+		tiles = 1
+	}
+	tileSize := (size + min(tiles, size) - 1) / min(tiles, size)
+	return tileSize, (size + tileSize - 1) / tileSize
+}
+
+// tileCoord returns the tile left of (or above) the pixel centre and the
+// blend weight towards the next tile
+func tileCoord(p, tileSize, tiles int) (int, float64) {
+	f := (float64(p)+0.5)/float64(tileSize) - 0.5
+	if f <= 0 {
+		return 0, 0
+	}
+	t := int(f)
+	if t >= tiles-1 {
+		return tiles - 1, 0
+	}
+	return t, f - float64(t)
+}
+
+// clipHistogram cuts bins above limit and spreads the excess evenly
+func clipHistogram(hist *[256]float64, limit float64) {
+	var excess float64
+	for i, n := range hist {
+		if n > limit {
+			excess += n - limit
+			hist[i] = limit
+		}
+	}
+	for i := range hist {
+		hist[i] += excess / 256
+	}
+}
+
+// equalizeLUT maps luminance through the normalized cumulative histogram
+func equalizeLUT(hist [256]float64, total float64) [256]uint8 {
+	var lut [256]uint8
+	if total == 0 {
+		for i := range lut {
+			lut[i] = uint8(i)
+		}
+		return lut
+	}
+	var cdf float64
+	for i, n := range hist {
+		cdf += n
+		lut[i] = clampUint8(255 * cdf / total)
+	}
+	return lut
+}
+
+// eachLuma calls fn with the luminance of every pixel and replaces it with
+// the returned value, keeping the chroma
+func eachLuma(img *image.RGBA, fn func(x, y int, l uint8) uint8) {
+	b := img.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			i := img.PixOffset(b.Min.X+x, b.Min.Y+y)
+			l, cb, cr := color.RGBToYCbCr(img.Pix[i], img.Pix[i+1], img.Pix[i+2])
+			if nl := fn(x, y, l); nl != l {
+				img.Pix[i], img.Pix[i+1], img.Pix[i+2] = color.YCbCrToRGB(nl, cb, cr)
+			}
+		}
+	}
+}
+
+// toRGBA returns an opaque RGBA copy of the image starting at 0, 0
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
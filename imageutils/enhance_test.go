@@ -0,0 +1,181 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// meanRGB returns the per channel mean of an RGBA image
+func meanRGB(img *image.RGBA) [3]float64 {
+	var sum [3]float64
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			sum[c] += float64(img.Pix[i+c])
+		}
+	}
+	n := float64(len(img.Pix) / 4)
+	return [3]float64{sum[0] / n, sum[1] / n, sum[2] / n}
+}
+
+func lumaStdDev(img image.Image) float64 {
+	lum := luminance(img)
+	var sum, sumSq float64
+	for _, v := range lum {
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(lum))
+	mean := sum / n
+	return math.Sqrt(sumSq/n - mean*mean)
+}
+
+// warmImage is the smooth pattern under a strong orange cast
+func warmImage(w, h int) *image.RGBA {
+	img := smoothImage(w, h)
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i+1] = uint8(float64(img.Pix[i+1]) * 0.8)
+		img.Pix[i+2] = uint8(float64(img.Pix[i+2]) * 0.5)
+	}
+	return img
+}
+
+// dimImage compresses the smooth pattern into the dark range [0, 64]
+func dimImage(w, h int) *image.RGBA {
+	img := smoothImage(w, h)
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			img.Pix[i+c] /= 4
+		}
+	}
+	return img
+}
+
+func TestGrayWorldRemovesCast(t *testing.T) {
+	out := GrayWorld{}.Enhance(warmImage(64, 48)).(*image.RGBA)
+	mean := meanRGB(out)
+	gray := (mean[0] + mean[1] + mean[2]) / 3
+	for c, m := range mean {
+		if math.Abs(m-gray) > 3 {
+			t.Errorf("channel %d mean %.1f, want about %.1f", c, m, gray)
+		}
+	}
+}
+
+func TestGamma(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 64, G: 128, B: 255, A: 255})
+
+	out := Gamma{Gamma: 2}.Enhance(img).(*image.RGBA).RGBAAt(0, 0)
+	want := color.RGBA{R: 128, G: 181, B: 255, A: 255}
+	if out != want {
+		t.Errorf("got %v, want %v", out, want)
+	}
+
+	if out := (Gamma{Gamma: 1}).Enhance(img).(*image.RGBA).RGBAAt(0, 0); out != img.RGBAAt(0, 0) {
+		t.Errorf("gamma 1 changed pixel to %v", out)
+	}
+}
+
+func TestEqualizationStretchesDimImage(t *testing.T) {
+	img := dimImage(96, 64)
+	before := lumaStdDev(img)
+
+	// minimum contrast gain, the clip limit deliberately holds CLAHE back
+	for _, tc := range []struct {
+		name string
+		e    Enhancer
+		gain float64
+	}{
+		{"equalize", HistogramEqualization{}, 2},
+		{"clahe", DefaultCLAHE, 1.1},
+		{"clahe-unclipped", CLAHE{TilesX: 4, TilesY: 4}, 2},
+	} {
+		name := tc.name
+		out := tc.e.Enhance(img)
+		if out.Bounds() != img.Bounds() {
+			t.Fatalf("%s: bounds %v, want %v", name, out.Bounds(), img.Bounds())
+		}
+		if after := lumaStdDev(out); after < tc.gain*before {
+			t.Errorf("%s: contrast %.1f -> %.1f, expected a clear increase", name, before, after)
+		}
+	}
+}
+
+func TestCLAHEFlatImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 100, 100, 100, 255
+	}
+
+	// the clip limit keeps a flat area from being pushed to white
+	out := CLAHE{ClipLimit: 2, TilesX: 3, TilesY: 3}.Enhance(img).(*image.RGBA)
+	first := out.RGBAAt(0, 0)
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			if c := out.RGBAAt(x, y); c != first {
+				t.Fatalf("pixel %d,%d is %v, expected a flat result %v", x, y, c, first)
+			}
+		}
+	}
+	if first.R > 200 {
+		t.Errorf("flat image mapped to %v", first)
+	}
+}
+
+func TestCLAHEUnevenTiles(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 100, 100, 100, 255
+	}
+
+	// 4 tiles of 3 pixels leave the last tile without pixels, which must not
+	// pull the right and bottom edges towards black
+	out := CLAHE{ClipLimit: 2, TilesX: 4, TilesY: 4}.Enhance(img).(*image.RGBA)
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			if c := out.RGBAAt(x, y); c.R < 100 {
+				t.Errorf("pixel %d,%d darkened to %v", x, y, c)
+			}
+		}
+	}
+}
+
+func TestTileGrid(t *testing.T) {
+	tests := []struct {
+		size, tiles         int
+		wantSize, wantTiles int
+	}{
+		{640, 8, 80, 8},
+		{9, 4, 3, 3},
+		{5, 4, 2, 3},
+		{3, 8, 1, 3},
+		{10, 0, 10, 1},
+		{1, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		size, tiles := tileGrid(tt.size, tt.tiles)
+		if size != tt.wantSize || tiles != tt.wantTiles {
+			t.Errorf("tileGrid(%d, %d) = %d, %d, want %d, %d", tt.size, tt.tiles, size, tiles, tt.wantSize, tt.wantTiles)
+		}
+	}
+}
+
+func TestEnhanceConfigChain(t *testing.T) {
+	if chain := (EnhanceConfig{Gamma: 1}).Chain(); chain != nil {
+		t.Errorf("expected empty chain, got %v", chain)
+	}
+
+	clahe := DefaultCLAHE
+	cfg := EnhanceConfig{WhiteBalance: true, Gamma: 1.5, Equalize: true, CLAHE: &clahe}
+	chain := cfg.Chain()
+	if len(chain) != 4 {
+		t.Fatalf("expected 4 steps, got %d", len(chain))
+	}
+
+	sub := warmImage(80, 60).SubImage(image.Rect(10, 10, 70, 50))
+	if out := chain.Enhance(sub); out.Bounds() != image.Rect(0, 0, 60, 40) {
+		t.Errorf("unexpected bounds %v", out.Bounds())
+	}
+}
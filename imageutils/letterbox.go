@@ -1,9 +1,10 @@
 package imageutils
 
 import (
+	"fmt"
 	"image"
+	"image/color"
 	"image/draw"
-	"math"
 )
 
 
@@ -14,29 +15,8 @@ func normalizeColor(color uint32) float32 {
     return normalized
 }
 
-func PreprocessImage(img image.Image, targetSize ImageSize) ([]float32, LetterboxParams) {
-	// letterbox
-	letterboxed, params := Letterbox(img, targetSize)
-
-	// create tensor N C H W
-	tensorData := make([]float32, 3*targetSize.Height*targetSize.Width)
-
-	// Convert to float 32
-	bounds := letterboxed.Bounds()
-	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
-		for x:= bounds.Min.X; x < bounds.Max.X; x++ {
-			r, g, b, _ := letterboxed.At(x, y).RGBA()
-
-			// in: uint32 [0, 65535]
-			// to: float32 [0, 1]
-			idx := y*targetSize.Width + x
-			tensorData[idx] = normalizeColor(r)
-			tensorData[idx + targetSize.Height*targetSize.Width] = normalizeColor(g)
-			tensorData[idx + 2*targetSize.Height*targetSize.Width] = normalizeColor(b)
-		}
-	}
-
-	return tensorData, params
+func PreprocessImage(img image.Image, targetSize ImageSize) ([]float32, LetterboxParams, error) {
+	return PreprocessImageSpec(img, targetSize, DefaultPreprocessSpec)
 }
 
 
@@ -49,89 +29,125 @@ func VerifyTensorData(data []float32) bool {
 	return true
 }
 
-func PreprocessBatch(imgs []image.Image, targetSize ImageSize) ([]float32, map[int]LetterboxParams){
-	batchSize := len(imgs)
-	params := make(map[int]LetterboxParams)
+func PreprocessBatch(imgs []image.Image, targetSize ImageSize) ([]float32, map[int]LetterboxParams, error){
+	return PreprocessBatchSpec(imgs, targetSize, DefaultPreprocessSpec)
+}
 
-	// init tensor batch
-	tensorData := make([]float32, batchSize*3*targetSize.Height*targetSize.Width)
+// ultralytics-compatible letterbox options
+type LetterboxOptions struct {
+	PadColor      color.RGBA
+	NoScaleUp     bool // only shrink, never enlarge small images (ultralytics scaleup=False)
+	Auto          bool // pad only up to a multiple of Stride (minimum rectangle)
+	Stride        int
+	Interpolation Interpolation
+}
 
-	// process images
-	for i, img := range imgs {
-		// single img
-		imgData, imgParams := PreprocessImage(img, targetSize)
-		params[i] = imgParams
+var DefaultLetterboxOptions = LetterboxOptions{
+	PadColor:      color.RGBA{A: 255},
+	Stride:        32,
+	Interpolation: Bilinear,
+}
 
-		// 
-		offset := i * 3 * targetSize.Height * targetSize.Width
-		copy(tensorData[offset:], imgData)
-	}
+// UltralyticsLetterboxOptions pads with gray 114 like ultralytics training
+var UltralyticsLetterboxOptions = LetterboxOptions{
+	PadColor:      color.RGBA{R: 114, G: 114, B: 114, A: 255},
+	Stride:        32,
+	Interpolation: Bilinear,
+}
 
-	return tensorData, params
+func Letterbox(img image.Image, targetSize ImageSize) (image.Image, LetterboxParams, error) {
+	return LetterboxWith(img, targetSize, DefaultLetterboxOptions)
 }
 
+// LetterboxWith letterboxes the image with the given options. With Auto the
+// result is smaller than targetSize: only the padding needed to reach a
+// multiple of Stride is added.
+func LetterboxWith(img image.Image, targetSize ImageSize, opts LetterboxOptions) (image.Image, LetterboxParams, error) {
+	bounds := img.Bounds()
+	srcSize := ImageSize{Width: bounds.Dx(), Height: bounds.Dy()}
+	spec := PreprocessSpec{Resize: ResizeLetterbox, NoScaleUp: opts.NoScaleUp}
+
+	params, err := FitParams(srcSize, targetSize, spec)
+	if err != nil {
+		return nil, LetterboxParams{}, err
+	}
+
+	canvas := targetSize
+	if opts.Auto {
+		if opts.Stride <= 0 {
+			return nil, LetterboxParams{}, fmt.Errorf("auto letterbox needs a positive stride, got %d", opts.Stride)
+		}
+		canvas = ImageSize{
+			Width:  params.Width + (targetSize.Width-params.Width)%opts.Stride,
+			Height: params.Height + (targetSize.Height-params.Height)%opts.Stride,
+		}
+		params = centerParams(params, canvas)
+	}
+
+	return place(img, canvas, params, opts.PadColor, opts.Interpolation), params, nil
+}
 
-func Letterbox(img image.Image, targetSize ImageSize) (image.Image, LetterboxParams) {
-	// original
+// place draws img scaled by params onto a canvas of targetSize filled with pad
+func place(img image.Image, targetSize ImageSize, params LetterboxParams, pad color.Color, interp Interpolation) *image.RGBA {
 	bounds := img.Bounds()
-	originalHeight, originalWidth := bounds.Dy(), bounds.Dx()
-
-	// scale to fit within
-	scaleWidth := float64(targetSize.Width) / float64(originalWidth)
-	scaleHeight := float64(targetSize.Height) / float64(originalHeight)
-	scale := math.Min(scaleWidth, scaleHeight)
-	
-	// new dims
-	newWidth := int(float64(originalWidth) * scale)
-	newHeight := int(float64(originalHeight) * scale)
-
-	// padding
-	top := (targetSize.Height - newHeight) / 2
-	left := (targetSize.Width - newWidth) / 2
-
-	// black background
+
+	// background
 	dst := image.NewRGBA(image.Rect(0, 0, targetSize.Width, targetSize.Height))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(pad), image.Point{}, draw.Src)
+
+	// area covered by the resized image
+	area := image.Rect(params.Left, params.Top, params.Left+params.Width, params.Top+params.Height)
 
-	// temp image for resized content
-	resized := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	if interp != Nearest {
+		resized := ResizeWith(img, params.Width, params.Height, interp)
+		draw.Draw(dst, area, resized, image.Point{}, draw.Src)
+		return dst
+	}
+
+	content := area.Intersect(dst.Bounds())
+	for y := content.Min.Y; y < content.Max.Y; y++ {
+		for x := content.Min.X; x < content.Max.X; x++ {
 
-	// simple resizing using nearest neighbot (TODO: improve later)
-	for y := 0; y < newHeight; y++ {
-		for x := 0; x < newWidth; x++ {
-			
 			// map new coordinates to original image
-			origX := int(float64(x) / scale)
-			origY := int(float64(y) / scale)
+			origX := int(float64(x-params.Left) / params.ScaleX)
+			origY := int(float64(y-params.Top) / params.ScaleY)
 
-			// get color
-			c := img.At(origX, origY)
-			resized.Set(x, y, c)
+			// get color (sub-images do not start at 0, 0)
+			dst.Set(x, y, img.At(bounds.Min.X+origX, bounds.Min.Y+origY))
 		}
 	}
 
-	// draw resized on padded background
-	draw.Draw(dst, image.Rect(left, top, left+newWidth, top+newHeight),
-		resized, image.Point{}, draw.Src)
-
-	params := LetterboxParams{
-        Scale: scale,
-        Left:  left,
-        Top:   top,
-    }
-
-	return dst, params
+	return dst
 }
 
+// UnLetterbox maps a point of the model input back onto the original image.
+// It handles letterbox, stretch and center crop parameters; parameters
+// failing Validate leave the point unscaled.
 func UnLetterbox(x, y float64, params LetterboxParams) (float64, float64){
 	// remove padding
 	unpadX := x - float64(params.Left)
 	unpadY := y - float64(params.Top)
 
-	// undo scale
-	if params.Scale > 0 {
+	// undo scale (stretching scales the axes differently)
+	if params.ScaleX > 0 && params.ScaleY > 0 {
+		unpadX /= params.ScaleX
+		unpadY /= params.ScaleY
+	} else if params.Scale > 0 {
 		unpadX /= params.Scale
 		unpadY /= params.Scale
 	}
 
 	return unpadX, unpadY
-}
\ No newline at end of file
+}
+
+// UnLetterboxSize converts a width and height from model input back to
+// original image pixels
+func UnLetterboxSize(w, h float64, params LetterboxParams) (float64, float64) {
+	if params.ScaleX > 0 && params.ScaleY > 0 {
+		return w / params.ScaleX, h / params.ScaleY
+	}
+	if params.Scale > 0 {
+		return w / params.Scale, h / params.Scale
+	}
+	return w, h
+}
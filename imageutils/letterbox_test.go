@@ -0,0 +1,85 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestLetterboxRejectsInvalidInput(t *testing.T) {
+	if _, _, err := Letterbox(image.NewRGBA(image.Rect(0, 0, 0, 10)), ImageSize{Width: 32, Height: 32}); err == nil {
+		t.Error("expected error for empty image")
+	}
+	if _, _, err := Letterbox(smoothImage(10, 10), ImageSize{Width: 0, Height: 32}); err == nil {
+		t.Error("expected error for empty target")
+	}
+	opts := DefaultLetterboxOptions
+	opts.Auto = true
+	opts.Stride = 0
+	if _, _, err := LetterboxWith(smoothImage(10, 10), ImageSize{Width: 32, Height: 32}, opts); err == nil {
+		t.Error("expected error for auto letterbox without stride")
+	}
+	if err := (LetterboxParams{}).Validate(); err == nil {
+		t.Error("expected zero params to be invalid")
+	}
+}
+
+func TestLetterboxOptions(t *testing.T) {
+	target := ImageSize{Width: 640, Height: 640}
+
+	// small images are not enlarged with NoScaleUp
+	opts := UltralyticsLetterboxOptions
+	opts.NoScaleUp = true
+	out, params, err := LetterboxWith(smoothImage(100, 50), target, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Scale != 1 || params.Width != 100 || params.Left != 270 || params.Top != 295 {
+		t.Errorf("unexpected params %+v", params)
+	}
+	if c := out.At(0, 0); c != (color.RGBA{R: 114, G: 114, B: 114, A: 255}) {
+		t.Errorf("expected gray padding, got %v", c)
+	}
+
+	// auto pads only to the next multiple of the stride
+	opts = UltralyticsLetterboxOptions
+	opts.Auto = true
+	out, params, err = LetterboxWith(smoothImage(1280, 720), target, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := out.Bounds(); b.Dx() != 640 || b.Dy() != 384 {
+		t.Errorf("auto letterbox size %v, want 640x384", b)
+	}
+	if params.Height != 360 || params.Top != 12 || params.Bottom != 12 {
+		t.Errorf("unexpected params %+v", params)
+	}
+}
+
+func TestUnLetterboxRoundTrip(t *testing.T) {
+	src := ImageSize{Width: 1280, Height: 720}
+	target := ImageSize{Width: 416, Height: 416}
+
+	for _, mode := range []ResizeMode{ResizeLetterbox, ResizeStretch, ResizeCenterCrop} {
+		for _, noScaleUp := range []bool{false, true} {
+			spec := PreprocessSpec{Resize: mode, NoScaleUp: noScaleUp}
+			params, err := FitParams(src, target, spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := params.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			// forward transform of an image point, then back
+			x, y := 1000.0, 100.0
+			fx := x*params.ScaleX + float64(params.Left)
+			fy := y*params.ScaleY + float64(params.Top)
+			bx, by := UnLetterbox(fx, fy, params)
+			if math.Abs(bx-x) > 1e-6 || math.Abs(by-y) > 1e-6 {
+				t.Errorf("mode %d: round trip (%v, %v) -> (%v, %v)", mode, x, y, bx, by)
+			}
+		}
+	}
+}
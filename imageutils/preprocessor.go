@@ -0,0 +1,327 @@
+package imageutils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sync"
+)
+
+// Preprocessor writes images straight into a caller-supplied tensor buffer,
+// e.g. the input tensor of an ONNX session. It resizes, pads, normalizes and
+// lays out the pixels in one pass over the source, reading *image.YCbCr,
+// *image.RGBA and *image.NRGBA without going through image.Image.At.
+// Sampling tables are cached for the last source size, so steady-state
+// processing of same-sized frames does not allocate.
+//
+// The output matches PreprocessImageSpec up to rounding. A Preprocessor is
+// not safe for concurrent use.
+type Preprocessor struct {
+	spec       PreprocessSpec
+	targetSize ImageSize
+	workers    int
+
+	// cached for srcSize
+	srcSize ImageSize
+	params  LetterboxParams
+	visible image.Rectangle // part of the target covered by the image
+	cols    []taps          // per visible column
+	rows    []taps          // per visible row
+	rowUsed []bool          // source rows referenced by rows
+	tmp     []float32       // horizontally filtered source rows, RGB
+	rowBufs [][]float32     // decoded source row per worker, RGB
+
+	// per channel: value = pixel*scale + offset, tensor channel index
+	scale   [3]float32
+	offset  [3]float32
+	channel [3]int
+	pad     [3]float32
+}
+
+// source pixels and weights contributing to one output pixel
+type taps struct {
+	start   int
+	weights []float32
+}
+
+// NewPreprocessor creates a preprocessor for the given target size. With
+// workers > 1 rows are processed by that many goroutines.
+func NewPreprocessor(targetSize ImageSize, spec PreprocessSpec, workers int) *Preprocessor {
+	p := &Preprocessor{
+		spec:       spec,
+		targetSize: targetSize,
+		workers:    workers,
+		channel:    [3]int{0, 1, 2},
+	}
+	if p.workers < 1 {
+		p.workers = 1
+	}
+	if spec.Order == BGR {
+		p.channel = [3]int{2, 1, 0}
+	}
+
+	pad := [3]uint8{spec.PadColor.R, spec.PadColor.G, spec.PadColor.B}
+	for c := 0; c < 3; c++ {
+		p.scale[c] = 1 / (255 * spec.std(c))
+		p.offset[c] = -spec.Mean[c] / spec.std(c)
+		p.pad[c] = float32(pad[c])*p.scale[c] + p.offset[c]
+	}
+	return p
+}
+
+// TensorLen returns the number of values Run writes for one image
+func (p *Preprocessor) TensorLen() int {
+	return 3 * p.targetSize.Width * p.targetSize.Height
+}
+
+// Run preprocesses img into dst, which must hold at least TensorLen values.
+// Every written value lies within Spec.ValueRange.
+func (p *Preprocessor) Run(dst []float32, img image.Image) (LetterboxParams, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return LetterboxParams{}, fmt.Errorf("cannot preprocess empty image")
+	}
+	if len(dst) < p.TensorLen() {
+		return LetterboxParams{}, fmt.Errorf("tensor buffer too small: %d < %d", len(dst), p.TensorLen())
+	}
+
+	if err := p.prepare(ImageSize{Width: bounds.Dx(), Height: bounds.Dy()}); err != nil {
+		return LetterboxParams{}, err
+	}
+
+	height := bounds.Dy()
+	if p.workers <= 1 {
+		p.horizontal(img, 0, 0, height)
+		p.vertical(dst, 0, p.targetSize.Height)
+	} else {
+		parallel(height, p.workers, func(worker, start, end int) {
+			p.horizontal(img, worker, start, end)
+		})
+		parallel(p.targetSize.Height, p.workers, func(_, start, end int) {
+			p.vertical(dst, start, end)
+		})
+	}
+
+	return p.params, nil
+}
+
+// prepare computes the sampling tables for a new source size
+func (p *Preprocessor) prepare(srcSize ImageSize) error {
+	if srcSize == p.srcSize && p.rowBufs != nil {
+		return nil
+	}
+	params, err := FitParams(srcSize, p.targetSize, p.spec)
+	if err != nil {
+		return err
+	}
+	p.srcSize = srcSize
+	p.params = params
+
+	newWidth, newHeight := params.Width, params.Height
+	content := image.Rect(p.params.Left, p.params.Top, p.params.Left+newWidth, p.params.Top+newHeight)
+	p.visible = content.Intersect(image.Rect(0, 0, p.targetSize.Width, p.targetSize.Height))
+
+	p.cols = p.taps(srcSize.Width, newWidth, p.params.ScaleX, p.visible.Min.X-p.params.Left, p.visible.Dx())
+	p.rows = p.taps(srcSize.Height, newHeight, p.params.ScaleY, p.visible.Min.Y-p.params.Top, p.visible.Dy())
+
+	p.rowUsed = make([]bool, srcSize.Height)
+	for _, r := range p.rows {
+		for k := range r.weights {
+			p.rowUsed[r.start+k] = true
+		}
+	}
+
+	p.tmp = make([]float32, srcSize.Height*p.visible.Dx()*3)
+	p.rowBufs = make([][]float32, p.workers)
+	for i := range p.rowBufs {
+		p.rowBufs[i] = make([]float32, srcSize.Width*3)
+	}
+	return nil
+}
+
+// taps returns the contributions of count resized pixels starting at first
+func (p *Preprocessor) taps(srcLen, newLen int, scale float64, first, count int) []taps {
+	result := make([]taps, count)
+	if count <= 0 {
+		return result
+	}
+
+	if p.spec.Interpolation == Nearest {
+		for i := range result {
+			src := int(float64(first+i) / scale)
+			if src >= srcLen {
+				src = srcLen - 1
+			}
+			result[i] = taps{start: src, weights: []float32{1}}
+		}
+		return result
+	}
+
+	all := contributions(srcLen, newLen, p.spec.Interpolation)
+	for i := range result {
+		c := all[first+i]
+		weights := make([]float32, len(c.weights))
+		for k, w := range c.weights {
+			weights[k] = float32(w)
+		}
+		result[i] = taps{start: c.start, weights: weights}
+	}
+	return result
+}
+
+// horizontal filters the used source rows in [start, end)
+func (p *Preprocessor) horizontal(img image.Image, worker, start, end int) {
+	buf := p.rowBufs[worker]
+	for y := start; y < end; y++ {
+		if p.rowUsed[y] {
+			decodeRow(buf, img, y)
+			p.filterRow(y, buf)
+		}
+	}
+}
+
+// vertical writes the target rows in [start, end) into the tensor
+func (p *Preprocessor) vertical(dst []float32, start, end int) {
+	for y := start; y < end; y++ {
+		p.writeRow(dst, y)
+	}
+}
+
+// filterRow applies the column taps to one decoded source row
+func (p *Preprocessor) filterRow(y int, buf []float32) {
+	out := p.tmp[y*len(p.cols)*3:]
+	for x, t := range p.cols {
+		var r, g, b float32
+		src := buf[t.start*3:]
+		for k, w := range t.weights {
+			r += src[k*3] * w
+			g += src[k*3+1] * w
+			b += src[k*3+2] * w
+		}
+		out[x*3] = r
+		out[x*3+1] = g
+		out[x*3+2] = b
+	}
+}
+
+// writeRow applies the row taps and writes target row y into the tensor
+func (p *Preprocessor) writeRow(dst []float32, y int) {
+	width := p.targetSize.Width
+	plane := width * p.targetSize.Height
+	nhwc := p.spec.Layout == NHWC
+
+	set := func(x int, rgb [3]float32) {
+		idx := y*width + x
+		for c := 0; c < 3; c++ {
+			if nhwc {
+				dst[idx*3+p.channel[c]] = rgb[c]
+			} else {
+				dst[p.channel[c]*plane+idx] = rgb[c]
+			}
+		}
+	}
+
+	if y < p.visible.Min.Y || y >= p.visible.Max.Y {
+		for x := 0; x < width; x++ {
+			set(x, p.pad)
+		}
+		return
+	}
+
+	t := p.rows[y-p.visible.Min.Y]
+	stride := len(p.cols) * 3
+	for x := 0; x < width; x++ {
+		if x < p.visible.Min.X || x >= p.visible.Max.X {
+			set(x, p.pad)
+			continue
+		}
+
+		col := (x - p.visible.Min.X) * 3
+		var px [3]float32
+		for k, w := range t.weights {
+			src := p.tmp[(t.start+k)*stride+col:]
+			px[0] += src[0] * w
+			px[1] += src[1] * w
+			px[2] += src[2] * w
+		}
+		for c := 0; c < 3; c++ {
+			v := px[c]
+			if v < 0 {
+				v = 0
+			} else if v > 255 {
+				v = 255
+			}
+			px[c] = v*p.scale[c] + p.offset[c]
+		}
+		set(x, px)
+	}
+}
+
+// decodeRow reads source row y (relative to the bounds) as RGB values in
+// [0, 255], premultiplied by alpha like image.Image.At
+func decodeRow(buf []float32, img image.Image, y int) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	sy := bounds.Min.Y + y
+
+	switch src := img.(type) {
+	case *image.YCbCr:
+		yi := src.YOffset(bounds.Min.X, sy)
+		for x := 0; x < width; x++ {
+			ci := src.COffset(bounds.Min.X+x, sy)
+			r, g, b := color.YCbCrToRGB(src.Y[yi+x], src.Cb[ci], src.Cr[ci])
+			buf[x*3] = float32(r)
+			buf[x*3+1] = float32(g)
+			buf[x*3+2] = float32(b)
+		}
+	case *image.RGBA:
+		pix := src.Pix[src.PixOffset(bounds.Min.X, sy):]
+		for x := 0; x < width; x++ {
+			buf[x*3] = float32(pix[x*4])
+			buf[x*3+1] = float32(pix[x*4+1])
+			buf[x*3+2] = float32(pix[x*4+2])
+		}
+	case *image.NRGBA:
+		pix := src.Pix[src.PixOffset(bounds.Min.X, sy):]
+		for x := 0; x < width; x++ {
+			a := float32(pix[x*4+3]) / 255
+			buf[x*3] = float32(pix[x*4]) * a
+			buf[x*3+1] = float32(pix[x*4+1]) * a
+			buf[x*3+2] = float32(pix[x*4+2]) * a
+		}
+	default:
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, sy).RGBA()
+			buf[x*3] = float32(r) / 257
+			buf[x*3+1] = float32(g) / 257
+			buf[x*3+2] = float32(b) / 257
+		}
+	}
+}
+
+// parallel splits [0, n) into contiguous chunks processed by up to workers
+// goroutines
+func parallel(n, workers int, fn func(worker, start, end int)) {
+	if workers <= 1 || n < workers {
+		fn(0, 0, n)
+		return
+	}
+
+	var wg sync.WaitGroup
+	chunk := (n + workers - 1) / workers
+	for w := 0; w < workers; w++ {
+		start, end := w*chunk, (w+1)*chunk
+		if end > n {
+			end = n
+		}
+		if start >= end {
+			break
+		}
+		wg.Add(1)
+		go func(w, start, end int) {
+			defer wg.Done()
+			fn(w, start, end)
+		}(w, start, end)
+	}
+	wg.Wait()
+}
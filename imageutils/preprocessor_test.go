@@ -0,0 +1,158 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// ycbcrImage converts the smooth test pattern into a 4:2:0 JPEG-style image
+func ycbcrImage(w, h int) *image.YCbCr {
+	src := smoothImage(w, h)
+	img := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio420)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := src.RGBAAt(x, y)
+			yy, cb, cr := color.RGBToYCbCr(c.R, c.G, c.B)
+			img.Y[img.YOffset(x, y)] = yy
+			ci := img.COffset(x, y)
+			img.Cb[ci] = cb
+			img.Cr[ci] = cr
+		}
+	}
+	return img
+}
+
+func nrgbaImage(w, h int) *image.NRGBA {
+	src := smoothImage(w, h)
+	img := image.NewNRGBA(src.Bounds())
+	copy(img.Pix, src.Pix)
+	return img
+}
+
+func TestPreprocessorMatchesGenericPath(t *testing.T) {
+	target := ImageSize{Width: 96, Height: 64}
+
+	images := map[string]image.Image{
+		"rgba":     smoothImage(320, 180),
+		"nrgba":    nrgbaImage(200, 300),
+		"ycbcr":    ycbcrImage(257, 131),
+		"subimage": smoothImage(400, 400).SubImage(image.Rect(37, 50, 290, 210)),
+	}
+
+	bgrNHWC := ImageNetPreprocessSpec
+	bgrNHWC.Order = BGR
+	bgrNHWC.Layout = NHWC
+
+	crop := DefaultPreprocessSpec
+	crop.Resize = ResizeCenterCrop
+	crop.Interpolation = Lanczos3
+
+	nearest := DefaultPreprocessSpec
+	nearest.Interpolation = Nearest
+	nearest.PadColor = color.RGBA{R: 114, G: 114, B: 114, A: 255}
+
+	specs := map[string]PreprocessSpec{
+		"default":  DefaultPreprocessSpec,
+		"bgr-nhwc": bgrNHWC,
+		"crop":     crop,
+		"nearest":  nearest,
+	}
+
+	for imgName, img := range images {
+		for specName, spec := range specs {
+			want, wantParams, err := PreprocessImageSpec(img, target, spec)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", imgName, specName, err)
+			}
+
+			for _, workers := range []int{1, 4} {
+				p := NewPreprocessor(target, spec, workers)
+				got := make([]float32, p.TensorLen())
+				params, err := p.Run(got, img)
+				if err != nil {
+					t.Fatalf("%s/%s: %v", imgName, specName, err)
+				}
+				if params != wantParams {
+					t.Errorf("%s/%s: params %+v, want %+v", imgName, specName, params, wantParams)
+				}
+
+				// one 8-bit step after normalization
+				lo, hi := spec.ValueRange()
+				tolerance := float64(hi-lo) * 1.5 / 255
+				var maxDiff float64
+				for i := range want {
+					maxDiff = math.Max(maxDiff, math.Abs(float64(got[i]-want[i])))
+				}
+				if maxDiff > tolerance {
+					t.Errorf("%s/%s workers=%d: max diff %.4f > %.4f", imgName, specName, workers, maxDiff, tolerance)
+				}
+				if !spec.VerifyTensorData(got) {
+					t.Errorf("%s/%s: values out of range", imgName, specName)
+				}
+			}
+		}
+	}
+}
+
+func TestPreprocessorDoesNotAllocate(t *testing.T) {
+	img := ycbcrImage(640, 480)
+	p := NewPreprocessor(ImageSize{Width: 416, Height: 416}, DefaultPreprocessSpec, 1)
+	dst := make([]float32, p.TensorLen())
+
+	allocs := testing.AllocsPerRun(5, func() {
+		if _, err := p.Run(dst, img); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations per frame, got %.0f", allocs)
+	}
+}
+
+func TestPreprocessorErrors(t *testing.T) {
+	p := NewPreprocessor(ImageSize{Width: 32, Height: 32}, DefaultPreprocessSpec, 1)
+	if _, err := p.Run(make([]float32, 10), smoothImage(8, 8)); err == nil {
+		t.Error("expected error for short buffer")
+	}
+	if _, err := p.Run(make([]float32, p.TensorLen()), image.NewRGBA(image.Rect(0, 0, 0, 0))); err == nil {
+		t.Error("expected error for empty image")
+	}
+}
+
+func benchmarkFrame() *image.YCbCr {
+	return ycbcrImage(1920, 1080)
+}
+
+func BenchmarkPreprocessImage(b *testing.B) {
+	img := benchmarkFrame()
+	target := ImageSize{Width: 416, Height: 416}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PreprocessImage(img, target)
+	}
+}
+
+func BenchmarkPreprocessorRun(b *testing.B) {
+	img := benchmarkFrame()
+	p := NewPreprocessor(ImageSize{Width: 416, Height: 416}, DefaultPreprocessSpec, 1)
+	dst := make([]float32, p.TensorLen())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Run(dst, img)
+	}
+}
+
+func BenchmarkPreprocessorRunParallel(b *testing.B) {
+	img := benchmarkFrame()
+	p := NewPreprocessor(ImageSize{Width: 416, Height: 416}, DefaultPreprocessSpec, 4)
+	dst := make([]float32, p.TensorLen())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Run(dst, img)
+	}
+}
@@ -0,0 +1,220 @@
+package imageutils
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// problem found by AssessQuality
+type QualityIssue string
+
+const (
+	IssueBlurry        QualityIssue = "blurry"
+	IssueOverExposed   QualityIssue = "over_exposed"
+	IssueUnderExposed  QualityIssue = "under_exposed"
+	IssueLowContrast   QualityIssue = "low_contrast"
+	IssueUniformRegion QualityIssue = "uniform_region"
+)
+
+// QualityThresholds decide which measurements count as issues
+type QualityThresholds struct {
+	AnalysisWidth int `json:"analysis_width"` // images are downscaled to this width first, 0 keeps the size
+
+	MinBlurVariance    float64 `json:"min_blur_variance"`    // Laplacian variance below this is blurry
+	MaxOverExposed     float64 `json:"max_over_exposed"`     // fraction of pixels >= BrightLevel
+	MaxUnderExposed    float64 `json:"max_under_exposed"`    // fraction of pixels <= DarkLevel
+	MinContrast        float64 `json:"min_contrast"`         // standard deviation of luminance, 0-255
+	MaxUniformFraction float64 `json:"max_uniform_fraction"` // fraction of the image covered by flat blocks
+
+	BrightLevel   uint8   `json:"bright_level"`
+	DarkLevel     uint8   `json:"dark_level"`
+	BlockSize     int     `json:"block_size"`     // size of the blocks checked for uniformity
+	UniformStdDev float64 `json:"uniform_stddev"` // blocks with less luminance deviation are flat
+}
+
+var DefaultQualityThresholds = QualityThresholds{
+	AnalysisWidth:      640,
+	MinBlurVariance:    60,
+	MaxOverExposed:     0.25,
+	MaxUnderExposed:    0.4,
+	MinContrast:        20,
+	MaxUniformFraction: 0.5,
+	BrightLevel:        250,
+	DarkLevel:          10,
+	BlockSize:          32,
+	UniformStdDev:      3,
+}
+
+// QualityReport holds the measurements of one frame
+type QualityReport struct {
+	BlurVariance    float64        `json:"blur_variance"`    // variance of the Laplacian, higher is sharper
+	MeanLuminance   float64        `json:"mean_luminance"`   // 0-255
+	Contrast        float64        `json:"contrast"`         // standard deviation of luminance
+	OverExposed     float64        `json:"over_exposed"`     // fraction of bright pixels
+	UnderExposed    float64        `json:"under_exposed"`    // fraction of dark pixels
+	UniformFraction float64        `json:"uniform_fraction"` // fraction covered by flat blocks (lens covered, wall, ...)
+	Issues          []QualityIssue `json:"issues"`
+}
+
+// Acceptable reports whether no issue was found
+func (r QualityReport) Acceptable() bool {
+	return len(r.Issues) == 0
+}
+
+// HasIssue reports whether the given issue was found
+func (r QualityReport) HasIssue(issue QualityIssue) bool {
+	for _, i := range r.Issues {
+		if i == issue {
+			return true
+		}
+	}
+	return false
+}
+
+func (r QualityReport) String() string {
+	return fmt.Sprintf("blur=%.1f mean=%.1f contrast=%.1f over=%.2f under=%.2f uniform=%.2f issues=%v",
+		r.BlurVariance, r.MeanLuminance, r.Contrast, r.OverExposed, r.UnderExposed, r.UniformFraction, r.Issues)
+}
+
+// AssessQuality measures sharpness, exposure, contrast and flat regions of
+// the image and lists the measurements failing the thresholds
+func AssessQuality(img image.Image, thresholds QualityThresholds) (QualityReport, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return QualityReport{}, fmt.Errorf("cannot assess empty image")
+	}
+
+	// work on a downscaled copy so the blur measure does not depend on the
+	// camera resolution
+	if w := thresholds.AnalysisWidth; w > 0 && bounds.Dx() > w {
+		h := int(float64(bounds.Dy()) * float64(w) / float64(bounds.Dx()))
+		if h < 1 {
+			h = 1
+		}
+		img = ResizeWith(img, w, h, Area)
+		bounds = img.Bounds()
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+	lum := luminance(img)
+
+	var report QualityReport
+
+	// exposure and contrast
+	var sum, sumSq float64
+	var bright, dark int
+	for _, v := range lum {
+		sum += v
+		sumSq += v * v
+		if v >= float64(thresholds.BrightLevel) {
+			bright++
+		}
+		if v <= float64(thresholds.DarkLevel) {
+			dark++
+		}
+	}
+	n := float64(len(lum))
+	report.MeanLuminance = sum / n
+	report.Contrast = math.Sqrt(math.Max(0, sumSq/n-report.MeanLuminance*report.MeanLuminance))
+	report.OverExposed = float64(bright) / n
+	report.UnderExposed = float64(dark) / n
+
+	report.BlurVariance = laplacianVariance(lum, w, h)
+	report.UniformFraction = uniformFraction(lum, w, h, thresholds.BlockSize, thresholds.UniformStdDev)
+
+	if report.BlurVariance < thresholds.MinBlurVariance {
+		report.Issues = append(report.Issues, IssueBlurry)
+	}
+	if report.OverExposed > thresholds.MaxOverExposed {
+		report.Issues = append(report.Issues, IssueOverExposed)
+	}
+	if report.UnderExposed > thresholds.MaxUnderExposed {
+		report.Issues = append(report.Issues, IssueUnderExposed)
+	}
+	if report.Contrast < thresholds.MinContrast {
+		report.Issues = append(report.Issues, IssueLowContrast)
+	}
+	if report.UniformFraction > thresholds.MaxUniformFraction {
+		report.Issues = append(report.Issues, IssueUniformRegion)
+	}
+
+	return report, nil
+}
+
+// luminance returns the Rec. 601 luma of every pixel, row by row
+func luminance(img image.Image) []float64 {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	lum := make([]float64, w*h)
+
+	// JPEG frames already carry luma
+	if ycc, ok := img.(*image.YCbCr); ok {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				lum[y*w+x] = float64(ycc.Y[ycc.YOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+			}
+		}
+		return lum
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			lum[y*w+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+		}
+	}
+	return lum
+}
+
+// laplacianVariance is the variance of the 4-neighbour Laplacian, a common
+// focus measure: sharp edges give large responses
+func laplacianVariance(lum []float64, w, h int) float64 {
+	if w < 3 || h < 3 {
+		return 0
+	}
+
+	var sum, sumSq float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			v := lum[i-w] + lum[i+w] + lum[i-1] + lum[i+1] - 4*lum[i]
+			sum += v
+			sumSq += v * v
+		}
+	}
+	n := float64((w - 2) * (h - 2))
+	mean := sum / n
+	return sumSq/n - mean*mean
+}
+
+// uniformFraction returns the fraction of the image covered by blocks whose
+// luminance barely varies
+func uniformFraction(lum []float64, w, h, blockSize int, maxStdDev float64) float64 {
+	if blockSize <= 0 {
+		return 0
+	}
+
+	var flat, total int
+	for by := 0; by < h; by += blockSize {
+		for bx := 0; bx < w; bx += blockSize {
+			var sum, sumSq float64
+			count := 0
+			for y := by; y < by+blockSize && y < h; y++ {
+				for x := bx; x < bx+blockSize && x < w; x++ {
+					v := lum[y*w+x]
+					sum += v
+					sumSq += v * v
+					count++
+				}
+			}
+			mean := sum / float64(count)
+			std := math.Sqrt(math.Max(0, sumSq/float64(count)-mean*mean))
+			if std < maxStdDev {
+				flat += count
+			}
+			total += count
+		}
+	}
+	return float64(flat) / float64(total)
+}
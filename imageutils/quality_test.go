@@ -0,0 +1,65 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// checkerImage has strong edges everywhere
+func checkerImage(w, h, cell int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(30)
+			if (x/cell+y/cell)%2 == 0 {
+				v = 220
+			}
+			img.SetRGBA(x, y, color.RGBA{R: v, G: v, B: v, A: 255})
+		}
+	}
+	return img
+}
+
+func flatImage(w, h int, v uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = v
+	}
+	return img
+}
+
+func TestAssessQuality(t *testing.T) {
+	cases := []struct {
+		name   string
+		img    image.Image
+		issues []QualityIssue
+	}{
+		{"sharp", checkerImage(320, 240, 4), nil},
+		{"smooth", smoothImage(320, 240), []QualityIssue{IssueBlurry}},
+		{"dark", flatImage(320, 240, 2), []QualityIssue{IssueBlurry, IssueUnderExposed, IssueLowContrast, IssueUniformRegion}},
+		{"bright", flatImage(320, 240, 255), []QualityIssue{IssueBlurry, IssueOverExposed, IssueLowContrast, IssueUniformRegion}},
+	}
+
+	for _, c := range cases {
+		report, err := AssessQuality(c.img, DefaultQualityThresholds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Issues) != len(c.issues) {
+			t.Errorf("%s: issues %v, want %v (%v)", c.name, report.Issues, c.issues, report)
+			continue
+		}
+		for _, issue := range c.issues {
+			if !report.HasIssue(issue) {
+				t.Errorf("%s: missing issue %s (%v)", c.name, issue, report)
+			}
+		}
+	}
+}
+
+func TestAssessQualityEmptyImage(t *testing.T) {
+	if _, err := AssessQuality(image.NewRGBA(image.Rect(0, 0, 0, 0)), DefaultQualityThresholds); err == nil {
+		t.Error("expected error for empty image")
+	}
+}
@@ -0,0 +1,187 @@
+package imageutils
+
+import (
+	"image"
+	"math"
+)
+
+// resampling kernel used when resizing
+type Interpolation int
+
+const (
+	Nearest Interpolation = iota
+	Bilinear
+	// box filter, the best choice for large downscaling (OpenCV INTER_AREA)
+	Area
+	CatmullRom
+	Lanczos3
+)
+
+// kernel returns the filter function and its support radius
+func (i Interpolation) kernel() (func(float64) float64, float64) {
+	switch i {
+	case Bilinear:
+		return func(x float64) float64 {
+			x = math.Abs(x)
+			if x < 1 {
+				return 1 - x
+			}
+			return 0
+		}, 1
+	case Area:
+		return func(x float64) float64 {
+			if x >= -0.5 && x < 0.5 {
+				return 1
+			}
+			return 0
+		}, 0.5
+	case CatmullRom:
+		return func(x float64) float64 {
+			x = math.Abs(x)
+			if x < 1 {
+				return (1.5*x-2.5)*x*x + 1
+			}
+			if x < 2 {
+				return ((-0.5*x+2.5)*x-4)*x + 2
+			}
+			return 0
+		}, 2
+	case Lanczos3:
+		return func(x float64) float64 {
+			if x == 0 {
+				return 1
+			}
+			if x <= -3 || x >= 3 {
+				return 0
+			}
+			px := math.Pi * x
+			return 3 * math.Sin(px) * math.Sin(px/3) / (px * px)
+		}, 3
+	}
+	return nil, 0
+}
+
+// contribution of the source pixels to one destination pixel
+type contribution struct {
+	start   int
+	weights []float64
+}
+
+// contributions computes the filter weights along one axis. When
+// downscaling the kernel is stretched by the scale factor so every source
+// pixel contributes (antialiasing, as PIL does).
+func contributions(srcLen, dstLen int, interp Interpolation) []contribution {
+	scale := float64(srcLen) / float64(dstLen)
+
+	// a box filter only averages when downscaling, like OpenCV upscale
+	// bilinearly instead
+	if interp == Area && scale < 1 {
+		interp = Bilinear
+	}
+	kernel, support := interp.kernel()
+	filterScale := math.Max(scale, 1)
+	support *= filterScale
+
+	result := make([]contribution, dstLen)
+	for x := range result {
+		center := (float64(x) + 0.5) * scale
+		start := int(math.Floor(center - support))
+		if start < 0 {
+			start = 0
+		}
+		end := int(math.Ceil(center + support))
+		if end > srcLen {
+			end = srcLen
+		}
+
+		weights := make([]float64, 0, end-start)
+		var total float64
+		for j := start; j < end; j++ {
+			w := kernel((float64(j) + 0.5 - center) / filterScale)
+			weights = append(weights, w)
+			total += w
+		}
+		if total != 0 {
+			for j := range weights {
+				weights[j] /= total
+			}
+		}
+		result[x] = contribution{start: start, weights: weights}
+	}
+	return result
+}
+
+// ResizeWith scales the image to width x height with the given kernel
+func ResizeWith(img image.Image, width, height int, interp Interpolation) *image.RGBA {
+	if interp == Nearest {
+		return Resize(img, width, height)
+	}
+
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width <= 0 || height <= 0 || bounds.Empty() {
+		return dst
+	}
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	// source as float RGBA, values in [0, 255]
+	src := make([]float64, srcW*srcH*4)
+	for y := 0; y < srcH; y++ {
+		for x := 0; x < srcW; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := (y*srcW + x) * 4
+			src[i] = float64(r) / 257
+			src[i+1] = float64(g) / 257
+			src[i+2] = float64(b) / 257
+			src[i+3] = float64(a) / 257
+		}
+	}
+
+	// horizontal pass: srcH rows of width pixels
+	cols := contributions(srcW, width, interp)
+	tmp := make([]float64, width*srcH*4)
+	for y := 0; y < srcH; y++ {
+		for x, c := range cols {
+			var px [4]float64
+			for k, w := range c.weights {
+				i := (y*srcW + c.start + k) * 4
+				px[0] += src[i] * w
+				px[1] += src[i+1] * w
+				px[2] += src[i+2] * w
+				px[3] += src[i+3] * w
+			}
+			copy(tmp[(y*width+x)*4:], px[:])
+		}
+	}
+
+	// vertical pass
+	rows := contributions(srcH, height, interp)
+	for y, c := range rows {
+		for x := 0; x < width; x++ {
+			var px [4]float64
+			for k, w := range c.weights {
+				i := ((c.start+k)*width + x) * 4
+				px[0] += tmp[i] * w
+				px[1] += tmp[i+1] * w
+				px[2] += tmp[i+2] * w
+				px[3] += tmp[i+3] * w
+			}
+			o := dst.PixOffset(x, y)
+			for ch := 0; ch < 4; ch++ {
+				dst.Pix[o+ch] = clampUint8(px[ch])
+			}
+		}
+	}
+	return dst
+}
+
+// clampUint8 rounds v to the nearest byte value
+func clampUint8(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
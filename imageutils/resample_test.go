@@ -0,0 +1,150 @@
+package imageutils
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// smooth returns a low-frequency test pattern sampled at (x, y) in units of
+// the source width w and height h
+func smooth(x, y, w, h float64) (float64, float64, float64) {
+	u, v := x/w, y/h
+	r := 127.5 + 100*math.Sin(2*math.Pi*u)
+	g := 127.5 + 100*math.Cos(2*math.Pi*v)
+	b := 127.5 + 100*math.Sin(2*math.Pi*(u+v)/2)
+	return r, g, b
+}
+
+// smoothImage renders the pattern at pixel centres
+func smoothImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b := smooth(float64(x)+0.5, float64(y)+0.5, float64(w), float64(h))
+			img.SetRGBA(x, y, color.RGBA{R: uint8(r + 0.5), G: uint8(g + 0.5), B: uint8(b + 0.5), A: 255})
+		}
+	}
+	return img
+}
+
+// diff returns the mean and maximum absolute channel difference
+func diff(a, b *image.RGBA) (float64, float64) {
+	var sum, maxDiff float64
+	n := 0
+	for i := range a.Pix {
+		if i%4 == 3 {
+			continue
+		}
+		d := math.Abs(float64(a.Pix[i]) - float64(b.Pix[i]))
+		sum += d
+		maxDiff = math.Max(maxDiff, d)
+		n++
+	}
+	return sum / float64(n), maxDiff
+}
+
+func TestResizeWithMatchesPattern(t *testing.T) {
+	// the resized image of a smooth pattern must be close to the pattern
+	// rendered directly at the target resolution
+	cases := []struct {
+		srcW, srcH, dstW, dstH int
+	}{
+		{256, 192, 64, 48},   // downscale
+		{256, 192, 100, 77},  // non-integer downscale
+		{64, 48, 200, 150},   // upscale
+		{128, 128, 416, 240}, // stretch
+	}
+
+	for _, interp := range []Interpolation{Bilinear, Area, CatmullRom, Lanczos3} {
+		for _, c := range cases {
+			src := smoothImage(c.srcW, c.srcH)
+			reference := smoothImage(c.dstW, c.dstH)
+
+			got := ResizeWith(src, c.dstW, c.dstH, interp)
+			mean, maxDiff := diff(got, reference)
+			if mean > 1.5 || maxDiff > 6 {
+				t.Errorf("interp %d %dx%d -> %dx%d: mean diff %.2f, max diff %.0f",
+					interp, c.srcW, c.srcH, c.dstW, c.dstH, mean, maxDiff)
+			}
+		}
+	}
+}
+
+func TestResizeWithMatchesXImage(t *testing.T) {
+	// x/image/draw implements the same separable kernels
+	src := smoothImage(300, 200)
+
+	cases := []struct {
+		interp    Interpolation
+		reference xdraw.Interpolator
+	}{
+		{Bilinear, xdraw.BiLinear},
+		{CatmullRom, xdraw.CatmullRom},
+	}
+
+	for _, c := range cases {
+		for _, size := range [][2]int{{120, 80}, {450, 300}} {
+			reference := image.NewRGBA(image.Rect(0, 0, size[0], size[1]))
+			c.reference.Scale(reference, reference.Bounds(), src, src.Bounds(), xdraw.Src, nil)
+
+			got := ResizeWith(src, size[0], size[1], c.interp)
+			mean, maxDiff := diff(got, reference)
+			if mean > 0.5 || maxDiff > 3 {
+				t.Errorf("interp %d -> %dx%d: mean diff %.2f, max diff %.0f",
+					c.interp, size[0], size[1], mean, maxDiff)
+			}
+		}
+	}
+}
+
+func TestAreaIsBlockAverage(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 8; x++ {
+			v := uint8(x*30 + y*5)
+			src.SetRGBA(x, y, color.RGBA{R: v, G: 255 - v, B: v / 2, A: 255})
+		}
+	}
+
+	got := ResizeWith(src, 2, 1, Area)
+	for bx := 0; bx < 2; bx++ {
+		var r, g, b float64
+		for y := 0; y < 4; y++ {
+			for x := bx * 4; x < bx*4+4; x++ {
+				c := src.RGBAAt(x, y)
+				r += float64(c.R)
+				g += float64(c.G)
+				b += float64(c.B)
+			}
+		}
+		want := color.RGBA{R: uint8(r/16 + 0.5), G: uint8(g/16 + 0.5), B: uint8(b/16 + 0.5), A: 255}
+		if c := got.RGBAAt(bx, 0); c != want {
+			t.Errorf("block %d: got %v, want %v", bx, c, want)
+		}
+	}
+}
+
+func TestResizeWithIdentityAndConstant(t *testing.T) {
+	src := smoothImage(40, 30)
+	flat := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for i := range flat.Pix {
+		flat.Pix[i] = 200
+	}
+
+	for _, interp := range []Interpolation{Bilinear, Area, CatmullRom, Lanczos3} {
+		if mean, maxDiff := diff(ResizeWith(src, 40, 30, interp), src); maxDiff > 0 {
+			t.Errorf("interp %d: identity resize changed pixels (mean %.2f)", interp, mean)
+		}
+
+		resized := ResizeWith(flat, 97, 13, interp)
+		for i, v := range resized.Pix {
+			if v != 200 {
+				t.Fatalf("interp %d: constant image changed at %d: %d", interp, i, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package imageutils
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// how an image is fitted into the model input
+type ResizeMode int
+
+const (
+	// keep aspect ratio, pad the remaining area
+	ResizeLetterbox ResizeMode = iota
+	// scale both axes independently to fill the input
+	ResizeStretch
+	// keep aspect ratio, fill the input and crop the overflow
+	ResizeCenterCrop
+)
+
+// order of the colour channels in the tensor
+type ChannelOrder int
+
+const (
+	RGB ChannelOrder = iota
+	BGR
+)
+
+// memory layout of the tensor
+type Layout int
+
+const (
+	NCHW Layout = iota
+	NHWC
+)
+
+// PreprocessSpec describes how an image is turned into a model input tensor.
+// Pixels are scaled to [0, 1] and then normalized per channel as
+// (v - Mean) / Std, with Mean and Std given in RGB order.
+type PreprocessSpec struct {
+	Resize        ResizeMode
+	NoScaleUp     bool // letterbox and crop only shrink, small images are padded
+	Interpolation Interpolation
+	Mean          [3]float32
+	Std           [3]float32 // zero entries are treated as 1
+	Order         ChannelOrder
+	Layout        Layout
+	PadColor      color.RGBA
+}
+
+// DefaultPreprocessSpec letterboxes bilinearly with black padding into an
+// RGB NCHW tensor in [0, 1]
+var DefaultPreprocessSpec = PreprocessSpec{
+	Resize:        ResizeLetterbox,
+	Interpolation: Bilinear,
+	Std:           [3]float32{1, 1, 1},
+	Order:         RGB,
+	Layout:        NCHW,
+	PadColor:      color.RGBA{A: 255},
+}
+
+// UltralyticsPreprocessSpec letterboxes with gray 114 padding like the
+// ultralytics training and export pipeline
+var UltralyticsPreprocessSpec = PreprocessSpec{
+	Resize:        ResizeLetterbox,
+	Interpolation: Bilinear,
+	Std:           [3]float32{1, 1, 1},
+	Order:         RGB,
+	Layout:        NCHW,
+	PadColor:      color.RGBA{R: 114, G: 114, B: 114, A: 255},
+}
+
+// ImageNetPreprocessSpec is the usual normalization of torchvision and
+// keras classification backbones
+var ImageNetPreprocessSpec = PreprocessSpec{
+	Resize:        ResizeStretch,
+	Interpolation: Bilinear,
+	Mean:          [3]float32{0.485, 0.456, 0.406},
+	Std:           [3]float32{0.229, 0.224, 0.225},
+	Order:         RGB,
+	Layout:        NCHW,
+	PadColor:      color.RGBA{A: 255},
+}
+
+// TensorShape returns the input shape for batchSize images of targetSize
+func (s PreprocessSpec) TensorShape(batchSize int, targetSize ImageSize) []int64 {
+	if s.Layout == NHWC {
+		return []int64{int64(batchSize), int64(targetSize.Height), int64(targetSize.Width), 3}
+	}
+	return []int64{int64(batchSize), 3, int64(targetSize.Height), int64(targetSize.Width)}
+}
+
+// std returns the divisor of channel c
+func (s PreprocessSpec) std(c int) float32 {
+	if s.Std[c] == 0 {
+		return 1
+	}
+	return s.Std[c]
+}
+
+// ValueRange returns the smallest and largest value the spec can produce
+func (s PreprocessSpec) ValueRange() (float32, float32) {
+	lo, hi := float32(math.Inf(1)), float32(math.Inf(-1))
+	for c := 0; c < 3; c++ {
+		a := (0 - s.Mean[c]) / s.std(c)
+		b := (1 - s.Mean[c]) / s.std(c)
+		if a > b {
+			a, b = b, a
+		}
+		if a < lo {
+			lo = a
+		}
+		if b > hi {
+			hi = b
+		}
+	}
+	return lo, hi
+}
+
+// VerifyTensorData checks that every value lies in the range of the spec
+func (s PreprocessSpec) VerifyTensorData(data []float32) bool {
+	lo, hi := s.ValueRange()
+	// allow rounding of the normalization
+	eps := (hi - lo) * 1e-5
+	for _, v := range data {
+		if !(v >= lo-eps && v <= hi+eps) {
+			return false
+		}
+	}
+	return true
+}
+
+// FitParams computes scale and offset that place an image of srcSize into
+// targetSize as described by the spec
+func FitParams(srcSize, targetSize ImageSize, spec PreprocessSpec) (LetterboxParams, error) {
+	if srcSize.Width <= 0 || srcSize.Height <= 0 {
+		return LetterboxParams{}, fmt.Errorf("invalid image size %dx%d", srcSize.Width, srcSize.Height)
+	}
+	if targetSize.Width <= 0 || targetSize.Height <= 0 {
+		return LetterboxParams{}, fmt.Errorf("invalid target size %dx%d", targetSize.Width, targetSize.Height)
+	}
+
+	scaleX := float64(targetSize.Width) / float64(srcSize.Width)
+	scaleY := float64(targetSize.Height) / float64(srcSize.Height)
+
+	if spec.Resize == ResizeStretch {
+		return LetterboxParams{
+			Scale:  math.Min(scaleX, scaleY),
+			ScaleX: scaleX,
+			ScaleY: scaleY,
+			Width:  targetSize.Width,
+			Height: targetSize.Height,
+		}, nil
+	}
+
+	scale := math.Min(scaleX, scaleY)
+	if spec.Resize == ResizeCenterCrop {
+		scale = math.Max(scaleX, scaleY)
+	}
+	if spec.NoScaleUp && scale > 1 {
+		scale = 1
+	}
+
+	// very thin images keep at least one pixel
+	params := LetterboxParams{
+		Scale:  scale,
+		ScaleX: scale,
+		ScaleY: scale,
+		Width:  int(float64(srcSize.Width) * scale),
+		Height: int(float64(srcSize.Height) * scale),
+	}
+	if params.Width < 1 {
+		params.Width = 1
+	}
+	if params.Height < 1 {
+		params.Height = 1
+	}
+	return centerParams(params, targetSize), nil
+}
+
+// centerParams centers the resized image on a canvas of the given size
+func centerParams(params LetterboxParams, canvas ImageSize) LetterboxParams {
+	params.Left = (canvas.Width - params.Width) / 2
+	params.Top = (canvas.Height - params.Height) / 2
+	params.Right = canvas.Width - params.Width - params.Left
+	params.Bottom = canvas.Height - params.Height - params.Top
+	return params
+}
+
+// ResizeWithSpec fits the image into targetSize as described by the spec
+func ResizeWithSpec(img image.Image, targetSize ImageSize, spec PreprocessSpec) (image.Image, LetterboxParams, error) {
+	bounds := img.Bounds()
+	params, err := FitParams(ImageSize{Width: bounds.Dx(), Height: bounds.Dy()}, targetSize, spec)
+	if err != nil {
+		return nil, LetterboxParams{}, err
+	}
+	return place(img, targetSize, params, spec.PadColor, spec.Interpolation), params, nil
+}
+
+// PreprocessImageSpec resizes the image and writes it into a new tensor
+func PreprocessImageSpec(img image.Image, targetSize ImageSize, spec PreprocessSpec) ([]float32, LetterboxParams, error) {
+	resized, params, err := ResizeWithSpec(img, targetSize, spec)
+	if err != nil {
+		return nil, LetterboxParams{}, err
+	}
+	tensorData := make([]float32, 3*targetSize.Height*targetSize.Width)
+	writeTensor(tensorData, resized, targetSize, spec)
+	return tensorData, params, nil
+}
+
+// PreprocessBatchSpec preprocesses every image into one contiguous tensor
+func PreprocessBatchSpec(imgs []image.Image, targetSize ImageSize, spec PreprocessSpec) ([]float32, map[int]LetterboxParams, error) {
+	params := make(map[int]LetterboxParams)
+	size := 3 * targetSize.Height * targetSize.Width
+	tensorData := make([]float32, len(imgs)*size)
+
+	for i, img := range imgs {
+		resized, imgParams, err := ResizeWithSpec(img, targetSize, spec)
+		if err != nil {
+			return nil, nil, fmt.Errorf("image %d: %v", i, err)
+		}
+		writeTensor(tensorData[i*size:(i+1)*size], resized, targetSize, spec)
+		params[i] = imgParams
+	}
+	return tensorData, params, nil
+}
+
+// writeTensor converts an image of targetSize into normalized tensor values
+func writeTensor(dst []float32, img image.Image, targetSize ImageSize, spec PreprocessSpec) {
+	plane := targetSize.Height * targetSize.Width
+
+	// tensor channel of r, g, b
+	channel := [3]int{0, 1, 2}
+	if spec.Order == BGR {
+		channel = [3]int{2, 1, 0}
+	}
+
+	bounds := img.Bounds()
+	for y := 0; y < targetSize.Height; y++ {
+		for x := 0; x < targetSize.Width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			rgb := [3]float32{normalizeColor(r), normalizeColor(g), normalizeColor(b)}
+
+			idx := y*targetSize.Width + x
+			for c, v := range rgb {
+				v = (v - spec.Mean[c]) / spec.std(c)
+				if spec.Layout == NHWC {
+					dst[idx*3+channel[c]] = v
+				} else {
+					dst[channel[c]*plane+idx] = v
+				}
+			}
+		}
+	}
+}
@@ -1,10 +1,29 @@
 package imageutils
 
+import (
+    "fmt"
+    "math"
+)
+
 // LetterboxParams stores the transformation parameters
 type LetterboxParams struct {
-    Scale float64
-    Left  int
-    Top   int
+    Scale  float64
+    ScaleX float64 // horizontal scale, differs from ScaleY when stretching
+    ScaleY float64
+    Left   int     // padding, negative when the image was cropped
+    Top    int
+    Right  int
+    Bottom int
+    Width  int     // size of the resized image before padding or cropping
+    Height int
+}
+
+// Validate reports parameters that cannot be inverted
+func (p LetterboxParams) Validate() error {
+    if !(p.ScaleX > 0) || !(p.ScaleY > 0) || math.IsInf(p.ScaleX, 0) || math.IsInf(p.ScaleY, 0) {
+        return fmt.Errorf("invalid letterbox scale %vx%v", p.ScaleX, p.ScaleY)
+    }
+    return nil
 }
 
 // ImageSize defines target dimensions for processing
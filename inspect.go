@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	onnxruntime "github.com/yalue/onnxruntime_go"
+)
+
+// tensor description of a model input or output
+type tensorInfo struct {
+	Name  string  `json:"name"`
+	Type  string  `json:"type"`
+	Shape []int64 `json:"shape"` // -1 for dynamic dimensions
+}
+
+// modelInfo is what inspect reports about a model
+type modelInfo struct {
+	Path     string            `json:"path"`
+	Inputs   []tensorInfo      `json:"inputs"`
+	Outputs  []tensorInfo      `json:"outputs"`
+	Producer string            `json:"producer,omitempty"`
+	Graph    string            `json:"graph,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"` // e.g. class names of ultralytics exports
+}
+
+func cmdInspect(ctx context.Context, args []string) error {
+	fs := newFlags("inspect", "models...")
+	lib := runtimeFlag(fs)
+	format := fs.String("format", "text", "output format: text or json")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := oneOf("format", *format, "text", "json"); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("no models given")
+	}
+
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	for _, path := range fs.Args() {
+		info, err := inspectModel(path)
+		if err != nil {
+			return err
+		}
+		if *format == "json" {
+			if err := enc.Encode(info); err != nil {
+				return err
+			}
+			continue
+		}
+		printModelInfo(info)
+	}
+	return nil
+}
+
+func inspectModel(path string) (modelInfo, error) {
+	info := modelInfo{Path: path}
+	inputs, outputs, err := onnxruntime.GetInputOutputInfo(path)
+	if err != nil {
+		return info, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	info.Inputs = tensorInfos(inputs)
+	info.Outputs = tensorInfos(outputs)
+
+	metadata, err := onnxruntime.GetModelMetadata(path)
+	if err != nil {
+		return info, fmt.Errorf("failed to read metadata of %s: %v", path, err)
+	}
+	defer metadata.Destroy()
+	info.Producer, _ = metadata.GetProducerName()
+	info.Graph, _ = metadata.GetGraphName()
+	keys, err := metadata.GetCustomMetadataMapKeys()
+	if err != nil {
+		return info, fmt.Errorf("failed to read metadata of %s: %v", path, err)
+	}
+	for _, key := range keys {
+		value, ok, err := metadata.LookupCustomMetadataMap(key)
+		if err != nil || !ok {
+			continue
+		}
+		if info.Metadata == nil {
+			info.Metadata = make(map[string]string)
+		}
+		info.Metadata[key] = value
+	}
+	return info, nil
+}
+
+func tensorInfos(infos []onnxruntime.InputOutputInfo) []tensorInfo {
+	tensors := make([]tensorInfo, len(infos))
+	for i, info := range infos {
+		tensors[i] = tensorInfo{Name: info.Name, Type: info.DataType.String(), Shape: info.Dimensions}
+	}
+	return tensors
+}
+
+func printModelInfo(info modelInfo) {
+	fmt.Fprintf(stdout, "%s\n", info.Path)
+	if info.Producer != "" || info.Graph != "" {
+		fmt.Fprintf(stdout, "  producer %q, graph %q\n", info.Producer, info.Graph)
+	}
+	for _, group := range []struct {
+		name    string
+		tensors []tensorInfo
+	}{{"input", info.Inputs}, {"output", info.Outputs}} {
+		for _, t := range group.tensors {
+			dims := make([]string, len(t.Shape))
+			for i, d := range t.Shape {
+				dims[i] = fmt.Sprint(d)
+				if d < 0 {
+					dims[i] = "?"
+				}
+			}
+			fmt.Fprintf(stdout, "  %s %s: %s [%s]\n", group.name, t.Name, t.Type, strings.Join(dims, ", "))
+		}
+	}
+
+	keys := make([]string, 0, len(info.Metadata))
+	for key := range info.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(stdout, "  metadata %s: %s\n", key, info.Metadata[key])
+	}
+}
@@ -2,272 +2,150 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"image"
-	_ "image/jpeg"
 	"os"
-	"time"
-	"yolo_detection/classifier"
+	"sort"
+	"yolo_detection/annotate"
 	"yolo_detection/detector"
-	"yolo_detection/imageutils"
+	"yolo_detection/imageloader"
 
 	onnxruntime "github.com/yalue/onnxruntime_go"
 )
 
-func main() {
-	// START-SCOPE
-
-	// cmake  libonnxruntime_providers_shared.so  libonnxruntime.so  libonnxruntime.so.1  libonnxruntime.so.1.20.0  pkgconfig
-	onnxruntime.SetSharedLibraryPath("detector/onnxruntime-linux-x64-1.20.0/lib/libonnxruntime.so")
-	err := onnxruntime.InitializeEnvironment()
-	if err != nil {
-		panic(err)
-	}
-
-	defer func() {
-		onnxruntime.DestroyEnvironment()
-		fmt.Println("Destroy Environment")
-	}()
-
-	ctx, cancel := context.WithCancel(context.Background())
-	debugClassifier(ctx)
-	cancel()
-	// ctx, cancel := context.WithCancel(context.Background())
-	// RunDetector(ctx)
-	// cancel()
-	// ctx, cancel = context.WithCancel(context.Background())
-	// RunClassifier(ctx)
-	// cancel()
-
-	// RunDetector()
-
-	// END-SCOPE
-	// -> cancel() -> inputTensor.Destroy, outputTensor.Destroy(), session.Destroy()
-	// -> onnxruntime.DestroyEnvironment()
+// exit codes scripts can rely on
+const (
+	exitOK         = 0
+	exitError      = 1 // the command failed
+	exitUsage      = 2 // invalid command, flags or arguments
+	exitRegression = 3 // bench compare found a significant slowdown
+)
 
-	time.Sleep(1 * time.Second)
+const usage = `usage: yolo_detection <command> [flags] [inputs]
+
+commands:
+  detect     run a detector on images
+  classify   run a classifier on images
+  bench      benchmark models, "bench compare" checks the history for regressions
+  inspect    print the inputs, outputs and metadata of ONNX models
+  serve      serve a detector over HTTP
+  run        run a pipeline of a deployment config on images
+  evaluate   evaluate a classifier on a folder-per-class dataset
+  sweep      tune detector thresholds on a YOLO labelled dataset
+
+Inputs are image files, directories or globs. Run "yolo_detection <command> -h"
+for the flags of a command.
+
+exit codes: 0 success, 1 failure, 2 usage error, 3 benchmark regression
+`
+
+type command func(ctx context.Context, args []string) error
+
+var commands = map[string]command{
+	"detect":   cmdDetect,
+	"classify": cmdClassify,
+	"bench":    cmdBench,
+	"inspect":  cmdInspect,
+	"serve":    cmdServe,
+	"run":      cmdRun,
+	"evaluate": cmdEvaluate,
+	"sweep":    cmdSweep,
 }
 
-func measureImageDetectionTime(yolo *detector.YOLODetector, imagePath string, runs int) (time.Duration, time.Duration, []detector.Detection, error) {
-	var totalLoadTime, totalDetectTime time.Duration
-	var lastDetections []detector.Detection
+// stdout receives the command output. os.Stdout is pointed at stderr, so
+// progress messages of the model packages stay out of the results.
+var stdout = os.Stdout
 
-	for i := 0; i < runs; i++ {
-		// Measure image loading time
-		loadStart := time.Now()
-		img, err := loadImage(imagePath)
-		if err != nil {
-			return 0, 0, nil, fmt.Errorf("error loading image: %v", err)
-		}
-		loadTime := time.Since(loadStart)
-		totalLoadTime += loadTime
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-		// Measure detection time
-		detectStart := time.Now()
-		detections, err := yolo.Detect(img)
-		if err != nil {
-			return 0, 0, nil, fmt.Errorf("error running detection: %v", err)
+func run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
 		}
-		detectTime := time.Since(detectStart)
-		totalDetectTime += detectTime
-
-		// Store last run's detections
-		lastDetections = detections
-
-		// fmt.Printf("Run %d - Load: %v, Detect: %v\n", i+1, loadTime, detectTime)
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "unknown command %q, want one of %v\n", args[0], names)
+		return exitUsage
 	}
 
-	avgLoadTime := totalLoadTime / time.Duration(runs)
-	avgDetectTime := totalDetectTime / time.Duration(runs)
-	fmt.Println("Average load time:", avgLoadTime)
-	fmt.Println("Average detect time: ", avgDetectTime)
-	return avgLoadTime, avgDetectTime, lastDetections, nil
-}
-
-func debugClassifier(ctx context.Context) {
-	fmt.Println("debug classifications")
-	imagePath := "/home/niklas/code/imageClassifierService/testImages/bundle_loaded.jpeg"
-	modelPath := "/home/niklas/code/imageClassifierService/networks/binary.onnx"
-
-	model, err := classifier.New(ctx, modelPath)
-	if err != nil {
-		fmt.Printf("Error initializing detector: %v\n", err)
-		return
-	}
+	os.Stdout = os.Stderr
+	ctx, cancel := context.WithCancel(context.Background())
+	err := cmd(ctx, args[1:])
 
-	// load image
-	img, err := loadImage(imagePath)
-	if err != nil {
-		fmt.Printf("Error loading image %v\n", err)
-		return
+	// END-SCOPE
+	// commands destroy their sessions and tensors before returning, so the
+	// environment can go right away. Models still waiting for ctx find
+	// themselves destroyed already and do not touch the runtime.
+	cancel()
+	if runtimeReady {
+		onnxruntime.DestroyEnvironment()
 	}
 
-	classifications, err := model.Classify(img)
-	if err != nil {
-		fmt.Printf("Error running detectioon: %v\n", err)
-		return
-	}
-	fmt.Println(classifications)
-	probEmpty := classifications[0]
-	probLoaded := classifications[1]
-	fmt.Printf("Probability Empty: %v, Probability Loaded: %v", probEmpty, probLoaded)
+	return exitCode(args[0], err)
 }
 
-func measureImageClassificationTime(model *classifier.Classifier, imagePath string, runs int) (time.Duration, time.Duration, []float32, error) {
-	var totalLoadTime, totalClassificationTime time.Duration
-	var lastClassifiactions []float32
-
-	for i := 0; i < runs; i++ {
-		// Measure image loading time
-		loadStart := time.Now()
-		img, err := loadImage(imagePath)
-		if err != nil {
-			return 0, 0, nil, fmt.Errorf("error loading image: %v", err)
+// exitCode reports the error of a command on stderr and maps it to the exit
+// code
+func exitCode(name string, err error) int {
+	var usageErr usageError
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &usageErr):
+		if !usageErr.printed {
+			fmt.Fprintf(os.Stderr, "%s: %v\nRun \"yolo_detection %s -h\" for usage.\n", name, err, name)
 		}
-		loadTime := time.Since(loadStart)
-		totalLoadTime += loadTime
-
-		// Measure detection time
-		classifyStart := time.Now()
-		classifications, err := model.Classify(img)
-		if err != nil {
-			return 0, 0, nil, fmt.Errorf("error running detection: %v", err)
-		}
-		detectTime := time.Since(classifyStart)
-		totalClassificationTime += detectTime
-
-		// Store last run's detections
-		lastClassifiactions = classifications
-
-		// fmt.Printf("Run %d - Load: %v, Detect: %v\n", i+1, loadTime, detectTime)
+		return exitUsage
+	case errors.Is(err, errRegression):
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return exitRegression
+	default:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return exitError
 	}
-
-	avgLoadTime := totalLoadTime / time.Duration(runs)
-	avgDetectTime := totalClassificationTime / time.Duration(runs)
-	fmt.Println("Average load time:", avgLoadTime)
-	fmt.Println("Average classification time: ", avgDetectTime)
-	return avgLoadTime, avgDetectTime, lastClassifiactions, nil
 }
 
-func RunDetector(ctx context.Context) {
-	// imagePath := "examples/images/fresh_food_counter.jpeg"
-	imagePath := "examples/images/fresh_food_counter.jpeg"
-
-	modelPath := "examples/models/object_detection1.onnx"
-	runs := 10
-
-	// load model
-	model, err := detector.New(ctx, modelPath)
-	if err != nil {
-		// fmt.Printf("Error initializing detector: %v\n", err)
-		return
-	}
-
-	// *detector.YOLODetector
-	measureImageDetectionTime(model, imagePath, runs)
-
-	// load image
-	img, err := loadImage(imagePath)
-	if err != nil {
-		fmt.Printf("Error loading image %v\n", err)
-		return
-	}
-
-	// Run benchmark
-	result, err := measureInferenceTimeYolo(model, img, 100)
-	if err != nil {
-		fmt.Printf("Error during benchmark: %v\n", err)
-		return
-	}
-
-	fmt.Printf("\nBenchmark Results:\n")
-	fmt.Printf("Average inference time over %d runs: %v\n",
-		result.NumRuns, result.InferenceTime)
-
-	// run detection
-	detections, err := model.Detect(img)
-	if err != nil {
-		fmt.Printf("Error running detectDrawioon: %v\n", err)
-		return
-	}
-
-	// print results
-	fmt.Println("Detections")
-	for _, det := range detections {
-		fmt.Printf("Found %s  (confidence %.2f) ad box: %+v\n",
-			det.Class, det.Confidence, det.Box)
-	}
-	fmt.Println("-------------------")
-
-
-	if err := DrawDebug(img, detections, "debug_output.jpg"); err != nil {
-		fmt.Printf("Failed to save debug image: %v\n", err)
-	}
+func loadImage(filepath string) (image.Image, error) {
+	return imageloader.Load(filepath)
 }
 
-func RunClassifier(ctx context.Context) {
-	imagePath := "examples/images/bundle.jpeg"
-	modelPath := "pbtf2onnx/models/lower_cart_empty_loaded.onnx"
-	runs := 10
-
-	model, err := classifier.New(ctx, modelPath)
-	if err != nil {
-		fmt.Printf("Error initializing detector: %v\n", err)
-		return
-	}
-	measureImageClassificationTime(model, imagePath, runs)
-
-	// load image
-	img, err := loadImage(imagePath)
-	if err != nil {
-		fmt.Printf("Error loading image %v\n", err)
-		return
-	}
-
-	result, err := measureInferenceTimeBinary(model, img, 100)
-	if err != nil {
-		fmt.Printf("Error during benchmark: %v\n", err)
-		return
-	}
-	fmt.Printf("\nBenchmark Results:\n")
-	fmt.Printf("Average inference time over %d runs: %v\n",
-		result.NumRuns, result.InferenceTime)
-	// run detection
-	classifications, err := model.Classify(img)
-	if err != nil {
-		fmt.Printf("Error running detectioon: %v\n", err)
-		return
-	}
-	fmt.Println(classifications)
-	probEmpty := classifications[0]
-	probLoaded := classifications[1]
-	fmt.Printf("Probability Empty: %v, Probability Loaded: %v", probEmpty, probLoaded)
+func DrawDebug(img image.Image, detections []detector.Detection, outputPath string) error {
+	canvas := annotate.NewCanvas(img)
+	annotate.Detections(canvas, detections, annotate.DefaultOptions)
+	return saveDebug(canvas, outputPath)
 }
 
-func loadImage(filepath string) (image.Image, error) {
-	f, err := os.Open(filepath)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
+func DrawPoseDebug(img image.Image, detections []detector.PoseDetection, outputPath string) error {
+	canvas := annotate.NewCanvas(img)
+	annotate.Poses(canvas, detections, annotate.DefaultOptions)
+	return saveDebug(canvas, outputPath)
+}
 
-	img, _, err := image.Decode(f)
-	return img, err
+func DrawOBBDebug(img image.Image, detections []detector.OBBDetection, outputPath string) error {
+	canvas := annotate.NewCanvas(img)
+	annotate.OBBs(canvas, detections, annotate.DefaultOptions)
+	return saveDebug(canvas, outputPath)
 }
 
-func DrawDebug(img image.Image, detections []detector.Detection, outputPath string) error {
-	debugDetections := make([]imageutils.Detection, len(detections))
-	for i, det := range detections {
-		debugDetections[i] = imageutils.Detection{
-			Box: imageutils.Box{
-				X1: det.Box.X1,
-				Y1: det.Box.Y1,
-				X2: det.Box.X2,
-				Y2: det.Box.Y2,
-			},
-			Class:      det.Class,
-			Confidence: det.Confidence,
-		}
+func saveDebug(img image.Image, outputPath string) error {
+	if err := annotate.Save(outputPath, img); err != nil {
+		return err
 	}
-	return imageutils.DrawResult(img, debugDetections, outputPath)
+	fmt.Printf("Debug image saved to: %s\n", outputPath)
+	return nil
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"help", flag.ErrHelp, exitOK},
+		{"usage", usagef("-model is required"), exitUsage},
+		{"flag error", usageError{err: errors.New("flag provided but not defined"), printed: true}, exitUsage},
+		{"wrapped usage", fmt.Errorf("detect: %w", usagef("no input images")), exitUsage},
+		{"regression", fmt.Errorf("%w in 2 stages", errRegression), exitRegression},
+		{"failure", errors.New("error loading image"), exitError},
+	}
+	for _, tt := range tests {
+		if got := exitCode("test", tt.err); got != tt.want {
+			t.Errorf("%s: exitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// the runtime is never loaded, every case fails or stops before
+func TestRun(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"--help"}, exitOK},
+		{[]string{"train"}, exitUsage},
+		{[]string{"detect", "-h"}, exitOK},
+		{[]string{"detect", "-bogus"}, exitUsage},
+		{[]string{"detect", "image.jpg"}, exitUsage},
+		{[]string{"classify", "-model", "m.onnx", "-format", "xml", "image.jpg"}, exitUsage},
+		{[]string{"bench", "-model", "m.onnx", "-concurrency", "1,x", "image.jpg"}, exitUsage},
+		{[]string{"inspect"}, exitUsage},
+		{[]string{"serve", "-model", "m.onnx", "extra"}, exitUsage},
+		{[]string{"evaluate", "-model", "m.onnx"}, exitUsage},
+		{[]string{"sweep", "-images", "images"}, exitUsage},
+		{[]string{"inspect", "-lib", "missing/libonnxruntime.so", "m.onnx"}, exitError},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args); got != tt.want {
+			t.Errorf("run(%q) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"yolo_detection/classifier"
+	"yolo_detection/detector"
+)
+
+// CropConfig decides how detection boxes are cut out for the second stage
+type CropConfig struct {
+	Padding float32 `json:"padding"`  // context added on each side, as a fraction of the box size
+	Square  bool    `json:"square"`   // expand the shorter side so the crop is square
+	MinSize int     `json:"min_size"` // boxes smaller than this (in pixels) are not classified
+}
+
+var DefaultCropConfig = CropConfig{
+	Padding: 0.1,
+	Square:  true,
+	MinSize: 8,
+}
+
+// BatchClassifier classifies several crops at once, implemented by
+// *classifier.Classifier
+type BatchClassifier interface {
+	ClassifyBatch(imgs []image.Image) ([][]classifier.Classification, error)
+}
+
+// ClassifyStage classifies the crop of every detection with the classifier
+// registered for its class, e.g. telling full from empty packs after
+// "cigarettes" was detected
+type ClassifyStage struct {
+	Classifiers map[string]BatchClassifier // by detection class
+	Crop        CropConfig
+}
+
+// CropRect returns the crop rectangle of a box within bounds. The box is
+// relative to bounds.Min, the result is in absolute image coordinates.
+func CropRect(box detector.Box, bounds image.Rectangle, cfg CropConfig) image.Rectangle {
+	w, h := box.X2-box.X1, box.Y2-box.Y1
+	x1, y1 := box.X1-w*cfg.Padding, box.Y1-h*cfg.Padding
+	x2, y2 := box.X2+w*cfg.Padding, box.Y2+h*cfg.Padding
+
+	if cfg.Square {
+		cx, cy := (x1+x2)/2, (y1+y2)/2
+		half := (x2 - x1) / 2
+		if y2-y1 > x2-x1 {
+			half = (y2 - y1) / 2
+		}
+		x1, x2 = cx-half, cx+half
+		y1, y2 = cy-half, cy+half
+	}
+
+	r := image.Rect(
+		int(math.Floor(float64(x1))), int(math.Floor(float64(y1))),
+		int(math.Ceil(float64(x2))), int(math.Ceil(float64(y2))),
+	)
+	return r.Add(bounds.Min).Intersect(bounds)
+}
+
+// Crop cuts the crop rectangle of a box out of img, sharing pixels with img
+// when possible
+func Crop(img image.Image, box detector.Box, cfg CropConfig) image.Image {
+	r := CropRect(box, img.Bounds(), cfg)
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+// Run classifies the items in place. Crops of the same class are sent to
+// their classifier as one batch.
+func (s *ClassifyStage) Run(img image.Image, items []Item) error {
+	byClass := make(map[string][]int)
+	for i, item := range items {
+		if _, ok := s.Classifiers[item.Class]; !ok {
+			continue
+		}
+		r := CropRect(item.Box, img.Bounds(), s.Crop)
+		if r.Dx() < s.Crop.MinSize || r.Dy() < s.Crop.MinSize || r.Empty() {
+			continue
+		}
+		byClass[item.Class] = append(byClass[item.Class], i)
+	}
+
+	for class, indices := range byClass {
+		crops := make([]image.Image, len(indices))
+		for k, i := range indices {
+			crops[k] = Crop(img, items[i].Box, s.Crop)
+		}
+
+		results, err := s.Classifiers[class].ClassifyBatch(crops)
+		if err != nil {
+			return fmt.Errorf("classifying %s crops failed: %v", class, err)
+		}
+		for k, i := range indices {
+			items[i].Classifications = results[k]
+		}
+	}
+	return nil
+}
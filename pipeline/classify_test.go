@@ -0,0 +1,138 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+	"yolo_detection/classifier"
+	"yolo_detection/detector"
+)
+
+func TestCropRect(t *testing.T) {
+	frame := image.Rect(0, 0, 100, 100)
+	plain := CropConfig{}
+	padded := CropConfig{Padding: 0.1}
+	square := CropConfig{Square: true}
+
+	for _, tc := range []struct {
+		name   string
+		box    detector.Box
+		bounds image.Rectangle
+		cfg    CropConfig
+		want   image.Rectangle
+	}{
+		{"plain", detector.Box{X1: 10, Y1: 20, X2: 30, Y2: 60}, frame, plain, image.Rect(10, 20, 30, 60)},
+		{"fractional box rounds outwards", detector.Box{X1: 10.5, Y1: 10.2, X2: 20.4, Y2: 20.9}, frame, plain, image.Rect(10, 10, 21, 21)},
+		{"padding", detector.Box{X1: 10, Y1: 20, X2: 30, Y2: 60}, frame, padded, image.Rect(8, 16, 32, 64)},
+		{"square widens a tall box", detector.Box{X1: 10, Y1: 20, X2: 30, Y2: 60}, frame, square, image.Rect(0, 20, 40, 60)},
+		{"square heightens a wide box", detector.Box{X1: 20, Y1: 40, X2: 60, Y2: 50}, frame, square, image.Rect(20, 25, 60, 65)},
+		{"square clamped at the left edge", detector.Box{X1: 0, Y1: 10, X2: 10, Y2: 30}, frame, square, image.Rect(0, 10, 15, 30)},
+		{"padding clamped at the corner", detector.Box{X1: 90, Y1: 90, X2: 100, Y2: 100}, frame, padded, image.Rect(89, 89, 100, 100)},
+		{"box relative to offset bounds", detector.Box{X1: 0, Y1: 0, X2: 10, Y2: 10}, image.Rect(100, 50, 200, 150), padded, image.Rect(100, 50, 111, 61)},
+		{"box outside the image", detector.Box{X1: 120, Y1: 10, X2: 130, Y2: 20}, frame, padded, image.Rectangle{}},
+	} {
+		if got := CropRect(tc.box, tc.bounds, tc.cfg); got != tc.want {
+			t.Errorf("%s: CropRect = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCrop(t *testing.T) {
+	box := detector.Box{X1: 10, Y1: 20, X2: 30, Y2: 40}
+
+	// sub images share pixels and keep their position
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	crop := Crop(img, box, CropConfig{})
+	if crop.Bounds() != image.Rect(10, 20, 30, 40) {
+		t.Errorf("sub image bounds %v", crop.Bounds())
+	}
+	img.SetRGBA(10, 20, color.RGBA{R: 255, A: 255})
+	if r, _, _, _ := crop.At(10, 20).RGBA(); r == 0 {
+		t.Error("crop does not share pixels with the image")
+	}
+
+	// other images are copied to the origin
+	copied := Crop(image.NewUniform(color.RGBA{G: 255, A: 255}), box, CropConfig{})
+	if copied.Bounds() != image.Rect(0, 0, 20, 20) {
+		t.Errorf("copied crop bounds %v", copied.Bounds())
+	}
+	if _, g, _, _ := copied.At(5, 5).RGBA(); g == 0 {
+		t.Error("copied crop is empty")
+	}
+}
+
+// fakeClassifier labels every crop with its left edge and records the calls
+type fakeClassifier struct {
+	calls [][]image.Rectangle
+	err   error
+}
+
+func (f *fakeClassifier) ClassifyBatch(imgs []image.Image) ([][]classifier.Classification, error) {
+	var bounds []image.Rectangle
+	results := make([][]classifier.Classification, len(imgs))
+	for i, img := range imgs {
+		bounds = append(bounds, img.Bounds())
+		results[i] = []classifier.Classification{{Class: fmt.Sprint(img.Bounds().Min.X), Confidence: 1}}
+	}
+	f.calls = append(f.calls, bounds)
+	return results, f.err
+}
+
+func item(class string, x float32) Item {
+	return Item{Detection: detector.Detection{Box: detector.Box{X1: x, Y1: 0, X2: x + 10, Y2: 10}, Class: class}}
+}
+
+func TestClassifyStageRun(t *testing.T) {
+	packs, bottles := &fakeClassifier{}, &fakeClassifier{}
+	stage := &ClassifyStage{
+		Classifiers: map[string]BatchClassifier{"cigarettes": packs, "jack_daniels": bottles},
+		Crop:        CropConfig{MinSize: 8},
+	}
+	items := []Item{
+		item("cigarettes", 0),
+		item("jack_daniels", 10),
+		item("cigarettes", 20),
+		item("redbull", 30), // no classifier
+		item("cigarettes", 40),
+		{Detection: detector.Detection{Box: detector.Box{X1: 50, Y1: 0, X2: 55, Y2: 5}, Class: "cigarettes"}}, // below MinSize
+		item("cigarettes", 60),
+		item("jack_daniels", 70),
+		item("cigarettes", 80),
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 20))
+	if err := stage.Run(img, items); err != nil {
+		t.Fatal(err)
+	}
+
+	// one call per class with the crops in item order
+	if len(packs.calls) != 1 || len(packs.calls[0]) != 5 {
+		t.Fatalf("cigarettes calls %v", packs.calls)
+	}
+	if len(bottles.calls) != 1 || len(bottles.calls[0]) != 2 {
+		t.Fatalf("jack_daniels calls %v", bottles.calls)
+	}
+
+	var got []string
+	for _, it := range items {
+		label := ""
+		if len(it.Classifications) > 0 {
+			label = it.Classifications[0].Class
+		}
+		got = append(got, label)
+	}
+	want := []string{"0", "10", "20", "", "40", "", "60", "70", "80"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("classifications %q, want %q", got, want)
+	}
+}
+
+func TestClassifyStageRunError(t *testing.T) {
+	stage := &ClassifyStage{Classifiers: map[string]BatchClassifier{"cigarettes": &fakeClassifier{err: errors.New("boom")}}}
+	if err := stage.Run(image.NewRGBA(image.Rect(0, 0, 20, 20)), []Item{item("cigarettes", 0)}); err == nil {
+		t.Error("expected the classifier error")
+	}
+}
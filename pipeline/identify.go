@@ -0,0 +1,54 @@
+package pipeline
+
+import (
+	"fmt"
+	"image"
+	"yolo_detection/embedding"
+)
+
+// IdentifyStage assigns SKU identities to generic detections by matching
+// the embedding of their crop against a gallery of reference products
+type IdentifyStage struct {
+	Embedder  *embedding.Embedder
+	Gallery   *embedding.Gallery
+	Classes   []string // detection classes to identify, empty identifies all
+	Crop      CropConfig
+	Threshold float32 // minimum cosine similarity, below the SKU is unknown
+}
+
+// Run sets the identity of the matching items in place
+func (s *IdentifyStage) Run(img image.Image, items []Item) error {
+	if s.Embedder.Dim() != s.Gallery.Dim() {
+		return fmt.Errorf("embedder dimension %d does not match gallery dimension %d", s.Embedder.Dim(), s.Gallery.Dim())
+	}
+
+	for i := range items {
+		if !s.applies(items[i].Class) {
+			continue
+		}
+		r := CropRect(items[i].Box, img.Bounds(), s.Crop)
+		if r.Dx() < s.Crop.MinSize || r.Dy() < s.Crop.MinSize || r.Empty() {
+			continue
+		}
+
+		vector, err := s.Embedder.Embed(Crop(img, items[i].Box, s.Crop))
+		if err != nil {
+			return fmt.Errorf("embedding failed: %v", err)
+		}
+		match := s.Gallery.Lookup(vector, s.Threshold)
+		items[i].Identity = &match
+	}
+	return nil
+}
+
+func (s *IdentifyStage) applies(class string) bool {
+	if len(s.Classes) == 0 {
+		return true
+	}
+	for _, c := range s.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"yolo_detection/classifier"
+	"yolo_detection/detector"
+	"yolo_detection/embedding"
+	"yolo_detection/imageutils"
+)
+
+// ErrFrameRejected is returned when the quality gate rejects a frame
+var ErrFrameRejected = errors.New("frame rejected by quality gate")
+
+// what to do with frames failing the quality gate
+type QualityPolicy int
+
+const (
+	// run detection anyway and mark the result as low confidence
+	QualityFlag QualityPolicy = iota
+	// skip detection and return ErrFrameRejected
+	QualityReject
+)
+
+// QualityGate checks frames before inference, so a dirty or blocked camera
+// does not look like an empty shelf
+type QualityGate struct {
+	Thresholds imageutils.QualityThresholds
+	Policy     QualityPolicy
+}
+
+// detection with the pipeline's annotations
+type Item struct {
+	detector.Detection
+	Zones           []string                    `json:"zones,omitempty"`           // names of the zones containing the detection
+	Classifications []classifier.Classification `json:"classifications,omitempty"` // set by the classify stage
+	Identity        *embedding.Match            `json:"identity,omitempty"`        // SKU set by the identify stage
+}
+
+// result of one frame
+type Result struct {
+	Items         []Item                    `json:"items"`
+	Quality       *imageutils.QualityReport `json:"quality,omitempty"` // nil without quality gate
+	LowConfidence bool                      `json:"low_confidence"`    // the frame failed the quality gate
+}
+
+// Pipeline runs the detection steps for one camera
+type Pipeline struct {
+	Detector *detector.YOLODetector
+	Quality  *QualityGate        // optional
+	Enhance  imageutils.Enhancer // optional, applied after the quality gate
+	Zones    *Zones              // optional
+	Classify *ClassifyStage      // optional second stage
+	Identify *IdentifyStage      // optional gallery lookup
+}
+
+// create new pipeline around a detector
+func New(yolo *detector.YOLODetector) *Pipeline {
+	return &Pipeline{Detector: yolo}
+}
+
+// Run processes one frame. A frame rejected by the quality gate returns the
+// result holding the quality report together with ErrFrameRejected.
+func (p *Pipeline) Run(img image.Image) (*Result, error) {
+	result := &Result{}
+
+	if p.Quality != nil {
+		report, err := imageutils.AssessQuality(img, p.Quality.Thresholds)
+		if err != nil {
+			return nil, fmt.Errorf("quality assessment failed: %v", err)
+		}
+		result.Quality = &report
+
+		if !report.Acceptable() {
+			if p.Quality.Policy == QualityReject {
+				return result, fmt.Errorf("%w: %v", ErrFrameRejected, report.Issues)
+			}
+			result.LowConfidence = true
+		}
+	}
+
+	// quality is judged on the raw frame, enhancement would hide a dark or
+	// washed out camera
+	if p.Enhance != nil {
+		img = p.Enhance.Enhance(img)
+	}
+
+	input, offset := img, image.Point{}
+	if p.Zones != nil {
+		var err error
+		if input, offset, err = p.Zones.Input(img); err != nil {
+			return nil, err
+		}
+	}
+
+	detections, err := p.Detector.Detect(input)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Items = p.items(detections, offset)
+
+	// crops are taken from the enhanced full frame
+	if p.Classify != nil {
+		if err := p.Classify.Run(img, result.Items); err != nil {
+			return nil, err
+		}
+	}
+	if p.Identify != nil {
+		if err := p.Identify.Run(img, result.Items); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// items moves detections of the detector input by offset into frame
+// coordinates and assigns them to the zones
+func (p *Pipeline) items(detections []detector.Detection, offset image.Point) []Item {
+	var items []Item
+	for _, det := range detections {
+		det.Box = det.Box.Translate(float32(offset.X), float32(offset.Y))
+
+		item := Item{Detection: det}
+		if p.Zones != nil {
+			item.Zones = p.Zones.Match(det.Box)
+			if len(item.Zones) == 0 && p.Zones.config.Drop {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,213 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"sync"
+	"yolo_detection/detector"
+)
+
+// how a detection is assigned to a zone
+type ZoneRule string
+
+const (
+	// the box centre lies inside the zone
+	ZoneCenter ZoneRule = "center"
+	// at least MinOverlap of the box area lies inside the zone
+	ZoneOverlap ZoneRule = "overlap"
+)
+
+// what part of the frame is passed to the detector
+type ZoneInput string
+
+const (
+	// the full frame
+	InputFull ZoneInput = "full"
+	// the bounding rectangle of all zones
+	InputCrop ZoneInput = "crop"
+	// the bounding rectangle, with everything outside the zones painted gray
+	InputMask ZoneInput = "mask"
+)
+
+// polygon in image coordinates of the camera
+type Zone struct {
+	Name    string           `json:"name"`
+	Polygon detector.Polygon `json:"polygon"`
+}
+
+// ZoneConfig describes the zones of one camera
+type ZoneConfig struct {
+	Zones      []Zone    `json:"zones"`
+	Rule       ZoneRule  `json:"rule"`        // default center
+	MinOverlap float32   `json:"min_overlap"` // for the overlap rule, default 0.5
+	Drop       bool      `json:"drop"`        // drop detections outside every zone, otherwise only tag them
+	Input      ZoneInput `json:"input"`       // default full
+}
+
+// Zones assigns detections to the zones of a camera
+type Zones struct {
+	config ZoneConfig
+
+	// mask cached for the last frame bounds
+	mu         sync.Mutex
+	maskBounds image.Rectangle
+	mask       *image.Alpha
+}
+
+// LoadZones reads a JSON zone config
+func LoadZones(path string) (*Zones, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zones: %v", err)
+	}
+	var config ZoneConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse zones %s: %v", path, err)
+	}
+	return NewZones(config)
+}
+
+// NewZones validates the config and fills in defaults
+func NewZones(config ZoneConfig) (*Zones, error) {
+	if len(config.Zones) == 0 {
+		return nil, fmt.Errorf("no zones defined")
+	}
+	for i, z := range config.Zones {
+		if len(z.Polygon) < 3 {
+			return nil, fmt.Errorf("zone %d (%q) needs at least 3 points, got %d", i, z.Name, len(z.Polygon))
+		}
+		if z.Polygon.Area() == 0 {
+			return nil, fmt.Errorf("zone %d (%q) has no area", i, z.Name)
+		}
+	}
+
+	switch config.Rule {
+	case "":
+		config.Rule = ZoneCenter
+	case ZoneCenter, ZoneOverlap:
+	default:
+		return nil, fmt.Errorf("unknown zone rule %q", config.Rule)
+	}
+	if config.MinOverlap <= 0 {
+		config.MinOverlap = 0.5
+	}
+	if config.MinOverlap > 1 {
+		return nil, fmt.Errorf("min_overlap must be in (0, 1], got %v", config.MinOverlap)
+	}
+
+	switch config.Input {
+	case "":
+		config.Input = InputFull
+	case InputFull, InputCrop, InputMask:
+	default:
+		return nil, fmt.Errorf("unknown zone input %q", config.Input)
+	}
+
+	return &Zones{config: config}, nil
+}
+
+// Config returns the config with defaults filled in
+func (z *Zones) Config() ZoneConfig {
+	return z.config
+}
+
+// Match returns the names of the zones containing the box under the rule
+func (z *Zones) Match(box detector.Box) []string {
+	var names []string
+	for _, zone := range z.config.Zones {
+		if z.contains(zone.Polygon, box) {
+			names = append(names, zone.Name)
+		}
+	}
+	return names
+}
+
+func (z *Zones) contains(poly detector.Polygon, box detector.Box) bool {
+	if z.config.Rule == ZoneCenter {
+		return poly.Contains(detector.Point{X: (box.X1 + box.X2) / 2, Y: (box.Y1 + box.Y2) / 2})
+	}
+
+	area := (box.X2 - box.X1) * (box.Y2 - box.Y1)
+	if area <= 0 {
+		return false
+	}
+	// the box is convex, so it can clip zones of any shape
+	rect := detector.Polygon{{X: box.X1, Y: box.Y1}, {X: box.X2, Y: box.Y1}, {X: box.X2, Y: box.Y2}, {X: box.X1, Y: box.Y2}}
+	return poly.ClipConvex(rect).Area()/area >= z.config.MinOverlap
+}
+
+// Rect returns the bounding rectangle of all zones within bounds. Zone
+// coordinates are relative to bounds.Min, like detections.
+func (z *Zones) Rect(bounds image.Rectangle) image.Rectangle {
+	var r image.Rectangle
+	for _, zone := range z.config.Zones {
+		b := zone.Polygon.Bounds()
+		r = r.Union(image.Rect(int(b.X1), int(b.Y1), int(b.X2+0.999), int(b.Y2+0.999)))
+	}
+	return r.Add(bounds.Min).Intersect(bounds)
+}
+
+// Input returns the image passed to the detector and the offset to add to
+// its detections
+func (z *Zones) Input(img image.Image) (image.Image, image.Point, error) {
+	if z.config.Input == InputFull {
+		return img, image.Point{}, nil
+	}
+
+	bounds := img.Bounds()
+	r := z.Rect(bounds)
+	if r.Empty() {
+		return nil, image.Point{}, fmt.Errorf("zones do not overlap the %dx%d frame", bounds.Dx(), bounds.Dy())
+	}
+	offset := r.Min.Sub(bounds.Min)
+
+	if z.config.Input == InputCrop {
+		if sub, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}); ok {
+			return sub.SubImage(r), offset, nil
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	if z.config.Input == InputMask {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.RGBA{R: 114, G: 114, B: 114, A: 255}), image.Point{}, draw.Src)
+		draw.DrawMask(dst, dst.Bounds(), img, r.Min, z.maskFor(bounds, r), image.Point{}, draw.Over)
+	} else {
+		draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	}
+	return dst, offset, nil
+}
+
+// maskFor returns the zone mask of the crop rectangle r, opaque inside any
+// zone. Zones are given relative to the frame origin.
+func (z *Zones) maskFor(bounds, r image.Rectangle) *image.Alpha {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.mask != nil && z.maskBounds == bounds {
+		return z.mask
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			pt := detector.Point{
+				X: float32(r.Min.X-bounds.Min.X+x) + 0.5,
+				Y: float32(r.Min.Y-bounds.Min.Y+y) + 0.5,
+			}
+			for _, zone := range z.config.Zones {
+				if zone.Polygon.Contains(pt) {
+					mask.Pix[mask.PixOffset(x, y)] = 255
+					break
+				}
+			}
+		}
+	}
+	z.mask = mask
+	z.maskBounds = bounds
+	return mask
+}
@@ -0,0 +1,223 @@
+package pipeline
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+	"yolo_detection/detector"
+)
+
+func rectZone(name string, x1, y1, x2, y2 float32) Zone {
+	return Zone{Name: name, Polygon: detector.Polygon{{X: x1, Y: y1}, {X: x2, Y: y1}, {X: x2, Y: y2}, {X: x1, Y: y2}}}
+}
+
+// triangle with the right angle at the origin
+var triangle = Zone{Name: "triangle", Polygon: detector.Polygon{{X: 0, Y: 0}, {X: 40, Y: 0}, {X: 0, Y: 40}}}
+
+func mustZones(t *testing.T, config ZoneConfig) *Zones {
+	t.Helper()
+	z, err := NewZones(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+func TestNewZones(t *testing.T) {
+	shelf := rectZone("shelf", 0, 0, 10, 10)
+	for _, tc := range []struct {
+		name   string
+		config ZoneConfig
+		err    string
+	}{
+		{"no zones", ZoneConfig{}, "no zones"},
+		{"two points", ZoneConfig{Zones: []Zone{{Name: "line", Polygon: detector.Polygon{{X: 0, Y: 0}, {X: 5, Y: 5}}}}}, "at least 3 points"},
+		{"no area", ZoneConfig{Zones: []Zone{{Name: "flat", Polygon: detector.Polygon{{X: 0, Y: 0}, {X: 5, Y: 0}, {X: 10, Y: 0}}}}}, "no area"},
+		{"unknown rule", ZoneConfig{Zones: []Zone{shelf}, Rule: "inside"}, "unknown zone rule"},
+		{"overlap above 1", ZoneConfig{Zones: []Zone{shelf}, MinOverlap: 1.5}, "min_overlap"},
+		{"unknown input", ZoneConfig{Zones: []Zone{shelf}, Input: "blur"}, "unknown zone input"},
+	} {
+		_, err := NewZones(tc.config)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.err)
+		}
+	}
+
+	config := mustZones(t, ZoneConfig{Zones: []Zone{shelf}}).Config()
+	if config.Rule != ZoneCenter || config.MinOverlap != 0.5 || config.Input != InputFull {
+		t.Errorf("defaults not filled in: %+v", config)
+	}
+}
+
+// box of size 2 centred on x, y
+func around(x, y float32) detector.Box {
+	return detector.Box{X1: x - 1, Y1: y - 1, X2: x + 1, Y2: y + 1}
+}
+
+func TestMatchCenter(t *testing.T) {
+	z := mustZones(t, ZoneConfig{Zones: []Zone{triangle, rectZone("right", 30, 0, 60, 40)}})
+	for _, tc := range []struct {
+		name string
+		box  detector.Box
+		want []string
+	}{
+		{"inside", around(10, 10), []string{"triangle"}},
+		{"inside both", around(35, 2), []string{"triangle", "right"}},
+		{"outside the hypotenuse", around(25, 25), nil},
+		{"outside every zone", around(70, 10), nil},
+		// only the centre counts, the box reaches far into the triangle
+		{"large box centred outside", detector.Box{X1: 0, Y1: 0, X2: 50, Y2: 70}, nil},
+		// the even-odd rule counts the top edge in and the bottom edge out
+		{"on the top edge", around(45, 0), []string{"right"}},
+		{"on the bottom edge", around(45, 40), nil},
+	} {
+		if got := z.Match(tc.box); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Match = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMatchOverlap(t *testing.T) {
+	z := mustZones(t, ZoneConfig{Zones: []Zone{rectZone("shelf", 0, 0, 100, 50)}, Rule: ZoneOverlap, MinOverlap: 0.5})
+	for _, tc := range []struct {
+		name string
+		box  detector.Box
+		want bool
+	}{
+		{"inside", detector.Box{X1: 10, Y1: 10, X2: 20, Y2: 20}, true},
+		{"half inside", detector.Box{X1: 90, Y1: 10, X2: 110, Y2: 20}, true},
+		{"40% inside", detector.Box{X1: 10, Y1: 46, X2: 20, Y2: 56}, false},
+		{"outside", detector.Box{X1: 200, Y1: 10, X2: 210, Y2: 20}, false},
+		{"enclosing the zone", detector.Box{X1: -100, Y1: -100, X2: 200, Y2: 200}, false},
+		{"empty box", detector.Box{X1: 10, Y1: 10, X2: 10, Y2: 20}, false},
+	} {
+		if got := len(z.Match(tc.box)) > 0; got != tc.want {
+			t.Errorf("%s: matched %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRect(t *testing.T) {
+	z := mustZones(t, ZoneConfig{Zones: []Zone{rectZone("a", 10, 20, 30.2, 40), rectZone("b", 50, 5, 60, 25)}})
+	if got, want := z.Rect(image.Rect(0, 0, 100, 100)), image.Rect(10, 5, 60, 40); got != want {
+		t.Errorf("Rect = %v, want %v", got, want)
+	}
+	// zones are relative to the frame origin and clipped to the frame
+	if got, want := z.Rect(image.Rect(100, 100, 155, 130)), image.Rect(110, 105, 155, 130); got != want {
+		t.Errorf("Rect of offset frame = %v, want %v", got, want)
+	}
+	if got := z.Rect(image.Rect(0, 0, 5, 5)); !got.Empty() {
+		t.Errorf("Rect outside the zones = %v, want empty", got)
+	}
+}
+
+func filled(r image.Rectangle, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestInput(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	frame := filled(image.Rect(0, 0, 100, 80), red)
+	zones := []Zone{{Name: "triangle", Polygon: detector.Polygon{{X: 20, Y: 10}, {X: 60, Y: 10}, {X: 20, Y: 50}}}}
+
+	full := mustZones(t, ZoneConfig{Zones: zones})
+	if img, offset, err := full.Input(frame); err != nil || img != image.Image(frame) || offset != (image.Point{}) {
+		t.Errorf("full input: %v %v %v", img.Bounds(), offset, err)
+	}
+
+	crop := mustZones(t, ZoneConfig{Zones: zones, Input: InputCrop})
+	img, offset, err := crop.Input(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(20, 10, 60, 50) || offset != image.Pt(20, 10) {
+		t.Errorf("crop input %v offset %v", img.Bounds(), offset)
+	}
+
+	mask := mustZones(t, ZoneConfig{Zones: zones, Input: InputMask})
+	img, offset, err = mask.Input(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 40, 40) || offset != image.Pt(20, 10) {
+		t.Fatalf("mask input %v offset %v", img.Bounds(), offset)
+	}
+	gray := color.RGBA{R: 114, G: 114, B: 114, A: 255}
+	for _, tc := range []struct {
+		p    image.Point
+		want color.RGBA
+	}{
+		{image.Pt(1, 1), red},    // near the right angle
+		{image.Pt(38, 38), gray}, // beyond the hypotenuse
+		{image.Pt(5, 30), red},
+		{image.Pt(30, 30), gray},
+	} {
+		if got := img.(*image.RGBA).RGBAAt(tc.p.X, tc.p.Y); got != tc.want {
+			t.Errorf("mask pixel %v = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+
+	outside := mustZones(t, ZoneConfig{Zones: []Zone{rectZone("far", 200, 200, 300, 300)}, Input: InputCrop})
+	if _, _, err := outside.Input(frame); err == nil {
+		t.Error("expected an error for zones outside the frame")
+	}
+}
+
+func TestMaskForOffsetFrame(t *testing.T) {
+	z := mustZones(t, ZoneConfig{Zones: []Zone{triangle}, Input: InputMask})
+	bounds := image.Rect(100, 100, 200, 200)
+	r := z.Rect(bounds)
+
+	m := z.maskFor(bounds, r)
+	if m.Bounds() != image.Rect(0, 0, 40, 40) {
+		t.Fatalf("mask bounds %v", m.Bounds())
+	}
+	if m.AlphaAt(0, 0).A != 255 || m.AlphaAt(39, 39).A != 0 {
+		t.Errorf("mask corners %v %v", m.AlphaAt(0, 0), m.AlphaAt(39, 39))
+	}
+	// cached per frame bounds
+	if z.maskFor(bounds, r) != m {
+		t.Error("mask not reused for the same frame bounds")
+	}
+	if z.maskFor(image.Rect(0, 0, 100, 100), z.Rect(image.Rect(0, 0, 100, 100))) == m {
+		t.Error("mask reused for other frame bounds")
+	}
+}
+
+func TestItemsMapCropToFrame(t *testing.T) {
+	zones := mustZones(t, ZoneConfig{
+		Zones: []Zone{rectZone("left", 20, 10, 60, 50), rectZone("right", 60, 10, 100, 50)},
+		Input: InputCrop,
+		Drop:  true,
+	})
+	frame := filled(image.Rect(0, 0, 120, 80), color.RGBA{A: 255})
+	_, offset, err := zones.Input(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Pipeline{Zones: zones}
+	// detections relative to the crop starting at 20, 10
+	items := p.items([]detector.Detection{
+		{Box: detector.Box{X1: 0, Y1: 0, X2: 10, Y2: 10}, Class: "a"},
+		{Box: detector.Box{X1: 50, Y1: 20, X2: 60, Y2: 30}, Class: "b"},
+		// centre 59, 55 lies below both zones, the item is dropped
+		{Box: detector.Box{X1: 38, Y1: 40, X2: 40, Y2: 50}, Class: "c"},
+	}, offset)
+
+	want := []Item{
+		{Detection: detector.Detection{Box: detector.Box{X1: 20, Y1: 10, X2: 30, Y2: 20}, Class: "a"}, Zones: []string{"left"}},
+		{Detection: detector.Detection{Box: detector.Box{X1: 70, Y1: 30, X2: 80, Y2: 40}, Class: "b"}, Zones: []string{"right"}},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("items =\n%+v\nwant\n%+v", items, want)
+	}
+}
@@ -0,0 +1,213 @@
+package results
+
+import (
+	"fmt"
+	"math"
+)
+
+// Box is an axis-aligned box in pixel coordinates, corners (x1, y1) and
+// (x2, y2) with x1 <= x2 and y1 <= y2
+type Box struct {
+	X1 float32 `json:"x1"`
+	Y1 float32 `json:"y1"`
+	X2 float32 `json:"x2"`
+	Y2 float32 `json:"y2"`
+}
+
+// layout of the four numbers describing a box
+type Format string
+
+const (
+	XYXY   Format = "xyxy"   // x1, y1, x2, y2 (Pascal VOC)
+	XYWH   Format = "xywh"   // x1, y1, width, height (COCO)
+	CXCYWH Format = "cxcywh" // centre x, centre y, width, height (YOLO)
+)
+
+// FromFormat builds a box from four values in the given format
+func FromFormat(v [4]float32, format Format) (Box, error) {
+	switch format {
+	case XYXY:
+		return Box{X1: v[0], Y1: v[1], X2: v[2], Y2: v[3]}, nil
+	case XYWH:
+		return Box{X1: v[0], Y1: v[1], X2: v[0] + v[2], Y2: v[1] + v[3]}, nil
+	case CXCYWH:
+		return Box{X1: v[0] - v[2]/2, Y1: v[1] - v[3]/2, X2: v[0] + v[2]/2, Y2: v[1] + v[3]/2}, nil
+	}
+	return Box{}, fmt.Errorf("unknown box format %q", format)
+}
+
+// To returns the four values of the box in the given format
+func (b Box) To(format Format) ([4]float32, error) {
+	switch format {
+	case XYXY:
+		return [4]float32{b.X1, b.Y1, b.X2, b.Y2}, nil
+	case XYWH:
+		return [4]float32{b.X1, b.Y1, b.Width(), b.Height()}, nil
+	case CXCYWH:
+		cx, cy := b.Center()
+		return [4]float32{cx, cy, b.Width(), b.Height()}, nil
+	}
+	return [4]float32{}, fmt.Errorf("unknown box format %q", format)
+}
+
+// Normalize divides the coordinates by the image size, giving values in
+// [0, 1] for boxes inside the image
+func (b Box) Normalize(width, height float32) Box {
+	return Box{X1: b.X1 / width, Y1: b.Y1 / height, X2: b.X2 / width, Y2: b.Y2 / height}
+}
+
+// Denormalize multiplies normalized coordinates by the image size
+func (b Box) Denormalize(width, height float32) Box {
+	return b.Scale(width, height)
+}
+
+func (b Box) Width() float32 {
+	return b.X2 - b.X1
+}
+
+func (b Box) Height() float32 {
+	return b.Y2 - b.Y1
+}
+
+// Center returns the centre point
+func (b Box) Center() (float32, float32) {
+	return (b.X1 + b.X2) / 2, (b.Y1 + b.Y2) / 2
+}
+
+// Area returns the area, 0 for empty boxes
+func (b Box) Area() float32 {
+	if b.Empty() {
+		return 0
+	}
+	return b.Width() * b.Height()
+}
+
+// Empty reports whether the box has no area
+func (b Box) Empty() bool {
+	return b.X2 <= b.X1 || b.Y2 <= b.Y1
+}
+
+// Intersect returns the overlapping box, the zero box if there is none
+func (b Box) Intersect(o Box) Box {
+	r := Box{
+		X1: max32(b.X1, o.X1),
+		Y1: max32(b.Y1, o.Y1),
+		X2: min32(b.X2, o.X2),
+		Y2: min32(b.Y2, o.Y2),
+	}
+	if r.Empty() {
+		return Box{}
+	}
+	return r
+}
+
+// Enclose returns the smallest box containing both boxes
+func (b Box) Enclose(o Box) Box {
+	return Box{
+		X1: min32(b.X1, o.X1),
+		Y1: min32(b.Y1, o.Y1),
+		X2: max32(b.X2, o.X2),
+		Y2: max32(b.Y2, o.Y2),
+	}
+}
+
+// IoU returns the intersection over union
+func (b Box) IoU(o Box) float32 {
+	inter := b.Intersect(o).Area()
+	union := b.Area() + o.Area() - inter
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
+
+// GIoU returns the generalized IoU, which also ranks disjoint boxes by how
+// much empty space their enclosing box holds. Range [-1, 1].
+func (b Box) GIoU(o Box) float32 {
+	inter := b.Intersect(o).Area()
+	union := b.Area() + o.Area() - inter
+	enclose := b.Enclose(o).Area()
+	if union <= 0 || enclose <= 0 {
+		return 0
+	}
+	return inter/union - (enclose-union)/enclose
+}
+
+// DIoU returns the distance IoU, IoU penalized by the squared distance of
+// the centres relative to the diagonal of the enclosing box
+func (b Box) DIoU(o Box) float32 {
+	return b.IoU(o) - b.centerPenalty(o)
+}
+
+// CIoU returns the complete IoU, DIoU additionally penalized by the
+// difference of the aspect ratios
+func (b Box) CIoU(o Box) float32 {
+	iou := b.IoU(o)
+	if b.Height() <= 0 || o.Height() <= 0 {
+		return iou - b.centerPenalty(o)
+	}
+	d := math.Atan(float64(o.Width()/o.Height())) - math.Atan(float64(b.Width()/b.Height()))
+	v := float32(4 / (math.Pi * math.Pi) * d * d)
+	var alpha float32
+	if v > 0 {
+		alpha = v / (1 - iou + v)
+	}
+	return iou - b.centerPenalty(o) - alpha*v
+}
+
+// centerPenalty is the squared centre distance over the squared diagonal of
+// the enclosing box
+func (b Box) centerPenalty(o Box) float32 {
+	bx, by := b.Center()
+	ox, oy := o.Center()
+	e := b.Enclose(o)
+	diagonal := e.Width()*e.Width() + e.Height()*e.Height()
+	if diagonal <= 0 {
+		return 0
+	}
+	return ((bx-ox)*(bx-ox) + (by-oy)*(by-oy)) / diagonal
+}
+
+// Clip limits the box to an image of the given size
+func (b Box) Clip(width, height float32) Box {
+	return Box{
+		X1: clamp32(b.X1, 0, width),
+		Y1: clamp32(b.Y1, 0, height),
+		X2: clamp32(b.X2, 0, width),
+		Y2: clamp32(b.Y2, 0, height),
+	}
+}
+
+// Scale multiplies the x and y coordinates, e.g. to map between image sizes
+func (b Box) Scale(sx, sy float32) Box {
+	return Box{X1: b.X1 * sx, Y1: b.Y1 * sy, X2: b.X2 * sx, Y2: b.Y2 * sy}
+}
+
+// Translate moves the box
+func (b Box) Translate(dx, dy float32) Box {
+	return Box{X1: b.X1 + dx, Y1: b.Y1 + dy, X2: b.X2 + dx, Y2: b.Y2 + dy}
+}
+
+// Pad grows the box by px on the left and right and py on the top and
+// bottom, negative values shrink it
+func (b Box) Pad(px, py float32) Box {
+	return Box{X1: b.X1 - px, Y1: b.Y1 - py, X2: b.X2 + px, Y2: b.Y2 + py}
+}
+
+func min32(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func clamp32(v, lo, hi float32) float32 {
+	return min32(max32(v, lo), hi)
+}
@@ -0,0 +1,122 @@
+package results
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-5
+}
+
+func TestFormats(t *testing.T) {
+	box := Box{X1: 10, Y1: 20, X2: 50, Y2: 40}
+	for _, format := range []Format{XYXY, XYWH, CXCYWH} {
+		v, err := box.To(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := FromFormat(v, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if back != box {
+			t.Errorf("%s: round trip gave %+v via %v", format, back, v)
+		}
+	}
+
+	if v, _ := box.To(CXCYWH); v != [4]float32{30, 30, 40, 20} {
+		t.Errorf("cxcywh: got %v", v)
+	}
+	if n := box.Normalize(100, 50); n != (Box{X1: 0.1, Y1: 0.4, X2: 0.5, Y2: 0.8}) {
+		t.Errorf("normalize: got %+v", n)
+	}
+	if _, err := FromFormat([4]float32{}, "yxyx"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestIoUVariants(t *testing.T) {
+	a := Box{X1: 0, Y1: 0, X2: 2, Y2: 2}
+	b := Box{X1: 1, Y1: 0, X2: 3, Y2: 2}
+
+	// overlap 2, union 6, enclosing 3x2, centre distance 1, diagonal^2 13
+	if got := a.IoU(b); !near(got, 1.0/3) {
+		t.Errorf("IoU %v", got)
+	}
+	if got := a.GIoU(b); !near(got, 1.0/3) {
+		t.Errorf("GIoU %v", got)
+	}
+	if got := a.DIoU(b); !near(got, 1.0/3-1.0/13) {
+		t.Errorf("DIoU %v", got)
+	}
+	// same aspect ratio, no extra penalty
+	if got := a.CIoU(b); !near(got, a.DIoU(b)) {
+		t.Errorf("CIoU %v", got)
+	}
+
+	// disjoint boxes: IoU 0, GIoU ranks by distance
+	c := Box{X1: 4, Y1: 0, X2: 6, Y2: 2}
+	if a.IoU(c) != 0 {
+		t.Errorf("disjoint IoU %v", a.IoU(c))
+	}
+	if got := a.GIoU(c); !near(got, -1.0/3) {
+		t.Errorf("disjoint GIoU %v", got)
+	}
+
+	// identical boxes
+	if a.IoU(a) != 1 || a.GIoU(a) != 1 || a.DIoU(a) != 1 || a.CIoU(a) != 1 {
+		t.Errorf("identical boxes: %v %v %v %v", a.IoU(a), a.GIoU(a), a.DIoU(a), a.CIoU(a))
+	}
+
+	// a different aspect ratio lowers CIoU below DIoU
+	d := Box{X1: 0, Y1: 0, X2: 4, Y2: 1}
+	if a.CIoU(d) >= a.DIoU(d) {
+		t.Errorf("CIoU %v not below DIoU %v", a.CIoU(d), a.DIoU(d))
+	}
+}
+
+func TestClipScalePad(t *testing.T) {
+	box := Box{X1: -5, Y1: 10, X2: 120, Y2: 60}
+	if got := box.Clip(100, 50); got != (Box{X1: 0, Y1: 10, X2: 100, Y2: 50}) {
+		t.Errorf("clip: %+v", got)
+	}
+	if got := box.Scale(2, 0.5); got != (Box{X1: -10, Y1: 5, X2: 240, Y2: 30}) {
+		t.Errorf("scale: %+v", got)
+	}
+	if got := box.Pad(5, -5); got != (Box{X1: -10, Y1: 15, X2: 125, Y2: 55}) {
+		t.Errorf("pad: %+v", got)
+	}
+	if (Box{X1: 3, Y1: 3, X2: 1, Y2: 5}).Area() != 0 {
+		t.Error("inverted box should have no area")
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	frame := Frame{
+		Source:     "cam1.jpg",
+		Width:      640,
+		Height:     480,
+		Detections: []Detection{{Box: Box{X1: 1, Y1: 2, X2: 3, Y2: 4}, Class: "redbull", Confidence: 0.5}},
+	}
+	data, err := Marshal(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := Marshal(frame)
+	if string(data) != string(again) {
+		t.Error("marshaling is not stable")
+	}
+
+	got, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SchemaVersion != SchemaVersion || got.Detections[0] != frame.Detections[0] {
+		t.Errorf("round trip gave %+v", got)
+	}
+
+	if _, err := Unmarshal([]byte(`{"schema_version": 99}`)); err == nil {
+		t.Error("expected error for future schema version")
+	}
+}
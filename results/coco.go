@@ -0,0 +1,96 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// COCO detection dataset (the subset of fields labelling tools read)
+type COCO struct {
+	Images      []COCOImage      `json:"images"`
+	Categories  []COCOCategory   `json:"categories"`
+	Annotations []COCOAnnotation `json:"annotations"`
+}
+
+type COCOImage struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+type COCOCategory struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type COCOAnnotation struct {
+	ID         int        `json:"id"`
+	ImageID    int        `json:"image_id"`
+	CategoryID int        `json:"category_id"`
+	BBox       [4]float32 `json:"bbox"` // x, y, width, height
+	Area       float32    `json:"area"`
+	IsCrowd    int        `json:"iscrowd"`
+	Score      float32    `json:"score,omitempty"` // confidence of predictions
+}
+
+// NewCOCO builds a dataset from frames. Category ids are the class indices
+// plus one, image and annotation ids count from one.
+func NewCOCO(frames []Frame, classes []string) (COCO, error) {
+	dataset := COCO{
+		Images:      []COCOImage{},
+		Categories:  make([]COCOCategory, len(classes)),
+		Annotations: []COCOAnnotation{},
+	}
+	for i, c := range classes {
+		dataset.Categories[i] = COCOCategory{ID: i + 1, Name: c}
+	}
+	index := classIndex(classes)
+
+	for i, frame := range frames {
+		imageID := i + 1
+		dataset.Images = append(dataset.Images, COCOImage{
+			ID:       imageID,
+			FileName: filepath.Base(frame.Source),
+			Width:    frame.Width,
+			Height:   frame.Height,
+		})
+
+		for _, det := range frame.Detections {
+			id, ok := index[det.Class]
+			if !ok {
+				return COCO{}, fmt.Errorf("class %q not in class list", det.Class)
+			}
+			box := det.Box
+			if frame.Width > 0 && frame.Height > 0 {
+				box = box.Clip(float32(frame.Width), float32(frame.Height))
+			}
+			bbox, _ := box.To(XYWH)
+			dataset.Annotations = append(dataset.Annotations, COCOAnnotation{
+				ID:         len(dataset.Annotations) + 1,
+				ImageID:    imageID,
+				CategoryID: id + 1,
+				BBox:       bbox,
+				Area:       box.Area(),
+				Score:      det.Confidence,
+			})
+		}
+	}
+	return dataset, nil
+}
+
+// WriteCOCO writes the frames as one COCO JSON document
+func WriteCOCO(w io.Writer, frames []Frame, classes []string) error {
+	dataset, err := NewCOCO(frames, classes)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(dataset); err != nil {
+		return fmt.Errorf("failed to encode COCO: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var exportClasses = []string{"cigarettes", "redbull"}
+
+var exportFrame = Frame{
+	Source: "images/shelf_01.jpg",
+	Width:  200,
+	Height: 100,
+	Detections: []Detection{
+		{Box: Box{X1: 20, Y1: 10, X2: 60, Y2: 50}, Class: "redbull", Confidence: 0.9},
+		{Box: Box{X1: 150, Y1: 60, X2: 210, Y2: 100}, Class: "cigarettes", Confidence: 0.4},
+	},
+}
+
+func TestWriteYOLO(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteYOLO(&buf, exportFrame, exportClasses, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "1 0.200000 0.300000 0.200000 0.400000\n" +
+		"0 0.875000 0.800000 0.250000 0.400000\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	WriteYOLO(&buf, exportFrame, exportClasses, true)
+	if !strings.HasSuffix(strings.Split(buf.String(), "\n")[0], " 0.900000") {
+		t.Errorf("missing confidence column: %q", buf.String())
+	}
+
+	if err := WriteYOLO(&buf, exportFrame, []string{"redbull"}, false); err == nil {
+		t.Error("expected error for class missing from the class list")
+	}
+}
+
+func TestWriteCOCO(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCOCO(&buf, []Frame{exportFrame, {Source: "b.jpg", Width: 10, Height: 10}}, exportClasses); err != nil {
+		t.Fatal(err)
+	}
+	var dataset COCO
+	if err := json.Unmarshal(buf.Bytes(), &dataset); err != nil {
+		t.Fatal(err)
+	}
+	if len(dataset.Images) != 2 || len(dataset.Categories) != 2 || len(dataset.Annotations) != 2 {
+		t.Fatalf("unexpected sizes: %+v", dataset)
+	}
+	a := dataset.Annotations[0]
+	if a.ID != 1 || a.ImageID != 1 || a.CategoryID != 2 || a.BBox != [4]float32{20, 10, 40, 40} || a.Area != 1600 {
+		t.Errorf("unexpected annotation %+v", a)
+	}
+	if dataset.Images[0].FileName != "shelf_01.jpg" {
+		t.Errorf("file name %q", dataset.Images[0].FileName)
+	}
+}
+
+func TestWriteVOC(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteVOC(&buf, exportFrame); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<filename>shelf_01.jpg</filename>",
+		"<folder>images</folder>",
+		"<width>200</width>",
+		"<name>redbull</name>",
+		"<xmin>21</xmin>",
+		"<xmax>60</xmax>",
+		"<truncated>1</truncated>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in\n%s", want, out)
+		}
+	}
+}
+
+func TestReadYOLORoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	WriteYOLO(&buf, exportFrame, exportClasses, true)
+	dets, err := ReadYOLO(&buf, exportClasses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 2 || dets[0].Class != "redbull" || dets[0].Confidence != 0.9 {
+		t.Fatalf("got %+v", dets)
+	}
+	box := dets[0].Box.Denormalize(200, 100)
+	if !near(box.X1, 20) || !near(box.Y2, 50) {
+		t.Errorf("box %+v", box)
+	}
+
+	if _, err := ReadYOLO(strings.NewReader("7 0.5 0.5 0.1 0.1\n"), exportClasses); err == nil {
+		t.Error("expected error for class out of range")
+	}
+}
+
+func TestReadCOCOSeparatesCrowds(t *testing.T) {
+	data := `{
+		"images": [{"id": 7, "file_name": "a.jpg", "width": 100, "height": 100}, {"id": 3, "file_name": "b.jpg"}],
+		"categories": [{"id": 2, "name": "redbull"}, {"id": 1, "name": "cigarettes"}],
+		"annotations": [
+			{"id": 1, "image_id": 7, "category_id": 2, "bbox": [10, 20, 30, 40], "iscrowd": 0},
+			{"id": 2, "image_id": 7, "category_id": 1, "bbox": [0, 0, 50, 50], "iscrowd": 1},
+			{"id": 3, "image_id": 3, "category_id": 1, "bbox": [5, 5, 10, 10], "score": 0.4}
+		]
+	}`
+	dataset, err := ReadCOCO(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataset.Annotations[1].IsCrowd != 1 {
+		t.Fatalf("iscrowd not read: %+v", dataset.Annotations[1])
+	}
+
+	frames, classes, err := dataset.Frames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(classes, ",") != "cigarettes,redbull" {
+		t.Errorf("classes %v", classes)
+	}
+	if len(frames) != 2 || len(frames[0].Detections) != 1 || len(frames[1].Detections) != 1 {
+		t.Fatalf("frames %+v", frames)
+	}
+	if got := frames[0].Detections[0]; got.Class != "redbull" || got.Box != (Box{X1: 10, Y1: 20, X2: 40, Y2: 60}) || got.Confidence != 1 {
+		t.Errorf("regular annotation %+v", got)
+	}
+	if got := frames[1].Detections[0]; got.Confidence != 0.4 {
+		t.Errorf("scored annotation %+v", got)
+	}
+
+	crowds, err := dataset.Crowds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crowds) != 2 || len(crowds[0]) != 1 || len(crowds[1]) != 0 {
+		t.Fatalf("crowds %+v", crowds)
+	}
+	if got := crowds[0][0]; got.Class != "cigarettes" || got.Box != (Box{X1: 0, Y1: 0, X2: 50, Y2: 50}) {
+		t.Errorf("crowd annotation %+v", got)
+	}
+
+	dataset.Annotations[0].ImageID = 99
+	if _, err := dataset.Crowds(); err == nil {
+		t.Error("expected error for unknown image")
+	}
+}
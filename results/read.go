@@ -0,0 +1,125 @@
+package results
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadYOLO parses a YOLO label file as written by WriteYOLO. Boxes stay
+// normalized to [0, 1], use Box.Denormalize with the image size. A sixth
+// column is read as confidence, ground truth gets confidence 1.
+func ReadYOLO(r io.Reader, classes []string) ([]Detection, error) {
+	var detections []Detection
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 5 && len(fields) != 6 {
+			return nil, fmt.Errorf("line %d: expected 5 or 6 columns, got %d", line, len(fields))
+		}
+
+		id, err := strconv.Atoi(fields[0])
+		if err != nil || id < 0 || id >= len(classes) {
+			return nil, fmt.Errorf("line %d: invalid class %q", line, fields[0])
+		}
+		var v [5]float32
+		for i, f := range fields[1:] {
+			x, err := strconv.ParseFloat(f, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			v[i] = float32(x)
+		}
+
+		box, _ := FromFormat([4]float32{v[0], v[1], v[2], v[3]}, CXCYWH)
+		confidence := float32(1)
+		if len(fields) == 6 {
+			confidence = v[4]
+		}
+		detections = append(detections, Detection{Box: box, Class: classes[id], Confidence: confidence})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read labels: %v", err)
+	}
+	return detections, nil
+}
+
+// ReadCOCO parses a COCO detection JSON
+func ReadCOCO(r io.Reader) (COCO, error) {
+	var dataset COCO
+	if err := json.NewDecoder(r).Decode(&dataset); err != nil {
+		return COCO{}, fmt.Errorf("failed to parse COCO: %v", err)
+	}
+	return dataset, nil
+}
+
+// Frames returns one frame per image with its annotations in pixel
+// coordinates, and the category names ordered by id. Annotations without
+// score get confidence 1. Crowd annotations are left out, see Crowds.
+func (c COCO) Frames() ([]Frame, []string, error) {
+	frames, _, classes, err := c.split()
+	return frames, classes, err
+}
+
+// Crowds returns the crowd annotations (iscrowd 1) of every image in pixel
+// coordinates, in the image order of Frames. They mark regions of many
+// objects labelled as one, evaluations ignore predictions inside them.
+func (c COCO) Crowds() ([][]Detection, error) {
+	_, crowds, _, err := c.split()
+	return crowds, err
+}
+
+// split converts the dataset, separating crowd from regular annotations
+func (c COCO) split() ([]Frame, [][]Detection, []string, error) {
+	categories := make(map[int]string, len(c.Categories))
+	for _, cat := range c.Categories {
+		categories[cat.ID] = cat.Name
+	}
+	ids := make([]int, 0, len(c.Categories))
+	for _, cat := range c.Categories {
+		ids = append(ids, cat.ID)
+	}
+	sort.Ints(ids)
+	classes := make([]string, len(ids))
+	for i, id := range ids {
+		classes[i] = categories[id]
+	}
+
+	frames := make([]Frame, len(c.Images))
+	crowds := make([][]Detection, len(c.Images))
+	byImage := make(map[int]int, len(c.Images))
+	for i, img := range c.Images {
+		frames[i] = Frame{SchemaVersion: SchemaVersion, Source: img.FileName, Width: img.Width, Height: img.Height}
+		byImage[img.ID] = i
+	}
+
+	for _, a := range c.Annotations {
+		i, ok := byImage[a.ImageID]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("annotation %d refers to unknown image %d", a.ID, a.ImageID)
+		}
+		class, ok := categories[a.CategoryID]
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("annotation %d refers to unknown category %d", a.ID, a.CategoryID)
+		}
+		box, _ := FromFormat(a.BBox, XYWH)
+		confidence := a.Score
+		if confidence == 0 {
+			confidence = 1
+		}
+		det := Detection{Box: box, Class: class, Confidence: confidence}
+		if a.IsCrowd != 0 {
+			crowds[i] = append(crowds[i], det)
+		} else {
+			frames[i].Detections = append(frames[i].Detections, det)
+		}
+	}
+	return frames, crowds, classes, nil
+}
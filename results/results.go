@@ -0,0 +1,59 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is written into every serialized Frame and bumped on
+// incompatible changes
+const SchemaVersion = 1
+
+// detection (one box with class and confidence)
+type Detection struct {
+	Box        Box     `json:"box"`
+	Class      string  `json:"class"`
+	Confidence float32 `json:"confidence"`
+}
+
+// classification (one label with confidence)
+type Classification struct {
+	Class      string  `json:"class"`
+	Confidence float32 `json:"confidence"`
+}
+
+// Frame holds the results of one image
+type Frame struct {
+	SchemaVersion   int              `json:"schema_version"`
+	Source          string           `json:"source,omitempty"` // image path, camera id, ...
+	Width           int              `json:"width,omitempty"`
+	Height          int              `json:"height,omitempty"`
+	Detections      []Detection      `json:"detections"`
+	Classifications []Classification `json:"classifications,omitempty"`
+}
+
+// Marshal encodes the frame as indented JSON with the current schema
+// version. Field order follows the struct, so output is stable.
+func Marshal(frame Frame) ([]byte, error) {
+	frame.SchemaVersion = SchemaVersion
+	if frame.Detections == nil {
+		frame.Detections = []Detection{}
+	}
+	data, err := json.MarshalIndent(frame, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode results: %v", err)
+	}
+	return data, nil
+}
+
+// Unmarshal decodes a frame, rejecting newer schema versions
+func Unmarshal(data []byte) (Frame, error) {
+	var frame Frame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return Frame{}, fmt.Errorf("failed to decode results: %v", err)
+	}
+	if frame.SchemaVersion < 1 || frame.SchemaVersion > SchemaVersion {
+		return Frame{}, fmt.Errorf("unsupported results schema version %d", frame.SchemaVersion)
+	}
+	return frame, nil
+}
@@ -0,0 +1,106 @@
+package results
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// Pascal VOC annotation of one image
+type VOCAnnotation struct {
+	XMLName  xml.Name    `xml:"annotation"`
+	Folder   string      `xml:"folder"`
+	Filename string      `xml:"filename"`
+	Size     VOCSize     `xml:"size"`
+	Objects  []VOCObject `xml:"object"`
+}
+
+type VOCSize struct {
+	Width  int `xml:"width"`
+	Height int `xml:"height"`
+	Depth  int `xml:"depth"`
+}
+
+type VOCObject struct {
+	Name      string    `xml:"name"`
+	Pose      string    `xml:"pose"`
+	Truncated int       `xml:"truncated"`
+	Difficult int       `xml:"difficult"`
+	BndBox    VOCBndBox `xml:"bndbox"`
+}
+
+// corners in 1-based pixel coordinates
+type VOCBndBox struct {
+	XMin int `xml:"xmin"`
+	YMin int `xml:"ymin"`
+	XMax int `xml:"xmax"`
+	YMax int `xml:"ymax"`
+}
+
+// NewVOC converts a frame into a VOC annotation. Boxes touching the image
+// border are marked truncated.
+func NewVOC(frame Frame) (VOCAnnotation, error) {
+	if frame.Width <= 0 || frame.Height <= 0 {
+		return VOCAnnotation{}, fmt.Errorf("frame %q has no image size", frame.Source)
+	}
+	w, h := float32(frame.Width), float32(frame.Height)
+
+	annotation := VOCAnnotation{
+		Folder:   filepath.Base(filepath.Dir(frame.Source)),
+		Filename: filepath.Base(frame.Source),
+		Size:     VOCSize{Width: frame.Width, Height: frame.Height, Depth: 3},
+	}
+	for _, det := range frame.Detections {
+		box := det.Box.Clip(w, h)
+		truncated := 0
+		if box != det.Box || box.X1 == 0 || box.Y1 == 0 || box.X2 == w || box.Y2 == h {
+			truncated = 1
+		}
+		annotation.Objects = append(annotation.Objects, VOCObject{
+			Name:      det.Class,
+			Pose:      "Unspecified",
+			Truncated: truncated,
+			BndBox: VOCBndBox{
+				XMin: int(math.Round(float64(box.X1))) + 1,
+				YMin: int(math.Round(float64(box.Y1))) + 1,
+				XMax: int(math.Round(float64(box.X2))),
+				YMax: int(math.Round(float64(box.Y2))),
+			},
+		})
+	}
+	return annotation, nil
+}
+
+// WriteVOC writes the VOC XML of one frame
+func WriteVOC(w io.Writer, frame Frame) error {
+	annotation, err := NewVOC(frame)
+	if err != nil {
+		return err
+	}
+	data, err := xml.MarshalIndent(annotation, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode VOC: %v", err)
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SaveVOC writes the VOC XML of the frame into dir, named after the source
+// image (images/a.jpg -> dir/a.xml)
+func SaveVOC(dir string, frame Frame) error {
+	path := filepath.Join(dir, labelName(frame.Source, ".xml"))
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create annotation file: %v", err)
+	}
+	if err := WriteVOC(f, frame); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,77 @@
+package results
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteYOLO writes one line "class cx cy w h" per detection, coordinates
+// normalized by the image size, as used by YOLO training sets. With
+// withConfidence the confidence is appended as sixth column. Classes are
+// looked up by name in classes.
+func WriteYOLO(w io.Writer, frame Frame, classes []string, withConfidence bool) error {
+	if frame.Width <= 0 || frame.Height <= 0 {
+		return fmt.Errorf("frame %q has no image size", frame.Source)
+	}
+	index := classIndex(classes)
+
+	for _, det := range frame.Detections {
+		id, ok := index[det.Class]
+		if !ok {
+			return fmt.Errorf("class %q not in class list", det.Class)
+		}
+		box := det.Box.Clip(float32(frame.Width), float32(frame.Height)).Normalize(float32(frame.Width), float32(frame.Height))
+		v, _ := box.To(CXCYWH)
+
+		line := fmt.Sprintf("%d %.6f %.6f %.6f %.6f", id, v[0], v[1], v[2], v[3])
+		if withConfidence {
+			line += fmt.Sprintf(" %.6f", det.Confidence)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveYOLO writes the label file of the frame into dir, named after the
+// source image (images/a.jpg -> dir/a.txt)
+func SaveYOLO(dir string, frame Frame, classes []string, withConfidence bool) error {
+	path := filepath.Join(dir, labelName(frame.Source, ".txt"))
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create label file: %v", err)
+	}
+	if err := WriteYOLO(f, frame, classes, withConfidence); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteYOLOClasses writes the class names one per line (classes.txt)
+func WriteYOLOClasses(w io.Writer, classes []string) error {
+	for _, c := range classes {
+		if _, err := fmt.Fprintln(w, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelName replaces the extension of the source file name
+func labelName(source, ext string) string {
+	base := filepath.Base(source)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ext
+}
+
+func classIndex(classes []string) map[string]int {
+	index := make(map[string]int, len(classes))
+	for i, c := range classes {
+		index[c] = i
+	}
+	return index
+}
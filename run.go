@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"yolo_detection/deploy"
+	"yolo_detection/pipeline"
+	"yolo_detection/results"
+)
+
+func cmdRun(ctx context.Context, args []string) error {
+	fs := newFlags("run", "images...")
+	lib := runtimeFlag(fs)
+	configPath := fs.String("config", os.Getenv("YOLO_CONFIG"), "deployment config (env YOLO_CONFIG), values can be overridden with "+deploy.EnvPrefix+"* variables")
+	name := fs.String("pipeline", "", "pipeline to run, optional when the config has only one")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *configPath == "" {
+		return usagef("-config is required")
+	}
+	paths, err := expandInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := deploy.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if *name == "" && len(cfg.Pipelines) == 1 {
+		for n := range cfg.Pipelines {
+			*name = n
+		}
+	}
+	if _, ok := cfg.Pipelines[*name]; !ok {
+		names := make([]string, 0, len(cfg.Pipelines))
+		for n := range cfg.Pipelines {
+			names = append(names, n)
+		}
+		return usagef("choose a pipeline with -pipeline, have %v", names)
+	}
+
+	// the config names the library unless -lib is given
+	libSet := false
+	fs.Visit(func(f *flag.Flag) { libSet = libSet || f.Name == "lib" })
+	if !libSet && cfg.Runtime.Library != "" {
+		*lib = cfg.Runtime.Library
+	}
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+
+	deployment, err := deploy.Build(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer deployment.Destroy()
+	p := deployment.Pipelines[*name]
+	classes := p.Detector.Classes()
+
+	outputs, err := openOutputs(cfg.Outputs, classes)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		img, err := loadImage(path)
+		if err != nil {
+			outputs.close()
+			return fmt.Errorf("error loading image: %v", err)
+		}
+
+		result, err := p.Run(img)
+		rejected := errors.Is(err, pipeline.ErrFrameRejected)
+		if err != nil && !rejected {
+			outputs.close()
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		b := img.Bounds()
+		rec := frameRecord{Source: path, Pipeline: *name, Width: b.Dx(), Height: b.Dy(), Rejected: rejected, Result: result}
+		if err := outputs.write(rec, img); err != nil {
+			outputs.close()
+			return err
+		}
+	}
+	return outputs.close()
+}
+
+// frameRecord is one line of the json output
+type frameRecord struct {
+	Source   string `json:"source"`
+	Pipeline string `json:"pipeline"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Rejected bool   `json:"rejected,omitempty"` // by the quality gate, there are no items
+	*pipeline.Result
+}
+
+// frame converts the record for the results exporters
+func (r frameRecord) frame() results.Frame {
+	frame := results.Frame{SchemaVersion: results.SchemaVersion, Source: r.Source, Width: r.Width, Height: r.Height, Detections: []results.Detection{}}
+	for _, item := range r.Items {
+		frame.Detections = append(frame.Detections, item.Detection)
+	}
+	return frame
+}
+
+// output writes frames in one format
+type output struct {
+	config deploy.OutputConfig
+	w      io.Writer
+	file   *os.File
+	frames []results.Frame // collected for coco
+}
+
+type outputs struct {
+	list    []*output
+	classes []string
+}
+
+// openOutputs opens the files and directories of the outputs, without
+// outputs the text summary goes to stdout
+func openOutputs(configs []deploy.OutputConfig, classes []string) (*outputs, error) {
+	if len(configs) == 0 {
+		configs = []deploy.OutputConfig{{Format: deploy.OutputText}}
+	}
+	o := &outputs{classes: classes}
+	for _, cfg := range configs {
+		out := &output{config: cfg, w: stdout}
+		switch {
+		case cfg.Format == deploy.OutputYOLO || cfg.Format == deploy.OutputAnnotate:
+			if err := os.MkdirAll(cfg.Path, 0o755); err != nil {
+				o.close()
+				return nil, err
+			}
+		case cfg.Path != "":
+			f, err := os.Create(cfg.Path)
+			if err != nil {
+				o.close()
+				return nil, fmt.Errorf("failed to create output: %v", err)
+			}
+			out.file, out.w = f, f
+		}
+		o.list = append(o.list, out)
+	}
+	return o, nil
+}
+
+func (o *outputs) write(rec frameRecord, img image.Image) error {
+	for _, out := range o.list {
+		var err error
+		switch out.config.Format {
+		case deploy.OutputText:
+			err = writeText(out.w, rec)
+		case deploy.OutputJSON:
+			err = json.NewEncoder(out.w).Encode(rec)
+		case deploy.OutputCOCO:
+			out.frames = append(out.frames, rec.frame())
+		case deploy.OutputYOLO:
+			err = results.SaveYOLO(out.config.Path, rec.frame(), o.classes, true)
+		case deploy.OutputAnnotate:
+			err = DrawDebug(img, rec.frame().Detections, filepath.Join(out.config.Path, filepath.Base(rec.Source)))
+		}
+		if err != nil {
+			return fmt.Errorf("%s output: %v", out.config.Format, err)
+		}
+	}
+	return nil
+}
+
+// close finishes the outputs collecting all frames and closes the files
+func (o *outputs) close() error {
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	for _, out := range o.list {
+		switch out.config.Format {
+		case deploy.OutputCOCO:
+			keep(results.WriteCOCO(out.w, out.frames, o.classes))
+		case deploy.OutputYOLO:
+			f, err := os.Create(filepath.Join(out.config.Path, "classes.txt"))
+			if err != nil {
+				keep(err)
+				break
+			}
+			keep(results.WriteYOLOClasses(f, o.classes))
+			keep(f.Close())
+		}
+		if out.file != nil {
+			keep(out.file.Close())
+		}
+	}
+	o.list = nil
+	return first
+}
+
+func writeText(w io.Writer, rec frameRecord) error {
+	if rec.Rejected {
+		_, err := fmt.Fprintf(w, "%s: rejected, %v\n", rec.Source, rec.Quality.Issues)
+		return err
+	}
+	fmt.Fprintf(w, "%s: %d items\n", rec.Source, len(rec.Items))
+	for _, item := range rec.Items {
+		fmt.Fprintf(w, "  %s %.2f %.0f %.0f %.0f %.0f", item.Class, item.Confidence, item.Box.X1, item.Box.Y1, item.Box.X2, item.Box.Y2)
+		if len(item.Zones) > 0 {
+			fmt.Fprintf(w, " zones=%s", strings.Join(item.Zones, ","))
+		}
+		for _, c := range item.Classifications {
+			fmt.Fprintf(w, " %s=%.2f", c.Class, c.Confidence)
+		}
+		if item.Identity != nil {
+			fmt.Fprintf(w, " sku=%s (%.2f)", item.Identity.Label, item.Identity.Similarity)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
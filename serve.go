@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+	"yolo_detection/deploy"
+	"yolo_detection/detector"
+	"yolo_detection/imageloader"
+	"yolo_detection/results"
+)
+
+// largest accepted request body
+const maxUploadBytes = 32 << 20
+
+func cmdServe(ctx context.Context, args []string) error {
+	fs := newFlags("serve", "")
+	lib := runtimeFlag(fs)
+	addr := fs.String("addr", ":8080", "listen address")
+	modelPath := fs.String("model", "", "detector model (required)")
+	labels := fs.String("labels", "", "class names, comma separated or a file with one per line")
+	conf := fs.Float64("conf", 0, "confidence threshold, 0 keeps the model default")
+	iou := fs.Float64("iou", 0, "NMS IoU threshold, 0 keeps the model default")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *modelPath == "" {
+		return usagef("-model is required")
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected arguments %v", fs.Args())
+	}
+
+	var err error
+	m := deploy.ModelConfig{Kind: deploy.KindDetector, Path: *modelPath, ConfThreshold: float32(*conf), IOUThreshold: float32(*iou)}
+	if m.Labels, err = parseLabels(*labels); err != nil {
+		return err
+	}
+	config, thresholdsPath, err := m.DetectorConfig()
+	if err != nil {
+		return err
+	}
+	if thresholdsPath != "" {
+		fmt.Printf("Loaded thresholds from %s\n", thresholdsPath)
+	}
+
+	if err := initRuntime(*lib); err != nil {
+		return err
+	}
+	model, err := detector.NewWithConfig(ctx, *modelPath, config)
+	if err != nil {
+		return fmt.Errorf("error initializing detector: %v", err)
+	}
+	defer model.Destroy()
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: *addr, Handler: detectHandler(model)}
+	// closed once running requests are done and the model is unused
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Serving %s on %s\n", *modelPath, *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		stop()
+		<-drained
+		return err
+	}
+	<-drained
+	return nil
+}
+
+// detectHandler serves POST /detect with an image body, answering with a
+// results frame, and GET /healthz
+func detectHandler(model *detector.YOLODetector) http.Handler {
+	// the detector reuses its tensors, one request at a time
+	var mu sync.Mutex
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	mux.HandleFunc("/detect", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST an image", http.StatusMethodNotAllowed)
+			return
+		}
+		img, _, err := imageloader.Decode(http.MaxBytesReader(w, r.Body, maxUploadBytes), imageloader.DefaultLimits)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		detections, err := model.Detect(img)
+		mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		b := img.Bounds()
+		frame := results.Frame{
+			SchemaVersion: results.SchemaVersion,
+			Source:        r.URL.Query().Get("source"),
+			Width:         b.Dx(),
+			Height:        b.Dy(),
+			Detections:    detections,
+		}
+		data, err := results.Marshal(frame)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	return mux
+}